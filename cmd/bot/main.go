@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -11,9 +12,19 @@ import (
 	"github.com/pinghoyk/neurobot/internal/config"
 	"github.com/pinghoyk/neurobot/internal/database"
 	"github.com/pinghoyk/neurobot/internal/gigachat"
+	"github.com/pinghoyk/neurobot/internal/ratelimit"
+	"github.com/pinghoyk/neurobot/pkg/models"
 )
 
 func main() {
+	// Служебная команда: bot migrate <status|up|down|to>
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("Ошибка миграции: %v", err)
+		}
+		return
+	}
+
 	// Загрузка конфигурации из переменных окружения
 	log.Println("Загрузка конфигурации...")
 	cfg, err := config.Load()
@@ -21,9 +32,9 @@ func main() {
 		log.Fatalf("Не удалось загрузить конфигурацию: %v", err)
 	}
 
-	// Создание базы данных
-	log.Println("Создание базы данных...")
-	db, err := database.New(cfg.DatabasePath)
+	// Создание хранилища
+	log.Printf("Создание хранилища (%s)...", cfg.StorageBackend)
+	db, err := openStorage(cfg)
 	if err != nil {
 		log.Fatalf("Не удалось создать базу данных: %v", err)
 	}
@@ -37,9 +48,21 @@ func main() {
 		cfg.GigaChatScope,
 	)
 
+	if cfg.QuotaDaily == 0 || cfg.QuotaMonthly == 0 {
+		log.Println("⚠️ QUOTA_DAILY или QUOTA_MONTHLY равна 0: генерации запрещены всем без индивидуальной квоты")
+	}
+
 	// Создание бота
 	log.Println("Создание бота...")
-	telegramBot, err := bot.New(cfg.TelegramBotToken, db, gigachatClient)
+	rateLimit := models.RateLimit{PerMinute: cfg.RateLimitPerMinute, Burst: cfg.RateLimitBurst}
+	limiter, closeLimiter := newLimiter(cfg, db, rateLimit)
+	defer closeLimiter()
+
+	telegramBot, err := bot.New(cfg.TelegramBotToken, db, gigachatClient, limiter, bot.Options{
+		RateLimit: rateLimit,
+		Quota:     models.Quota{Daily: cfg.QuotaDaily, Monthly: cfg.QuotaMonthly},
+		AdminIDs:  cfg.AdminIDs,
+	})
 	if err != nil {
 		log.Fatalf("Не удалось создать бота: %v", err)
 	}
@@ -66,3 +89,76 @@ func main() {
 
 	log.Println("Бот успешно остановлен")
 }
+
+// openStorage создаёт хранилище, выбранное в конфигурации
+func openStorage(cfg *config.Config) (database.Storage, error) {
+	if cfg.StorageBackend == config.StorageMemory {
+		log.Println("⚠️ Используется хранилище в памяти: данные пропадут после перезапуска")
+		return database.NewMemory(), nil
+	}
+
+	var db *database.DB
+	var err error
+	if cfg.StorageBackend == config.StoragePostgres {
+		db, err = database.NewPostgres(cfg.DatabaseURL)
+	} else {
+		db, err = database.New(cfg.DatabasePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := enableEncryption(cfg, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// Ключи продуктов, сохранённые прежней версией ShoppingKey, пересчитываются один раз
+	rekeyed, err := db.RekeyProducts()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("не удалось пересчитать ключи продуктов: %w", err)
+	}
+	if rekeyed > 0 {
+		log.Printf("Пересчитаны ключи продуктов в списках покупок и запасах: %d", rekeyed)
+	}
+	return db, nil
+}
+
+// enableEncryption подключает ключи шифрования и перешифровывает открытые
+// и зашифрованные старым ключом предпочтения и их снимки в рецептах
+func enableEncryption(cfg *config.Config, db *database.DB) error {
+	keyring, err := cfg.Keyring()
+	if err != nil {
+		return err
+	}
+	if keyring == nil {
+		log.Println("⚠️ Ключи шифрования не заданы: аллергии и цели хранятся открытым текстом")
+		return nil
+	}
+
+	db.UseKeyring(keyring)
+	rewritten, failed, err := db.EncryptPreferences()
+	if err != nil {
+		return fmt.Errorf("не удалось зашифровать предпочтения: %w", err)
+	}
+	if rewritten > 0 {
+		log.Printf("Предпочтения и снимки в рецептах перешифрованы ключом %s: %d", keyring.CurrentID(), rewritten)
+	}
+	if failed > 0 {
+		log.Printf("⚠️ Не удалось расшифровать записей с предпочтениями: %d, проверьте ENCRYPTION_KEYS", failed)
+	}
+	return nil
+}
+
+// newLimiter создаёт лимитер генераций, выбранный в конфигурации
+func newLimiter(cfg *config.Config, db database.Storage, limit models.RateLimit) (ratelimit.Limiter, func()) {
+	if cfg.RateLimiter == config.RateLimiterMemory {
+		log.Println("Лимитер генераций: в памяти процесса")
+		m := ratelimit.NewMemory(limit)
+		return m, func() { m.Close() }
+	}
+
+	log.Println("Лимитер генераций: через хранилище")
+	return ratelimit.NewStore(db, limit), func() {}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pinghoyk/neurobot/internal/config"
+	"github.com/pinghoyk/neurobot/internal/database"
+)
+
+const migrateUsage = `Использование: bot migrate <команда>
+
+Команды:
+  status     показать текущую версию схемы и список миграций
+  up         применить все новые миграции
+  down [N]   откатить N последних миграций (по умолчанию 1)
+  to V       привести схему к версии V
+  encrypt    зашифровать предпочтения и их снимки в рецептах текущим ключом (ENCRYPTION_KEYS)`
+
+// runMigrate выполняет подкоманду migrate
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		fmt.Println(migrateUsage)
+		return nil
+	}
+
+	cfg := config.LoadDatabase()
+	var db *database.DB
+	var err error
+	switch cfg.StorageBackend {
+	case config.StoragePostgres:
+		db, err = database.OpenPostgres(cfg.DatabaseURL)
+	case config.StorageSQLite:
+		db, err = database.Open(cfg.DatabasePath)
+	default:
+		return fmt.Errorf("хранилище %s не поддерживает миграции", cfg.StorageBackend)
+	}
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "status":
+		// вывод ниже
+	case "up":
+		if err := db.MigrateUp(); err != nil {
+			return err
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("некорректное число шагов: %s", args[1])
+			}
+		}
+		if err := db.MigrateDown(steps); err != nil {
+			return err
+		}
+	case "to":
+		if len(args) < 2 {
+			return fmt.Errorf("укажите версию: bot migrate to V")
+		}
+		target, err := strconv.Atoi(args[1])
+		if err != nil || target < 0 {
+			return fmt.Errorf("некорректная версия: %s", args[1])
+		}
+		if err := db.MigrateTo(target); err != nil {
+			return err
+		}
+	case "encrypt":
+		return runEncrypt(cfg, db)
+	default:
+		return fmt.Errorf("неизвестная команда %q\n\n%s", args[0], migrateUsage)
+	}
+
+	return printMigrationStatus(db)
+}
+
+// runEncrypt шифрует открытые значения и перешифровывает значения старых ключей
+func runEncrypt(cfg *config.Config, db *database.DB) error {
+	keyring, err := cfg.Keyring()
+	if err != nil {
+		return err
+	}
+	if keyring == nil {
+		return fmt.Errorf("ключи шифрования не заданы: укажите ENCRYPTION_KEYS или ENCRYPTION_KEYS_FILE")
+	}
+
+	db.UseKeyring(keyring)
+	rewritten, failed, err := db.EncryptPreferences()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Перешифровано ключом %s: %d, не удалось расшифровать: %d\n", keyring.CurrentID(), rewritten, failed)
+	if failed > 0 {
+		return fmt.Errorf("часть предпочтений или снимков в рецептах зашифрована неизвестным ключом")
+	}
+	return nil
+}
+
+// printMigrationStatus выводит текущую версию и состояние каждой миграции
+func printMigrationStatus(db *database.DB) error {
+	version, err := db.MigrationVersion()
+	if err != nil {
+		return err
+	}
+
+	states, err := db.MigrationStatus()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Текущая версия схемы: %d (последняя доступная: %d)\n\n", version, db.LatestMigrationVersion())
+	for _, s := range states {
+		mark := "[ ]"
+		applied := "не применена"
+		if s.Applied {
+			mark = "[x]"
+			applied = "применена " + s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%s %04d_%s — %s\n", mark, s.Version, s.Name, applied)
+	}
+
+	return nil
+}
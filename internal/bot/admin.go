@@ -0,0 +1,229 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// cmdStats — /stats: пользователи, генерации и ошибки за сегодня
+func (b *Bot) cmdStats(msg *tgbotapi.Message, _ *models.UserState) {
+	dayStart, _ := models.QuotaPeriods(time.Now())
+
+	stats, err := b.db.GetStats(dayStart)
+	if err != nil {
+		log.Printf("Ошибка получения статистики: %v", err)
+		b.sendText(msg.Chat.ID, "❌ Не удалось получить статистику.")
+		return
+	}
+
+	b.sendText(msg.Chat.ID, fmt.Sprintf(`📈 Статистика за сегодня
+
+Пользователей всего: %d
+Активных сегодня: %d
+Генераций сегодня: %d
+Ошибок генерации: %d
+Забанено: %d
+Заблокировали бота: %d`,
+		stats.TotalUsers, stats.ActiveToday, stats.GenerationsToday,
+		stats.ErrorsToday, stats.BannedUsers, stats.InactiveUsers))
+}
+
+// cmdBan — /ban <id> [причина]
+func (b *Bot) cmdBan(msg *tgbotapi.Message, _ *models.UserState) {
+	args := strings.Fields(msg.CommandArguments())
+	targetID, ok := b.parseUserID(msg.Chat.ID, args, "/ban <id> [причина]")
+	if !ok {
+		return
+	}
+
+	if b.isAdmin(targetID) {
+		b.sendText(msg.Chat.ID, "⚠️ Нельзя забанить администратора.")
+		return
+	}
+
+	reason := strings.Join(args[1:], " ")
+	if err := b.db.BanUser(targetID, msg.From.ID, reason); err != nil {
+		log.Printf("Ошибка бана: %v", err)
+		b.sendText(msg.Chat.ID, "❌ Не удалось забанить пользователя.")
+		return
+	}
+
+	log.Printf("Администратор %d забанил пользователя %d: %s", msg.From.ID, targetID, reason)
+	b.sendText(msg.Chat.ID, fmt.Sprintf("🚫 Пользователь %d забанен.", targetID))
+}
+
+// cmdUnban — /unban <id>
+func (b *Bot) cmdUnban(msg *tgbotapi.Message, _ *models.UserState) {
+	targetID, ok := b.parseUserID(msg.Chat.ID, strings.Fields(msg.CommandArguments()), "/unban <id>")
+	if !ok {
+		return
+	}
+
+	if err := b.db.UnbanUser(targetID); err != nil {
+		log.Printf("Ошибка снятия бана: %v", err)
+		b.sendText(msg.Chat.ID, "❌ Не удалось снять бан.")
+		return
+	}
+
+	log.Printf("Администратор %d снял бан с пользователя %d", msg.From.ID, targetID)
+	b.sendText(msg.Chat.ID, fmt.Sprintf("✅ Бан с пользователя %d снят.", targetID))
+}
+
+// cmdQuota — /quota <id> <в день> [в месяц] или /quota <id> reset
+func (b *Bot) cmdQuota(msg *tgbotapi.Message, _ *models.UserState) {
+	const usage = "/quota <id> <в день> [в месяц] — число, «-» без ограничения, 0 запрещает генерации; reset — вернуть квоту по умолчанию"
+
+	args := strings.Fields(msg.CommandArguments())
+	targetID, ok := b.parseUserID(msg.Chat.ID, args, usage)
+	if !ok {
+		return
+	}
+	if len(args) < 2 {
+		b.sendText(msg.Chat.ID, "Использование: "+usage)
+		return
+	}
+
+	override := &models.QuotaOverride{UserID: targetID}
+	if args[1] != "reset" {
+		daily, ok := parseQuotaLimit(args[1])
+		if !ok {
+			b.sendText(msg.Chat.ID, "Использование: "+usage)
+			return
+		}
+		override.Daily = &daily
+
+		if len(args) > 2 {
+			monthly, ok := parseQuotaLimit(args[2])
+			if !ok {
+				b.sendText(msg.Chat.ID, "Использование: "+usage)
+				return
+			}
+			override.Monthly = &monthly
+		}
+	}
+
+	if err := b.db.SetUserQuota(override); err != nil {
+		log.Printf("Ошибка сохранения квоты: %v", err)
+		b.sendText(msg.Chat.ID, "❌ Не удалось сохранить квоту.")
+		return
+	}
+
+	quota := override.Apply(b.opts.Quota)
+	log.Printf("Администратор %d назначил пользователю %d квоту %+v", msg.From.ID, targetID, quota)
+	b.sendText(msg.Chat.ID, fmt.Sprintf("✅ Квота пользователя %d: в день — %s, в месяц — %s.",
+		targetID, formatLimit(quota.Daily), formatLimit(quota.Monthly)))
+}
+
+// cmdUser — /user <id>: предпочтения, состояние и квоты пользователя
+func (b *Bot) cmdUser(msg *tgbotapi.Message, _ *models.UserState) {
+	targetID, ok := b.parseUserID(msg.Chat.ID, strings.Fields(msg.CommandArguments()), "/user <id>")
+	if !ok {
+		return
+	}
+
+	state, err := b.db.GetUserState(targetID)
+	if err != nil {
+		log.Printf("Ошибка получения состояния: %v", err)
+	}
+	prefs, err := b.db.GetUserPreferences(targetID)
+	if err != nil {
+		log.Printf("Ошибка получения предпочтений: %v", err)
+	}
+	user, err := b.db.GetUser(targetID)
+	if err != nil {
+		log.Printf("Ошибка получения пользователя: %v", err)
+	}
+	quota, err := b.userQuota(targetID)
+	if err != nil {
+		log.Printf("Ошибка получения квоты: %v", err)
+	}
+	usage, err := b.db.GetQuotaUsage(targetID, quota, time.Now())
+	if err != nil {
+		log.Printf("Ошибка получения квот: %v", err)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "👤 Пользователь %d\n\n", targetID)
+	if state != nil {
+		fmt.Fprintf(&sb, "Состояние: %s\n", state.CurrentState)
+	}
+	if user != nil {
+		if user.Username != "" {
+			fmt.Fprintf(&sb, "Username: @%s\n", user.Username)
+		}
+		fmt.Fprintf(&sb, "Имя: %s\nЯзык: %s\n", user.FirstName, user.LanguageCode)
+		if !user.FirstSeen.IsZero() {
+			fmt.Fprintf(&sb, "Первый визит: %s\nПоследний визит: %s\n",
+				user.FirstSeen.Format("02.01.2006 15:04"), user.LastSeen.Format("02.01.2006 15:04"))
+		}
+		fmt.Fprintf(&sb, "Заблокировал бота: %s\nЗабанен: %s", yesNo(user.IsBlocked), yesNo(user.IsBanned))
+		if user.IsBanned && user.BanReason != "" {
+			fmt.Fprintf(&sb, " (%s)", user.BanReason)
+		}
+		sb.WriteString("\n\n")
+	}
+	if prefs == nil {
+		sb.WriteString("Предпочтения: не удалось прочитать (см. лог)\n\n")
+	} else {
+		fmt.Fprintf(&sb, "Цель по весу: %s\nОграничения: %s\nЦель: %s\nАллергии: %s\nЛюбит: %s\nНе любит: %s\n\n",
+			prefs.DietaryType, strings.Join(prefs.DietRestrictions, ", "), prefs.Goal, strings.Join(prefs.AllergyList(), ", "), prefs.Likes, prefs.Dislikes)
+	}
+	fmt.Fprintf(&sb, "Генераций сегодня: %d из %s\nГенераций за месяц: %d из %s",
+		usage.UsedToday, formatLimit(usage.Daily), usage.UsedMonth, formatLimit(usage.Monthly))
+
+	b.sendText(msg.Chat.ID, sb.String())
+}
+
+// parseUserID разбирает ID пользователя из первого аргумента команды
+func (b *Bot) parseUserID(chatID int64, args []string, usage string) (int64, bool) {
+	if len(args) == 0 {
+		b.sendText(chatID, "Использование: "+usage)
+		return 0, false
+	}
+
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || userID <= 0 {
+		b.sendText(chatID, "⚠️ Некорректный ID пользователя: "+args[0])
+		return 0, false
+	}
+	return userID, true
+}
+
+// sendText отправляет простое сообщение без разметки (пользовательские данные могут сломать Markdown)
+func (b *Bot) sendText(chatID int64, text string) {
+	if _, err := b.send(chatID, tgbotapi.NewMessage(chatID, text)); err != nil {
+		log.Printf("Ошибка отправки сообщения: %v", err)
+	}
+}
+
+func formatLimit(limit int) string {
+	if limit == models.QuotaUnlimited {
+		return "∞"
+	}
+	return strconv.Itoa(limit)
+}
+
+// parseQuotaLimit разбирает лимит из /quota: неотрицательное число или «-» (без ограничения)
+func parseQuotaLimit(s string) (int, bool) {
+	if s == "-" {
+		return models.QuotaUnlimited, true
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		return 0, false
+	}
+	return v, true
+}
+
+func yesNo(v bool) string {
+	if v {
+		return "да"
+	}
+	return "нет"
+}
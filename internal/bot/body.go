@@ -0,0 +1,197 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pinghoyk/neurobot/pkg/locales"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// showBodyMenu отображает параметры тела и рассчитанные нормы.
+// notice выводится над текстом меню (например, сообщение об успешном сохранении).
+func (b *Bot) showBodyMenu(chatID, userID int64, editMsgID int, notice string) {
+	l := locales.Get()
+
+	prefs, ok := b.userPreferences(chatID, userID, editMsgID)
+	if !ok {
+		return
+	}
+
+	details := l.BodyMenu.Empty
+	fillButton := l.BodyMenu.Buttons.Fill
+	if prefs.Body.Complete() {
+		details = formatBodyProfile(prefs.Body) + "\n\n" + formatTargets(prefs)
+		fillButton = l.BodyMenu.Buttons.Refill
+	}
+
+	text := fmt.Sprintf(l.BodyMenu.Text, details)
+	if notice != "" {
+		text = notice + "\n\n" + text
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fillButton, "body:start"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.BodyMenu.Buttons.BackToSettings, "menu:settings"),
+		),
+	)
+
+	b.sendOrEditMessage(chatID, userID, editMsgID, text, keyboard, models.StateSettingsBody)
+}
+
+// showBodyStep показывает шаг мастера параметров тела
+func (b *Bot) showBodyStep(chatID, userID int64, editMsgID int, step, notice string) {
+	l := locales.Get()
+
+	var text string
+	var rows [][]tgbotapi.InlineKeyboardButton
+	switch step {
+	case models.StateSettingsBodySex:
+		text = l.BodyMenu.SexText
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.BodyMenu.Sex.Male, "body:sex:"+models.SexMale),
+			tgbotapi.NewInlineKeyboardButtonData(l.BodyMenu.Sex.Female, "body:sex:"+models.SexFemale),
+		))
+	case models.StateSettingsBodyAge:
+		text = l.BodyMenu.AgeText
+	case models.StateSettingsBodyHeight:
+		text = l.BodyMenu.HeightText
+	case models.StateSettingsBodyWeight:
+		text = l.BodyMenu.WeightText
+	case models.StateSettingsBodyActivity:
+		text = l.BodyMenu.ActivityText
+		for _, a := range models.ActivityLevels {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(a.Name, "body:act:"+a.Code),
+			))
+		}
+	}
+
+	if notice != "" {
+		text = notice + "\n\n" + text
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(l.BodyMenu.Buttons.Cancel, "menu:body"),
+	))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	b.sendOrEditMessage(chatID, userID, editMsgID, text, keyboard, step)
+}
+
+// handleBodyCallback обрабатывает кнопки мастера: start, sex:<m|f>, act:<код>
+func (b *Bot) handleBodyCallback(chatID, userID int64, msgID int, data string) {
+	if data == "start" {
+		b.showBodyStep(chatID, userID, msgID, models.StateSettingsBodySex, "")
+		return
+	}
+
+	field, value, _ := strings.Cut(data, ":")
+	profile := b.bodyDraft(userID)
+
+	switch {
+	case field == "sex" && (value == models.SexMale || value == models.SexFemale):
+		profile.Sex = value
+		if b.saveBodyProfile(profile) {
+			b.showBodyStep(chatID, userID, msgID, models.StateSettingsBodyAge, "")
+		}
+	case field == "act":
+		if _, ok := models.ActivityLevelByCode(value); !ok {
+			log.Printf("Некорректный уровень активности: %q", value)
+			return
+		}
+		profile.Activity = value
+		if b.saveBodyProfile(profile) {
+			b.showBodyMenu(chatID, userID, msgID, locales.Get().BodyMenu.Success)
+		}
+	default:
+		log.Printf("Некорректный callback параметров тела: %q", data)
+	}
+}
+
+// handleBodyInput обрабатывает ввод возраста, роста и веса
+func (b *Bot) handleBodyInput(chatID, userID int64, text string, editMsgID int, step string) {
+	l := locales.Get()
+
+	value, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(text), ",", "."), 64)
+
+	profile := b.bodyDraft(userID)
+	var next string
+	var lo, hi int
+	switch step {
+	case models.StateSettingsBodyAge:
+		lo, hi, next = models.MinAge, models.MaxAge, models.StateSettingsBodyHeight
+		profile.Age = int(value)
+	case models.StateSettingsBodyHeight:
+		lo, hi, next = models.MinHeightCm, models.MaxHeightCm, models.StateSettingsBodyWeight
+		profile.HeightCm = value
+	case models.StateSettingsBodyWeight:
+		lo, hi, next = models.MinWeightKg, models.MaxWeightKg, models.StateSettingsBodyActivity
+		profile.WeightKg = value
+	}
+
+	// NaN не попадает под сравнения с границами, поэтому проверяется отдельно
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) || value < float64(lo) || value > float64(hi) {
+		b.showBodyStep(chatID, userID, editMsgID, step, fmt.Sprintf(l.BodyMenu.Invalid, lo, hi))
+		return
+	}
+
+	if b.saveBodyProfile(profile) {
+		b.showBodyStep(chatID, userID, editMsgID, next, "")
+	}
+}
+
+// bodyDraft возвращает сохранённые параметры тела или пустой профиль для мастера
+func (b *Bot) bodyDraft(userID int64) *models.BodyProfile {
+	profile, err := b.db.GetBodyProfile(userID)
+	if err != nil {
+		log.Printf("Ошибка получения параметров тела: %v", err)
+	}
+	if profile == nil {
+		profile = &models.BodyProfile{UserID: userID}
+	}
+	return profile
+}
+
+// saveBodyProfile сохраняет шаг мастера; false — сохранить не удалось
+func (b *Bot) saveBodyProfile(profile *models.BodyProfile) bool {
+	if err := b.db.SaveBodyProfile(profile); err != nil {
+		log.Printf("Ошибка сохранения параметров тела: %v", err)
+		return false
+	}
+	return true
+}
+
+// formatBodyProfile форматирует параметры тела
+func formatBodyProfile(p *models.BodyProfile) string {
+	l := locales.Get()
+
+	sex := l.BodyMenu.Sex.Female
+	if p.Sex == models.SexMale {
+		sex = l.BodyMenu.Sex.Male
+	}
+	activity, _ := models.ActivityLevelByCode(p.Activity)
+
+	return fmt.Sprintf(l.BodyMenu.Profile, sex, p.Age, formatNumber(p.HeightCm), formatNumber(p.WeightKg), activity.Name)
+}
+
+// formatTargets форматирует суточную норму и норму на приём пищи
+func formatTargets(prefs *models.UserPreferences) string {
+	p := prefs.Body
+	day := p.DailyTargets(prefs.DietaryType, prefs.DietRestrictions)
+	meal := p.MealTargets(prefs.DietaryType, prefs.DietRestrictions)
+	return fmt.Sprintf(locales.Get().BodyMenu.Targets,
+		day.Calories, day.Protein, day.Fat, day.Carbs,
+		meal.Calories, meal.Protein, meal.Fat, meal.Carbs)
+}
+
+// formatNumber выводит число без лишних нулей и с запятой: 68,5
+func formatNumber(v float64) string {
+	return strings.ReplaceAll(strconv.FormatFloat(v, 'f', -1, 64), ".", ",")
+}
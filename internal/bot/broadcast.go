@@ -0,0 +1,390 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+const (
+	broadcastPollInterval = 30 * time.Second
+	broadcastLease        = 2 * time.Minute // продлевается после каждого сообщения
+	broadcastBatchSize    = 100
+
+	// Рассылка идёт медленнее общего лимита Telegram, чтобы оставить запас для ответов пользователям
+	broadcastSendInterval = time.Second / 20
+
+	activeSegmentPeriod = 30 * 24 * time.Hour
+)
+
+// Сегменты рассылки: all, active, diet:<none|lose|gain>
+const (
+	segmentAll    = "all"
+	segmentActive = "active"
+	segmentDiet   = "diet:"
+)
+
+var segmentDiets = map[string]string{
+	"none": "Обычное",
+	"lose": "Похудение",
+	"gain": "Набор массы",
+}
+
+// broadcastButtonRe — строка вида [Текст](https://ссылка) в конце сообщения рассылки
+var broadcastButtonRe = regexp.MustCompile(`^\[(.+)\]\((https?://\S+)\)$`)
+
+const broadcastUsage = `Использование:
+/broadcast [сегмент]
+Текст сообщения
+[Кнопка](https://ссылка)
+
+Сегменты: all (по умолчанию), active — запрашивали рецепты за 30 дней, diet:none, diet:lose, diet:gain.
+Первая строка из одного слова считается сегментом: для текста из одного слова укажите сегмент явно.
+Строки-кнопки в конце сообщения необязательны. Перед отправкой бот покажет предпросмотр.
+
+/broadcast status <id> — прогресс рассылки
+/broadcast cancel <id> — отменить рассылку`
+
+// cmdBroadcast — /broadcast: показывает предпросмотр рассылки, её статус или отменяет её
+func (b *Bot) cmdBroadcast(msg *tgbotapi.Message, _ *models.UserState) {
+	args := strings.TrimSpace(msg.CommandArguments())
+	fields := strings.Fields(args)
+
+	if len(fields) == 2 && (fields[0] == "status" || fields[0] == "cancel") {
+		id, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			b.sendText(msg.Chat.ID, broadcastUsage)
+			return
+		}
+		if fields[0] == "status" {
+			b.showBroadcastStatus(msg.Chat.ID, id)
+		} else {
+			b.cancelBroadcast(msg.Chat.ID, msg.From.ID, id)
+		}
+		return
+	}
+
+	job, ok := parseBroadcast(args)
+	if !ok {
+		b.sendText(msg.Chat.ID, broadcastUsage)
+		return
+	}
+	b.previewBroadcast(msg.Chat.ID, msg.From.ID, job, args)
+}
+
+// previewBroadcast показывает сообщение рассылки так, как его увидят получатели, и просит
+// подтвердить отправку. Аргументы команды ждут подтверждения в состоянии администратора
+// вместе с ID сообщения с кнопками: кнопки прежних предпросмотров не сработают.
+func (b *Bot) previewBroadcast(chatID, adminID int64, job *models.Broadcast, args string) {
+	if _, err := b.send(chatID, broadcastMessage(job, chatID)); err != nil {
+		log.Printf("Ошибка отправки предпросмотра рассылки: %v", err)
+		b.sendText(chatID, "❌ Не удалось показать предпросмотр рассылки.")
+		return
+	}
+
+	confirm := tgbotapi.NewMessage(chatID, fmt.Sprintf("👆 Так сообщение увидят получатели (сегмент: %s). Отправить рассылку?", job.Segment))
+	confirm.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Отправить", "broadcast:send"),
+			tgbotapi.NewInlineKeyboardButtonData("✖️ Отмена", "broadcast:discard"),
+		),
+	)
+	sent, err := b.send(chatID, confirm)
+	if err != nil {
+		log.Printf("Ошибка отправки сообщения: %v", err)
+		return
+	}
+
+	state := &models.UserState{
+		UserID:        adminID,
+		CurrentState:  models.StateBroadcastConfirm,
+		LastMessageID: sent.MessageID,
+		InputData:     strconv.Itoa(sent.MessageID) + "\n" + args,
+	}
+	if err := b.db.SaveUserState(state); err != nil {
+		log.Printf("Ошибка сохранения состояния: %v", err)
+	}
+}
+
+// handleBroadcastCallback обрабатывает подтверждение рассылки: broadcast:send или broadcast:discard
+func (b *Bot) handleBroadcastCallback(chatID, userID int64, msgID int, action string, state *models.UserState) {
+	if !b.isAdmin(userID) {
+		log.Printf("Попытка подтвердить рассылку без прав администратора: %d", userID)
+		return
+	}
+
+	pendingMsgID, args, _ := strings.Cut(state.InputData, "\n")
+	if state.CurrentState != models.StateBroadcastConfirm || pendingMsgID != strconv.Itoa(msgID) {
+		b.editBroadcastConfirm(chatID, msgID, "⚠️ Предпросмотр устарел: отправьте /broadcast заново.")
+		return
+	}
+
+	state.CurrentState, state.InputData = models.StateMain, ""
+	if err := b.db.SaveUserState(state); err != nil {
+		log.Printf("Ошибка сохранения состояния: %v", err)
+	}
+
+	switch action {
+	case "send":
+		b.queueBroadcast(chatID, userID, msgID, args)
+	case "discard":
+		b.editBroadcastConfirm(chatID, msgID, "✖️ Рассылка отменена, сообщения не отправлялись.")
+	}
+}
+
+// queueBroadcast ставит подтверждённую рассылку в очередь
+func (b *Bot) queueBroadcast(chatID, adminID int64, msgID int, args string) {
+	job, ok := parseBroadcast(args)
+	if !ok {
+		b.editBroadcastConfirm(chatID, msgID, broadcastUsage)
+		return
+	}
+	job.AdminID = adminID
+	job.AdminChatID = chatID
+	job.CreatedAt = time.Now()
+
+	id, err := b.db.CreateBroadcast(job)
+	if err != nil {
+		log.Printf("Ошибка создания рассылки: %v", err)
+		b.editBroadcastConfirm(chatID, msgID, "❌ Не удалось создать рассылку.")
+		return
+	}
+
+	log.Printf("Администратор %d создал рассылку #%d (%s)", adminID, id, job.Segment)
+	b.editBroadcastConfirm(chatID, msgID, fmt.Sprintf("📣 Рассылка #%d поставлена в очередь (сегмент: %s). Отчёт придёт по завершении.", id, job.Segment))
+	b.wakeBroadcasts()
+}
+
+// editBroadcastConfirm заменяет текст сообщения с кнопками подтверждения, убирая кнопки
+func (b *Bot) editBroadcastConfirm(chatID int64, msgID int, text string) {
+	if _, err := b.send(chatID, tgbotapi.NewEditMessageText(chatID, msgID, text)); err != nil {
+		log.Printf("Не удалось отредактировать сообщение: %v", err)
+	}
+}
+
+// parseBroadcast разбирает аргументы /broadcast: сегмент в первой строке,
+// текст и строки-кнопки в конце. Первая строка из одного слова — всегда сегмент:
+// опечатка в нём («activ») не должна превращаться в рассылку всем.
+func parseBroadcast(args string) (*models.Broadcast, bool) {
+	job := &models.Broadcast{Segment: segmentAll}
+
+	header, body, _ := strings.Cut(args, "\n")
+	header = strings.TrimSpace(header)
+	if len(strings.Fields(header)) == 1 {
+		if _, ok := parseSegment(header, time.Time{}); !ok {
+			return nil, false
+		}
+		job.Segment, args = header, body
+	}
+
+	lines := strings.Split(strings.TrimSpace(args), "\n")
+	for len(lines) > 0 {
+		m := broadcastButtonRe.FindStringSubmatch(strings.TrimSpace(lines[len(lines)-1]))
+		if m == nil {
+			break
+		}
+		job.Buttons = append([]models.BroadcastButton{{Text: m[1], URL: m[2]}}, job.Buttons...)
+		lines = lines[:len(lines)-1]
+	}
+
+	job.Text = strings.TrimSpace(strings.Join(lines, "\n"))
+	return job, job.Text != ""
+}
+
+// parseSegment превращает сегмент рассылки в фильтр получателей.
+// Активность отсчитывается от createdAt, чтобы после перезапуска выборка не сдвигалась.
+func parseSegment(segment string, createdAt time.Time) (models.RecipientFilter, bool) {
+	switch {
+	case segment == segmentAll:
+		return models.RecipientFilter{}, true
+	case segment == segmentActive:
+		return models.RecipientFilter{ActiveSince: createdAt.Add(-activeSegmentPeriod)}, true
+	case strings.HasPrefix(segment, segmentDiet):
+		diet, ok := segmentDiets[strings.TrimPrefix(segment, segmentDiet)]
+		return models.RecipientFilter{DietaryType: diet}, ok
+	}
+	return models.RecipientFilter{}, false
+}
+
+func (b *Bot) showBroadcastStatus(chatID, id int64) {
+	job, err := b.db.GetBroadcast(id)
+	if err != nil {
+		log.Printf("Ошибка получения рассылки: %v", err)
+		b.sendText(chatID, "❌ Не удалось получить рассылку.")
+		return
+	}
+	if job == nil {
+		b.sendText(chatID, fmt.Sprintf("⚠️ Рассылка #%d не найдена.", id))
+		return
+	}
+
+	b.sendText(chatID, formatBroadcastReport(job))
+}
+
+func (b *Bot) cancelBroadcast(chatID, adminID, id int64) {
+	cancelled, err := b.db.CancelBroadcast(id, time.Now())
+	if err != nil {
+		log.Printf("Ошибка отмены рассылки: %v", err)
+		b.sendText(chatID, "❌ Не удалось отменить рассылку.")
+		return
+	}
+	if !cancelled {
+		b.sendText(chatID, fmt.Sprintf("⚠️ Рассылка #%d не найдена или уже завершена.", id))
+		return
+	}
+
+	log.Printf("Администратор %d отменил рассылку #%d", adminID, id)
+	b.sendText(chatID, fmt.Sprintf("🛑 Рассылка #%d отменена.", id))
+}
+
+// wakeBroadcasts будит обработчик рассылок, не дожидаясь очередного опроса
+func (b *Bot) wakeBroadcasts() {
+	select {
+	case b.broadcastWake <- struct{}{}:
+	default:
+	}
+}
+
+// runBroadcasts обрабатывает очередь рассылок до остановки бота
+func (b *Bot) runBroadcasts(ctx context.Context) {
+	ticker := time.NewTicker(broadcastPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			job, err := b.db.ClaimBroadcast(time.Now(), broadcastLease)
+			if err != nil {
+				log.Printf("Ошибка получения рассылки: %v", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			b.deliverBroadcast(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-b.broadcastWake:
+		}
+	}
+}
+
+// deliverBroadcast отправляет рассылку, начиная с получателя после job.LastUserID,
+// и сохраняет прогресс после каждого сообщения
+func (b *Bot) deliverBroadcast(ctx context.Context, job *models.Broadcast) {
+	filter, ok := parseSegment(job.Segment, job.CreatedAt)
+	if !ok {
+		// Повтор не поможет: без отмены рассылку подхватывали бы после каждой аренды
+		log.Printf("Рассылка #%d: неизвестный сегмент %q, рассылка отменена", job.ID, job.Segment)
+		if _, err := b.db.CancelBroadcast(job.ID, time.Now()); err != nil {
+			log.Printf("Рассылка #%d: ошибка отмены: %v", job.ID, err)
+			return
+		}
+		b.sendText(job.AdminChatID, fmt.Sprintf("❌ Рассылка #%d отменена: неизвестный сегмент «%s».", job.ID, job.Segment))
+		return
+	}
+
+	if job.LastUserID > 0 {
+		log.Printf("Рассылка #%d продолжается после пользователя %d", job.ID, job.LastUserID)
+	}
+
+	pace := &throttle{interval: broadcastSendInterval, burst: 1}
+	for {
+		userIDs, err := b.db.ListBroadcastRecipients(filter, job.LastUserID, broadcastBatchSize)
+		if err != nil {
+			// Аренда истечёт, и рассылка будет подхвачена заново
+			log.Printf("Рассылка #%d: ошибка получения получателей: %v", job.ID, err)
+			return
+		}
+		if len(userIDs) == 0 {
+			break
+		}
+
+		for _, userID := range userIDs {
+			if err := sleepCtx(ctx, pace.reserve(time.Now())); err != nil {
+				return
+			}
+
+			_, err := b.sender.send(ctx, userID, broadcastMessage(job, userID))
+			if ctx.Err() != nil {
+				// Остановка бота: этот получатель будет обработан после перезапуска
+				return
+			}
+			b.checkBlocked(userID, err)
+
+			switch {
+			case err == nil:
+				job.Delivered++
+			case errors.Is(err, errBotBlocked):
+				job.Blocked++
+			default:
+				job.Failed++
+				log.Printf("Рассылка #%d: ошибка отправки пользователю %d: %v", job.ID, userID, err)
+			}
+			job.LastUserID = userID
+
+			running, err := b.db.SaveBroadcastProgress(job, time.Now().Add(broadcastLease))
+			if err != nil {
+				log.Printf("Рассылка #%d: ошибка сохранения прогресса: %v", job.ID, err)
+				return
+			}
+			if !running {
+				log.Printf("Рассылка #%d остановлена", job.ID)
+				return
+			}
+		}
+	}
+
+	if err := b.db.FinishBroadcast(job.ID, time.Now()); err != nil {
+		log.Printf("Рассылка #%d: ошибка завершения: %v", job.ID, err)
+		return
+	}
+	job.Status = models.BroadcastDone
+
+	log.Printf("Рассылка #%d завершена: доставлено %d, не доставлено %d, заблокировали %d",
+		job.ID, job.Delivered, job.Failed, job.Blocked)
+	b.sendText(job.AdminChatID, formatBroadcastReport(job))
+}
+
+// broadcastMessage собирает сообщение рассылки без разметки, с кнопками-ссылками
+func broadcastMessage(job *models.Broadcast, chatID int64) tgbotapi.MessageConfig {
+	msg := tgbotapi.NewMessage(chatID, job.Text)
+	if len(job.Buttons) == 0 {
+		return msg
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, button := range job.Buttons {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonURL(button.Text, button.URL)))
+	}
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return msg
+}
+
+func formatBroadcastReport(job *models.Broadcast) string {
+	status := map[string]string{
+		models.BroadcastPending:   "в очереди",
+		models.BroadcastRunning:   "выполняется",
+		models.BroadcastDone:      "завершена",
+		models.BroadcastCancelled: "отменена",
+	}[job.Status]
+
+	return fmt.Sprintf(`📣 Рассылка #%d — %s
+Сегмент: %s
+
+Доставлено: %d
+Не доставлено: %d
+Заблокировали бота: %d`,
+		job.ID, status, job.Segment, job.Delivered, job.Failed, job.Blocked)
+}
@@ -0,0 +1,123 @@
+package bot
+
+import (
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// commandHandler обрабатывает команду бота
+type commandHandler func(msg *tgbotapi.Message, state *models.UserState)
+
+// registerCommands составляет таблицу команд. Права доступа навешиваются
+// через middleware (adminOnly), а не проверяются внутри обработчиков.
+func (b *Bot) registerCommands() {
+	b.commands = map[string]commandHandler{
+		"start":     b.menuCommand(b.showMainMenu),
+		"settings":  b.menuCommand(b.showSettings),
+		"help":      b.menuCommand(b.showHelp),
+		"limits":    b.menuCommand(b.showLimits),
+		"history":   b.menuCommand(b.showHistory),
+		"favorites": b.menuCommand(b.showFavorites),
+		"shopping":  b.menuCommand(b.showShopping),
+		"pantry":    b.menuCommand(b.showPantry),
+		"plan":      b.menuCommand(b.showPlan),
+		"today":     b.menuCommand(b.showToday),
+		"week":      b.menuCommand(b.showWeek),
+		"notify":    b.menuCommand(b.showNotify),
+		"mydata":    b.cmdMyData,
+		"deleteme":  b.menuCommand(b.showDeleteConfirm),
+
+		// Команды администратора
+		"stats":     b.adminOnly(b.cmdStats),
+		"ban":       b.adminOnly(b.cmdBan),
+		"unban":     b.adminOnly(b.cmdUnban),
+		"quota":     b.adminOnly(b.cmdQuota),
+		"broadcast": b.adminOnly(b.cmdBroadcast),
+		"user":      b.adminOnly(b.cmdUser),
+		"feedback":  b.adminOnly(b.cmdFeedback),
+	}
+}
+
+// menuCommand превращает показ экрана меню в обработчик команды
+func (b *Bot) menuCommand(show func(chatID, userID int64, editMsgID int)) commandHandler {
+	return func(msg *tgbotapi.Message, state *models.UserState) {
+		show(msg.Chat.ID, msg.From.ID, state.LastMessageID)
+	}
+}
+
+// adminOnly пропускает команду только для администраторов из конфигурации
+func (b *Bot) adminOnly(next commandHandler) commandHandler {
+	return func(msg *tgbotapi.Message, state *models.UserState) {
+		if !b.isAdmin(msg.From.ID) {
+			log.Printf("Попытка вызвать /%s без прав администратора: %d", msg.Command(), msg.From.ID)
+			b.sendText(msg.Chat.ID, "⛔ Команда доступна только администраторам.")
+			return
+		}
+		next(msg, state)
+	}
+}
+
+// trackUsers обновляет профиль пользователя при каждом обновлении
+// и отмечает заблокировавших бота по событию my_chat_member
+func (b *Bot) trackUsers(next func(tgbotapi.Update)) func(tgbotapi.Update) {
+	return func(update tgbotapi.Update) {
+		if member := update.MyChatMember; member != nil && member.Chat.IsPrivate() {
+			if member.NewChatMember.WasKicked() {
+				if err := b.db.MarkUserInactive(member.From.ID, "my_chat_member: kicked"); err != nil {
+					log.Printf("Ошибка отметки неактивного пользователя: %v", err)
+				}
+				return
+			}
+			b.upsertUser(&member.From)
+			return
+		}
+
+		if from := update.SentFrom(); from != nil {
+			b.upsertUser(from)
+		}
+		next(update)
+	}
+}
+
+func (b *Bot) upsertUser(from *tgbotapi.User) {
+	user := &models.User{
+		ID:           from.ID,
+		Username:     from.UserName,
+		FirstName:    from.FirstName,
+		LanguageCode: from.LanguageCode,
+	}
+	if err := b.db.UpsertUser(user, time.Now()); err != nil {
+		log.Printf("Ошибка сохранения пользователя: %v", err)
+	}
+}
+
+// rejectBanned отбрасывает обновления от пользователей, заблокированных администратором.
+// Если проверить бан не удалось, обновление тоже отбрасывается: администраторов это не касается.
+func (b *Bot) rejectBanned(next func(tgbotapi.Update)) func(tgbotapi.Update) {
+	return func(update tgbotapi.Update) {
+		if from := update.SentFrom(); from != nil && !b.isAdmin(from.ID) {
+			banned, err := b.db.IsBanned(from.ID)
+			if err != nil {
+				log.Printf("Ошибка проверки бана пользователя %d, обновление отброшено: %v", from.ID, err)
+				return
+			}
+			if banned {
+				return
+			}
+		}
+		next(update)
+	}
+}
+
+// isAdmin проверяет, указан ли пользователь в ADMIN_IDS
+func (b *Bot) isAdmin(userID int64) bool {
+	for _, id := range b.opts.AdminIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,269 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pinghoyk/neurobot/pkg/locales"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+const (
+	// diaryBarWidth — длина полоски графика в символах
+	diaryBarWidth = 10
+	// maxDiaryButtons — сколько записей за день можно удалить кнопками
+	maxDiaryButtons = 10
+)
+
+var diaryWeekdays = [...]string{"Вс", "Пн", "Вт", "Ср", "Чт", "Пт", "Сб"}
+
+// showToday — дневник питания за сегодня для команды /today
+func (b *Bot) showToday(chatID, userID int64, editMsgID int) {
+	b.showDiaryDay(chatID, userID, editMsgID, "")
+}
+
+// showWeek — сводка дневника за последние 7 дней для команды /week
+func (b *Bot) showWeek(chatID, userID int64, editMsgID int) {
+	b.showDiaryWeek(chatID, userID, editMsgID)
+}
+
+// showDiaryDay отображает съеденное за сегодня и график выполнения суточной нормы
+func (b *Bot) showDiaryDay(chatID, userID int64, editMsgID int, notice string) {
+	l := locales.Get()
+
+	today := b.userToday(userID)
+	day := today.Format(models.DayLayout)
+	entries, err := b.db.ListDiaryEntries(userID, day, day)
+	if err != nil {
+		log.Printf("Ошибка получения дневника питания: %v", err)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(l.Diary.Title + "\n" + formatPlanDay(today, today) + "\n\n")
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if len(entries) == 0 {
+		sb.WriteString(l.Diary.Empty)
+	} else {
+		var total models.Nutrition
+		var row []tgbotapi.InlineKeyboardButton
+		for i, e := range entries {
+			total = total.Add(e.Nutrition)
+			sb.WriteString(fmt.Sprintf("• %s — %.0f ккал\n", e.Title, e.Nutrition.Calories))
+
+			if i < maxDiaryButtons {
+				row = append(row, tgbotapi.NewInlineKeyboardButtonData("🗑 "+e.Title, fmt.Sprintf("diary:del:%d", e.ID)))
+				if len(row) == 2 {
+					rows = append(rows, row)
+					row = nil
+				}
+			}
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+		sb.WriteString("\n" + b.diaryChart(userID, total))
+	}
+
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.Diary.Buttons.Week, "diary:week"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.Diary.Buttons.BackToMain, "menu:main"),
+		),
+	)
+
+	text := sb.String()
+	if notice != "" {
+		text = notice + "\n\n" + text
+	}
+	b.sendOrEditMessage(chatID, userID, editMsgID, text, tgbotapi.NewInlineKeyboardMarkup(rows...), models.StateDiary)
+}
+
+// showDiaryWeek отображает калории по дням за последние 7 дней и среднее за день по БЖУ
+func (b *Bot) showDiaryWeek(chatID, userID int64, editMsgID int) {
+	l := locales.Get()
+
+	today := b.userToday(userID)
+	start := today.AddDate(0, 0, -6)
+	entries, err := b.db.ListDiaryEntries(userID, start.Format(models.DayLayout), today.Format(models.DayLayout))
+	if err != nil {
+		log.Printf("Ошибка получения дневника питания: %v", err)
+		return
+	}
+
+	byDay := make(map[string]models.Nutrition)
+	for _, e := range entries {
+		byDay[e.Day] = byDay[e.Day].Add(e.Nutrition)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(l.Diary.WeekTitle, start.Format("02.01"), today.Format("02.01")) + "\n\n")
+	if len(byDay) == 0 {
+		sb.WriteString(l.Diary.WeekEmpty)
+	} else {
+		targets := models.DailyNutritionTargets(b.diaryPreferences(userID))
+
+		var total models.Nutrition
+		sb.WriteString("```\n")
+		for d := start; !d.After(today); d = d.AddDate(0, 0, 1) {
+			n, ok := byDay[d.Format(models.DayLayout)]
+			calories := "—"
+			if ok {
+				total = total.Add(n)
+				calories = strconv.Itoa(int(math.Round(n.Calories)))
+			}
+			sb.WriteString(fmt.Sprintf("%s %s %s %5s\n", diaryWeekdays[d.Weekday()], d.Format("02.01"),
+				diaryBar(n.Calories, float64(targets.Calories)), calories))
+		}
+		sb.WriteString("```\n")
+
+		days := float64(len(byDay))
+		average := models.Nutrition{
+			Calories: total.Calories / days,
+			Protein:  total.Protein / days,
+			Fat:      total.Fat / days,
+			Carbs:    total.Carbs / days,
+		}
+		sb.WriteString(fmt.Sprintf(l.Diary.Average, len(byDay)) + "\n" + b.diaryChart(userID, average))
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.Diary.Buttons.Today, "diary:today"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.Diary.Buttons.BackToMain, "menu:main"),
+		),
+	)
+	b.sendOrEditMessage(chatID, userID, editMsgID, sb.String(), keyboard, models.StateDiary)
+}
+
+// handleDiaryCallback обрабатывает кнопки дневника: today, week, del:<id>
+func (b *Bot) handleDiaryCallback(chatID, userID int64, msgID int, data string) {
+	action, arg, _ := strings.Cut(data, ":")
+	switch action {
+	case "today":
+		b.showDiaryDay(chatID, userID, msgID, "")
+	case "week":
+		b.showDiaryWeek(chatID, userID, msgID)
+	case "del":
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			log.Printf("Некорректный ID записи дневника: %q", data)
+			return
+		}
+		if _, err := b.db.DeleteDiaryEntry(userID, id); err != nil {
+			log.Printf("Ошибка удаления записи дневника: %v", err)
+		}
+		b.showDiaryDay(chatID, userID, msgID, "")
+	default:
+		log.Printf("Некорректный callback дневника: %q", data)
+	}
+}
+
+// logRecipe записывает в дневник порцию рецепта с пищевой ценностью из его текста.
+// Дневник приходит отдельным сообщением, чтобы рецепт остался на экране.
+func (b *Bot) logRecipe(chatID, userID, recipeID int64) {
+	l := locales.Get()
+
+	recipe, err := b.db.GetRecipe(userID, recipeID)
+	if err != nil {
+		log.Printf("Ошибка получения рецепта: %v", err)
+		return
+	}
+	if recipe == nil {
+		b.sendText(chatID, l.Recipes.NotFound)
+		return
+	}
+
+	nutrition, ok := models.ParseNutrition(recipe.Text)
+	if !ok {
+		b.sendText(chatID, l.Diary.NoNutrition)
+		return
+	}
+
+	title := recipe.Title
+	if title == "" {
+		title = models.RecipeTitle(recipe.Text)
+	}
+	// Название попадёт в текст с разметкой и на кнопку удаления
+	title = strings.NewReplacer("*", "", "_", "", "`", "", "[", "").Replace(title)
+
+	entry := &models.DiaryEntry{
+		UserID:    userID,
+		Day:       b.userToday(userID).Format(models.DayLayout),
+		RecipeID:  recipe.ID,
+		Title:     title,
+		Nutrition: nutrition,
+		CreatedAt: time.Now(),
+	}
+	if _, err := b.db.AddDiaryEntry(entry); err != nil {
+		log.Printf("Ошибка записи в дневник питания: %v", err)
+		return
+	}
+	b.showDiaryDay(chatID, userID, 0, fmt.Sprintf(l.Diary.Logged, title, int(math.Round(nutrition.Calories))))
+}
+
+// diaryChart — график «съедено / норма» по калориям и БЖУ моноширинным блоком
+func (b *Bot) diaryChart(userID int64, n models.Nutrition) string {
+	l := locales.Get()
+
+	prefs := b.diaryPreferences(userID)
+	targets := models.DailyNutritionTargets(prefs)
+
+	lines := []struct {
+		name          string
+		value, target float64
+		unit          string
+	}{
+		{l.Diary.Fields.Calories, n.Calories, float64(targets.Calories), ""},
+		{l.Diary.Fields.Protein, n.Protein, float64(targets.Protein), " г"},
+		{l.Diary.Fields.Fat, n.Fat, float64(targets.Fat), " г"},
+		{l.Diary.Fields.Carbs, n.Carbs, float64(targets.Carbs), " г"},
+	}
+
+	var sb strings.Builder
+	sb.WriteString("```\n")
+	for _, line := range lines {
+		percent := 0
+		if line.target > 0 {
+			percent = int(math.Round(line.value / line.target * 100))
+		}
+		sb.WriteString(fmt.Sprintf("%-8s %s %3d%% %.0f/%.0f%s\n",
+			line.name, diaryBar(line.value, line.target), percent, line.value, line.target, line.unit))
+	}
+	sb.WriteString("```")
+
+	if prefs == nil || !prefs.Body.Complete() {
+		sb.WriteString("\n" + l.Diary.DefaultTargets)
+	}
+	return sb.String()
+}
+
+// diaryPreferences возвращает предпочтения пользователя для расчёта нормы;
+// nil, если их не удалось прочитать, — тогда считается норма по умолчанию
+func (b *Bot) diaryPreferences(userID int64) *models.UserPreferences {
+	prefs, err := b.db.GetUserPreferences(userID)
+	if err != nil {
+		log.Printf("Ошибка получения предпочтений: %v", err)
+	}
+	return prefs
+}
+
+// diaryBar — полоска «██████░░░░» с долей value от target; сверх нормы полоска заполнена целиком
+func diaryBar(value, target float64) string {
+	filled := 0
+	if target > 0 {
+		filled = int(math.Round(value / target * diaryBarWidth))
+	}
+	filled = min(max(filled, 0), diaryBarWidth)
+	return strings.Repeat("█", filled) + strings.Repeat("░", diaryBarWidth-filled)
+}
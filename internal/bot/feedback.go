@@ -0,0 +1,292 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pinghoyk/neurobot/pkg/locales"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// maxFeedbackComment — максимальная длина комментария к оценке, символов
+const maxFeedbackComment = 500
+
+// feedbackReportDays — период отчёта /feedback по умолчанию
+const feedbackReportDays = 30
+
+// feedbackRow — кнопки оценки под рецептом; текущая оценка отмечена галочкой
+func feedbackRow(id, suffix string, rating int) []tgbotapi.InlineKeyboardButton {
+	l := locales.Get()
+
+	like, dislike := l.Feedback.Buttons.Like, l.Feedback.Buttons.Dislike
+	switch rating {
+	case models.RatingLike:
+		like = "✅ " + like
+	case models.RatingDislike:
+		dislike = "✅ " + dislike
+	}
+	return tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(like, "feedback:like:"+id+suffix),
+		tgbotapi.NewInlineKeyboardButtonData(dislike, "feedback:dislike:"+id+suffix),
+	)
+}
+
+// feedbackReasonsKeyboard — выбор причины отрицательной оценки вместо кнопок рецепта
+func feedbackReasonsKeyboard(id, suffix string) tgbotapi.InlineKeyboardMarkup {
+	l := locales.Get()
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, r := range models.FeedbackReasons {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(r.Name, "feedback:"+r.Code+":"+id+suffix))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(l.Feedback.Buttons.Other, "feedback:"+models.FeedbackReasonOther+":"+id+suffix),
+		tgbotapi.NewInlineKeyboardButtonData(l.Feedback.Buttons.Skip, "feedback:skip:"+id+suffix),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// handleFeedbackCallback обрабатывает оценку рецепта: <действие>:<id>[:<список>].
+// Действие — like, dislike, skip, other или код причины из models.FeedbackReasons.
+func (b *Bot) handleFeedbackCallback(chatID, userID int64, msgID int, data string) {
+	parts := strings.Split(data, ":")
+	if len(parts) < 2 {
+		log.Printf("Некорректный callback оценки: %q", data)
+		return
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		log.Printf("Некорректный ID рецепта: %q", data)
+		return
+	}
+
+	var list *recipeList
+	if len(parts) > 2 {
+		parsed, ok := parseRecipeList(parts[2])
+		if !ok {
+			log.Printf("Некорректный список рецептов: %q", data)
+			return
+		}
+		list = &parsed
+	}
+
+	recipe, err := b.db.GetRecipe(userID, id)
+	if err != nil {
+		log.Printf("Ошибка получения рецепта: %v", err)
+		return
+	}
+	if recipe == nil {
+		b.sendText(chatID, locales.Get().Recipes.NotFound)
+		return
+	}
+
+	action := parts[0]
+	markup := recipeKeyboard(recipe, models.RatingDislike, list)
+	switch {
+	case action == "like":
+		if !b.saveFeedback(recipe, models.RatingLike, "", "") {
+			return
+		}
+		markup = recipeKeyboard(recipe, models.RatingLike, list)
+	case action == "dislike":
+		if !b.saveFeedback(recipe, models.RatingDislike, "", "") {
+			return
+		}
+		markup = feedbackReasonsKeyboard(parts[1], listSuffix(list))
+	case action == "skip":
+	case action == models.FeedbackReasonOther:
+		if !b.saveFeedback(recipe, models.RatingDislike, action, "") {
+			return
+		}
+		b.askFeedbackComment(chatID, userID, id)
+	case models.FeedbackReasonName(action) != action:
+		if !b.saveFeedback(recipe, models.RatingDislike, action, "") {
+			return
+		}
+	default:
+		log.Printf("Неизвестное действие оценки: %q", data)
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageReplyMarkup(chatID, msgID, markup)
+	if err := b.request(chatID, edit); err != nil {
+		log.Printf("Не удалось обновить кнопки рецепта: %v", err)
+	}
+}
+
+// askFeedbackComment отправляет отдельное сообщение с просьбой описать проблему;
+// ID рецепта запоминается в состоянии до ответа пользователя
+func (b *Bot) askFeedbackComment(chatID, userID, recipeID int64) {
+	msg := tgbotapi.NewMessage(chatID, locales.Get().Feedback.CommentText)
+	msg.ParseMode = "Markdown"
+	sent, err := b.send(chatID, msg)
+	if err != nil {
+		log.Printf("Ошибка отправки сообщения: %v", err)
+		return
+	}
+
+	state := &models.UserState{
+		UserID:        userID,
+		CurrentState:  models.StateFeedbackComment,
+		LastMessageID: sent.MessageID,
+		InputData:     strconv.FormatInt(recipeID, 10),
+	}
+	if err := b.db.SaveUserState(state); err != nil {
+		log.Printf("Ошибка сохранения состояния: %v", err)
+	}
+}
+
+// handleFeedbackComment сохраняет комментарий к отрицательной оценке
+func (b *Bot) handleFeedbackComment(chatID, userID int64, text string, state *models.UserState) {
+	l := locales.Get()
+
+	recipeID, _ := strconv.ParseInt(state.InputData, 10, 64)
+	recipe, err := b.db.GetRecipe(userID, recipeID)
+	if err != nil {
+		log.Printf("Ошибка получения рецепта: %v", err)
+		return
+	}
+
+	comment := strings.TrimSpace(text)
+	if utf8.RuneCountInString(comment) > maxFeedbackComment {
+		comment = string([]rune(comment)[:maxFeedbackComment])
+	}
+	if recipe != nil && !b.saveFeedback(recipe, models.RatingDislike, models.FeedbackReasonOther, comment) {
+		return
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(l.Recipes.Buttons.BackToMain, "menu:main"),
+	))
+	b.sendOrEditMessage(chatID, userID, state.LastMessageID, l.Feedback.Thanks, keyboard, models.StateMain)
+}
+
+// saveFeedback сохраняет оценку вместе с условиями генерации рецепта; false — сохранить не удалось
+func (b *Bot) saveFeedback(recipe *models.Recipe, rating int, reason, comment string) bool {
+	f := &models.RecipeFeedback{
+		RecipeID:      recipe.ID,
+		UserID:        recipe.UserID,
+		Rating:        rating,
+		Reason:        reason,
+		Comment:       comment,
+		PromptVersion: recipe.PromptVersion,
+		Model:         recipe.Model,
+		CreatedAt:     time.Now(),
+	}
+	if recipe.Preferences != nil {
+		f.DietType = recipe.Preferences.DietaryType
+	}
+
+	if err := b.db.SaveRecipeFeedback(f); err != nil {
+		log.Printf("Ошибка сохранения оценки рецепта: %v", err)
+		return false
+	}
+	return true
+}
+
+// recipeRating возвращает оценку рецепта пользователем (0 — не оценивал)
+func (b *Bot) recipeRating(userID, recipeID int64) int {
+	f, err := b.db.GetRecipeFeedback(userID, recipeID)
+	if err != nil {
+		log.Printf("Ошибка получения оценки рецепта: %v", err)
+	}
+	if f == nil {
+		return 0
+	}
+	return f.Rating
+}
+
+// cmdFeedback — /feedback [дней]: оценки рецептов по типу питания, версии промпта и модели
+func (b *Bot) cmdFeedback(msg *tgbotapi.Message, _ *models.UserState) {
+	days := feedbackReportDays
+	if arg := strings.TrimSpace(msg.CommandArguments()); arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n <= 0 {
+			b.sendText(msg.Chat.ID, "Использование: /feedback [дней]")
+			return
+		}
+		days = n
+	}
+
+	stats, reasons, err := b.db.FeedbackReport(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		log.Printf("Ошибка получения отчёта по оценкам: %v", err)
+		b.sendText(msg.Chat.ID, "❌ Не удалось получить отчёт по оценкам.")
+		return
+	}
+	if len(stats) == 0 {
+		b.sendText(msg.Chat.ID, fmt.Sprintf("📝 За %d дн. оценок нет.", days))
+		return
+	}
+
+	var likes, dislikes int
+	for _, s := range stats {
+		likes += s.Likes
+		dislikes += s.Dislikes
+	}
+
+	text := fmt.Sprintf("📝 Оценки рецептов за %d дн.\n\nВсего: %s", days, formatRatings(likes, dislikes))
+	text += "\n\nПо типу питания:\n" + feedbackBreakdown(stats, func(s models.FeedbackStat) string {
+		if s.DietType == "" {
+			return "не указан"
+		}
+		return s.DietType
+	})
+	text += "\n\nПо версии промпта:\n" + feedbackBreakdown(stats, func(s models.FeedbackStat) string { return s.PromptVersion })
+	text += "\n\nПо модели:\n" + feedbackBreakdown(stats, func(s models.FeedbackStat) string { return s.Model })
+
+	if len(reasons) > 0 {
+		var lines []string
+		for _, r := range reasons {
+			lines = append(lines, fmt.Sprintf("• %s — %d", models.FeedbackReasonName(r.Reason), r.Count))
+		}
+		text += "\n\nПричины 👎:\n" + strings.Join(lines, "\n")
+	}
+
+	b.sendText(msg.Chat.ID, text)
+}
+
+// feedbackBreakdown суммирует оценки по ключу и выводит по строке на значение
+func feedbackBreakdown(stats []models.FeedbackStat, key func(models.FeedbackStat) string) string {
+	totals := make(map[string]*[2]int)
+	var keys []string
+	for _, s := range stats {
+		k := key(s)
+		if totals[k] == nil {
+			totals[k] = &[2]int{}
+			keys = append(keys, k)
+		}
+		totals[k][0] += s.Likes
+		totals[k][1] += s.Dislikes
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, k := range keys {
+		lines = append(lines, fmt.Sprintf("• %s: %s", k, formatRatings(totals[k][0], totals[k][1])))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatRatings — «👍 12 · 👎 4 (75% 👍)»
+func formatRatings(likes, dislikes int) string {
+	text := fmt.Sprintf("👍 %d · 👎 %d", likes, dislikes)
+	if total := likes + dislikes; total > 0 {
+		text += fmt.Sprintf(" (%d%% 👍)", likes*100/total)
+	}
+	return text
+}
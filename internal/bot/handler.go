@@ -2,13 +2,17 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"strings"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/pinghoyk/neurobot/internal/database"
 	"github.com/pinghoyk/neurobot/internal/gigachat"
+	"github.com/pinghoyk/neurobot/internal/ratelimit"
 	"github.com/pinghoyk/neurobot/pkg/locales"
 	"github.com/pinghoyk/neurobot/pkg/models"
 )
@@ -16,12 +20,29 @@ import (
 // Bot представляет Telegram бота
 type Bot struct {
 	api      *tgbotapi.BotAPI
-	db       *database.DB
+	sender   *sender
+	db       database.Storage
 	gigachat *gigachat.Client
+	limiter  ratelimit.Limiter
+	opts     Options
+	commands map[string]commandHandler
+
+	// ctx — контекст работы бота из Start: при остановке прерывает ожидание
+	// лимитов Telegram в send и request
+	ctx context.Context
+
+	broadcastWake chan struct{}
+}
+
+// Options — параметры бота из конфигурации
+type Options struct {
+	RateLimit models.RateLimit // показывается пользователю в /limits
+	Quota     models.Quota     // дневная и месячная квоты генераций по умолчанию
+	AdminIDs  []int64          // пользователи с доступом к командам администратора
 }
 
 // New создает нового бота
-func New(token string, db *database.DB, gigachatClient *gigachat.Client) (*Bot, error) {
+func New(token string, db database.Storage, gigachatClient *gigachat.Client, limiter ratelimit.Limiter, opts Options) (*Bot, error) {
 	api, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка создания бота: %w", err)
@@ -29,26 +50,42 @@ func New(token string, db *database.DB, gigachatClient *gigachat.Client) (*Bot,
 
 	log.Printf("Авторизован как @%s", api.Self.UserName)
 
-	return &Bot{
+	b := &Bot{
 		api:      api,
+		sender:   newSender(api),
 		db:       db,
 		gigachat: gigachatClient,
-	}, nil
+		limiter:  limiter,
+		opts:     opts,
+		ctx:      context.Background(),
+
+		broadcastWake: make(chan struct{}, 1),
+	}
+	b.registerCommands()
+
+	return b, nil
 }
 
 // Start запускает обработку обновлений
 func (b *Bot) Start(ctx context.Context) error {
+	// Записывается до запуска обработчиков, поэтому они видят контекст без синхронизации
+	b.ctx = ctx
+
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
 
 	updates := b.api.GetUpdatesChan(u)
+	handle := b.trackUsers(b.rejectBanned(b.handleUpdate))
+
+	go b.runBroadcasts(ctx)
+	go b.runNotifications(ctx)
 
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		case update := <-updates:
-			go b.handleUpdate(update)
+			go handle(update)
 		}
 	}
 }
@@ -71,7 +108,9 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 
 	// Удаляем сообщение пользователя
 	deleteMsg := tgbotapi.NewDeleteMessage(msg.Chat.ID, msg.MessageID)
-	b.api.Send(deleteMsg)
+	if err := b.request(msg.Chat.ID, deleteMsg); err != nil {
+		log.Printf("Не удалось удалить сообщение: %v", err)
+	}
 
 	// Получаем текущее состояние
 	state, err := b.db.GetUserState(userID)
@@ -82,15 +121,10 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 
 	// Обработка команд
 	if msg.IsCommand() {
-		switch msg.Command() {
-		case "start":
-			b.showMainMenu(msg.Chat.ID, userID, state.LastMessageID)
-		case "settings":
-			b.showSettings(msg.Chat.ID, userID, state.LastMessageID)
-		case "help":
-			b.showHelp(msg.Chat.ID, userID, state.LastMessageID)
-		default:
-			b.handleRecipeRequest(msg.Chat.ID, userID, msg.Text, state.LastMessageID)
+		if handler, ok := b.commands[msg.Command()]; ok {
+			handler(msg, state)
+		} else {
+			b.handleRecipeRequest(msg.Chat.ID, userID, msg.Text, "", state.LastMessageID)
 		}
 		return
 	}
@@ -99,15 +133,25 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 	switch state.CurrentState {
 	case models.StateSettingsGoal:
 		b.handleGoalInput(msg.Chat.ID, userID, msg.Text, state.LastMessageID)
-	case models.StateSettingsAllerg:
+	case models.StateSettingsAllerg, models.StateSettingsAllergOther:
 		b.handleAllergiesInput(msg.Chat.ID, userID, msg.Text, state.LastMessageID)
 	case models.StateSettingsHabitsLikes:
 		b.handleLikesInput(msg.Chat.ID, userID, msg.Text, state.LastMessageID)
 	case models.StateSettingsHabitsDislikes:
 		b.handleDislikesInput(msg.Chat.ID, userID, msg.Text, state.LastMessageID)
+	case models.StateSettingsBodyAge, models.StateSettingsBodyHeight, models.StateSettingsBodyWeight:
+		b.handleBodyInput(msg.Chat.ID, userID, msg.Text, state.LastMessageID, state.CurrentState)
+	case models.StateFeedbackComment:
+		b.handleFeedbackComment(msg.Chat.ID, userID, msg.Text, state)
+	case models.StateScaleFactor:
+		b.handleScaleFactorInput(msg.Chat.ID, userID, msg.Text, state)
+	case models.StatePantryAdd:
+		b.handlePantryInput(msg.Chat.ID, userID, msg.Text, state.LastMessageID)
+	case models.StateNotifyTime, models.StateNotifyTimezone, models.StateNotifyQuiet:
+		b.handleNotifyInput(msg.Chat.ID, userID, msg.Text, state)
 	default:
 		// Генерация рецепта
-		b.handleRecipeRequest(msg.Chat.ID, userID, msg.Text, state.LastMessageID)
+		b.handleRecipeRequest(msg.Chat.ID, userID, msg.Text, "", state.LastMessageID)
 	}
 }
 
@@ -118,7 +162,9 @@ func (b *Bot) handleCallback(callback *tgbotapi.CallbackQuery) {
 	msgID := callback.Message.MessageID
 
 	// Отвечаем на callback чтобы убрать "часики"
-	b.api.Send(tgbotapi.NewCallback(callback.ID, ""))
+	if err := b.request(0, tgbotapi.NewCallback(callback.ID, "")); err != nil {
+		log.Printf("Не удалось ответить на callback: %v", err)
+	}
 
 	// Получаем состояние
 	state, err := b.db.GetUserState(userID)
@@ -135,18 +181,32 @@ func (b *Bot) handleCallback(callback *tgbotapi.CallbackQuery) {
 		b.showMainMenu(chatID, userID, msgID)
 	case "menu:settings":
 		b.showSettings(chatID, userID, msgID)
+	case "menu:pantry":
+		b.showPantry(chatID, userID, msgID)
+	case "menu:diary":
+		b.showToday(chatID, userID, msgID)
+	case "menu:notify":
+		b.showNotify(chatID, userID, msgID)
 	case "menu:diet":
 		b.showDietMenu(chatID, userID, msgID)
+	case "menu:restrictions":
+		b.showRestrictionsMenu(chatID, userID, msgID)
+	case "menu:body":
+		b.showBodyMenu(chatID, userID, msgID, "")
 	case "menu:goal":
 		b.showGoalInput(chatID, userID, msgID)
 	case "menu:allergies":
-		b.showAllergiesInput(chatID, userID, msgID)
+		b.showAllergiesMenu(chatID, userID, msgID, "")
+	case "allergies:other":
+		b.showAllergiesOtherInput(chatID, userID, msgID)
 	case "menu:habits":
 		b.showHabitsMenu(chatID, userID, msgID)
 	case "menu:likes":
 		b.showLikesInput(chatID, userID, msgID)
 	case "menu:dislikes":
 		b.showDislikesInput(chatID, userID, msgID)
+	case "menu:limits":
+		b.showLimits(chatID, userID, msgID)
 	case "menu:clear":
 		b.showClearConfirm(chatID, userID, msgID)
 	case "menu:help":
@@ -165,6 +225,44 @@ func (b *Bot) handleCallback(callback *tgbotapi.CallbackQuery) {
 		b.clearAllSettings(chatID, userID, msgID)
 	case "clear:no":
 		b.showSettings(chatID, userID, msgID)
+
+	// Подтверждение удаления всех данных
+	case "deleteme:yes":
+		b.deleteUserData(chatID, userID, msgID)
+	case "deleteme:no":
+		b.showMainMenu(chatID, userID, msgID)
+
+	default:
+		// Переключение аллергена: allergen:on:<код> или allergen:off:<код>
+		if rest, ok := strings.CutPrefix(callback.Data, "allergen:"); ok {
+			b.toggleAllergen(chatID, userID, msgID, rest)
+		} else if rest, ok := strings.CutPrefix(callback.Data, "restriction:"); ok {
+			b.toggleDietRestriction(chatID, userID, msgID, rest)
+		} else if rest, ok := strings.CutPrefix(callback.Data, "body:"); ok {
+			b.handleBodyCallback(chatID, userID, msgID, rest)
+		} else if rest, ok := strings.CutPrefix(callback.Data, "recipe:"); ok {
+			b.handleRecipeCallback(chatID, userID, msgID, rest)
+		} else if rest, ok := strings.CutPrefix(callback.Data, "feedback:"); ok {
+			b.handleFeedbackCallback(chatID, userID, msgID, rest)
+		} else if rest, ok := strings.CutPrefix(callback.Data, "scale:"); ok {
+			b.handleScaleCallback(chatID, userID, msgID, rest)
+		} else if rest, ok := strings.CutPrefix(callback.Data, "shopping:"); ok {
+			b.handleShoppingCallback(chatID, userID, msgID, rest)
+		} else if rest, ok := strings.CutPrefix(callback.Data, "pantry:"); ok {
+			b.handlePantryCallback(chatID, userID, msgID, rest)
+		} else if rest, ok := strings.CutPrefix(callback.Data, "diary:"); ok {
+			b.handleDiaryCallback(chatID, userID, msgID, rest)
+		} else if rest, ok := strings.CutPrefix(callback.Data, "notify:"); ok {
+			b.handleNotifyCallback(chatID, userID, msgID, rest)
+		} else if rest, ok := strings.CutPrefix(callback.Data, "plan:"); ok {
+			b.handlePlanCallback(chatID, userID, msgID, rest)
+		} else if rest, ok := strings.CutPrefix(callback.Data, "broadcast:"); ok {
+			b.handleBroadcastCallback(chatID, userID, msgID, rest, state)
+		} else if rest, ok := strings.CutPrefix(callback.Data, "history:"); ok {
+			b.handleRecipeListCallback(chatID, userID, msgID, false, rest)
+		} else if rest, ok := strings.CutPrefix(callback.Data, "favorites:"); ok {
+			b.handleRecipeListCallback(chatID, userID, msgID, true, rest)
+		}
 	}
 }
 
@@ -174,6 +272,10 @@ func (b *Bot) showMainMenu(chatID, userID int64, editMsgID int) {
 	text := l.MainMenu.Text
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.MainMenu.Buttons.Pantry, "menu:pantry"),
+			tgbotapi.NewInlineKeyboardButtonData(l.MainMenu.Buttons.Diary, "menu:diary"),
+		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(l.MainMenu.Buttons.Settings, "menu:settings"),
 		),
@@ -182,11 +284,37 @@ func (b *Bot) showMainMenu(chatID, userID int64, editMsgID int) {
 	b.sendOrEditMessage(chatID, userID, editMsgID, text, keyboard, models.StateMain)
 }
 
+// userPreferences читает предпочтения пользователя. Если их не удалось прочитать
+// (например, поле не расшифровывается), сообщает об этом и возвращает false:
+// без аллергий и ограничений нельзя ни генерировать рецепты, ни сохранять настройки.
+func (b *Bot) userPreferences(chatID, userID int64, editMsgID int) (*models.UserPreferences, bool) {
+	prefs, err := b.db.GetUserPreferences(userID)
+	if err == nil {
+		return prefs, true
+	}
+	log.Printf("Ошибка получения предпочтений: %v", err)
+
+	l := locales.Get()
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.SettingsMenu.Buttons.Clear, "menu:clear"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.ClearSuccess.Buttons.ToMain, "menu:main"),
+		),
+	)
+	b.sendOrEditMessage(chatID, userID, editMsgID, l.SettingsMenu.Unavailable, keyboard, models.StateMain)
+	return nil, false
+}
+
 // showSettings отображает меню настроек
 func (b *Bot) showSettings(chatID, userID int64, editMsgID int) {
 	l := locales.Get()
 
-	prefs, _ := b.db.GetUserPreferences(userID)
+	prefs, ok := b.userPreferences(chatID, userID, editMsgID)
+	if !ok {
+		return
+	}
 	settingsText := b.formatSettingsText(prefs)
 	text := fmt.Sprintf(l.SettingsMenu.Text, settingsText)
 
@@ -196,8 +324,16 @@ func (b *Bot) showSettings(chatID, userID int64, editMsgID int) {
 			tgbotapi.NewInlineKeyboardButtonData(l.SettingsMenu.Buttons.Goal, "menu:goal"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.SettingsMenu.Buttons.Restrictions, "menu:restrictions"),
 			tgbotapi.NewInlineKeyboardButtonData(l.SettingsMenu.Buttons.Allergies, "menu:allergies"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(l.SettingsMenu.Buttons.Habits, "menu:habits"),
+			tgbotapi.NewInlineKeyboardButtonData(l.SettingsMenu.Buttons.Body, "menu:body"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.SettingsMenu.Buttons.Notify, "menu:notify"),
+			tgbotapi.NewInlineKeyboardButtonData(l.SettingsMenu.Buttons.Limits, "menu:limits"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(l.SettingsMenu.Buttons.Clear, "menu:clear"),
@@ -210,7 +346,7 @@ func (b *Bot) showSettings(chatID, userID int64, editMsgID int) {
 	b.sendOrEditMessage(chatID, userID, editMsgID, text, keyboard, models.StateSettings)
 }
 
-// showDietMenu отображает меню выбора типа питания
+// showDietMenu отображает меню выбора цели по весу
 func (b *Bot) showDietMenu(chatID, userID int64, editMsgID int) {
 	l := locales.Get()
 
@@ -245,17 +381,80 @@ func (b *Bot) showGoalInput(chatID, userID int64, editMsgID int) {
 	b.sendOrEditMessage(chatID, userID, editMsgID, l.GoalMenu.Text, keyboard, models.StateSettingsGoal)
 }
 
-// showAllergiesInput запрашивает ввод аллергий
-func (b *Bot) showAllergiesInput(chatID, userID int64, editMsgID int) {
+// showAllergiesMenu отображает справочник аллергенов с переключателями.
+// notice выводится над текстом меню (например, сообщение об успешном сохранении).
+func (b *Bot) showAllergiesMenu(chatID, userID int64, editMsgID int, notice string) {
+	l := locales.Get()
+
+	prefs, ok := b.userPreferences(chatID, userID, editMsgID)
+	if !ok {
+		return
+	}
+
+	text := l.AllergiesMenu.Text
+	if prefs.Allergies != "" {
+		text += "\n\n" + fmt.Sprintf(l.AllergiesMenu.OtherCurrent, prefs.Allergies)
+	}
+	if notice != "" {
+		text = notice + "\n\n" + text
+	}
+
+	// По два аллергена в ряд; в callback передаём желаемое состояние, чтобы повторное нажатие было безопасным
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, a := range models.Allergens {
+		label, action := a.Name, "on"
+		if prefs.HasAllergen(a.Code) {
+			label, action = "✅ "+a.Name, "off"
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, "allergen:"+action+":"+a.Code))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.AllergiesMenu.Buttons.Other, "allergies:other"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.AllergiesMenu.Buttons.Done, "menu:settings"),
+		),
+	)
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	b.sendOrEditMessage(chatID, userID, editMsgID, text, keyboard, models.StateSettingsAllerg)
+}
+
+// showAllergiesOtherInput запрашивает аллергии, которых нет в справочнике
+func (b *Bot) showAllergiesOtherInput(chatID, userID int64, editMsgID int) {
 	l := locales.Get()
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(l.AllergiesMenu.Buttons.BackToSettings, "menu:settings"),
+			tgbotapi.NewInlineKeyboardButtonData(l.AllergiesMenu.Buttons.BackToAllergies, "menu:allergies"),
 		),
 	)
 
-	b.sendOrEditMessage(chatID, userID, editMsgID, l.AllergiesMenu.Text, keyboard, models.StateSettingsAllerg)
+	b.sendOrEditMessage(chatID, userID, editMsgID, l.AllergiesMenu.OtherText, keyboard, models.StateSettingsAllergOther)
+}
+
+// toggleAllergen отмечает аллерген или снимает отметку; data — "on:<код>" или "off:<код>"
+func (b *Bot) toggleAllergen(chatID, userID int64, msgID int, data string) {
+	action, code, _ := strings.Cut(data, ":")
+	if _, ok := models.AllergenByCode(code); !ok || (action != "on" && action != "off") {
+		log.Printf("Некорректный callback аллергена: %q", data)
+		return
+	}
+
+	if err := b.db.SetAllergen(userID, code, action == "on"); err != nil {
+		log.Printf("Ошибка сохранения аллергена: %v", err)
+	}
+
+	b.showAllergiesMenu(chatID, userID, msgID, "")
 }
 
 // showHabitsMenu отображает меню привычек
@@ -321,7 +520,21 @@ func (b *Bot) showHelp(chatID, userID int64, editMsgID int) {
 
 Я — бот для генерации персонализированных рецептов.
 
-Настройте тип питания, цели, аллергии и предпочтения — и я учту всё при подборе блюд.
+Настройте цель по весу, ограничения питания, аллергии и предпочтения — и я учту всё при подборе блюд.
+
+Все рецепты сохраняются: /history — история, /favorites — избранное (кнопка «⭐ Сохранить» под рецептом).
+
+/plan — план питания на неделю: завтрак, обед и ужин по вашей норме калорий, любое блюдо можно заменить.
+
+/pantry — ваши продукты со сроками годности: рецепт из того, что есть дома, и списание израсходованного кнопкой «🧺 Списать продукты» под рецептом.
+/today и /week — дневник питания: калории и БЖУ за день и за неделю против вашей нормы. Блюдо записывается кнопкой «🍴 Записать в дневник» под рецептом.
+/notify — уведомления: продукты, у которых завтра истекает срок, «что на ужин?» и меню на день из плана. Можно выбрать время, часовой пояс и тихие часы.
+
+Кнопка «🛒 В покупки» добавит ингредиенты рецепта в /shopping — список покупок по отделам магазина.
+
+Команда /limits покажет, сколько генераций осталось на сегодня и на месяц.
+
+Ваши данные: /mydata — выгрузить всё, что хранит бот, /deleteme — удалить их безвозвратно.
 
 Чтобы начать — откройте *Настройки*.`
 
@@ -334,11 +547,14 @@ func (b *Bot) showHelp(chatID, userID int64, editMsgID int) {
 	b.sendOrEditMessage(chatID, userID, editMsgID, text, keyboard, models.StateHelp)
 }
 
-// saveDietType сохраняет тип питания
+// saveDietType сохраняет цель по весу
 func (b *Bot) saveDietType(chatID, userID int64, editMsgID int, dietType string) {
 	l := locales.Get()
 
-	prefs, _ := b.db.GetUserPreferences(userID)
+	prefs, ok := b.userPreferences(chatID, userID, editMsgID)
+	if !ok {
+		return
+	}
 	prefs.UserID = userID
 	prefs.DietaryType = dietType
 
@@ -356,7 +572,10 @@ func (b *Bot) saveDietType(chatID, userID int64, editMsgID int, dietType string)
 func (b *Bot) handleGoalInput(chatID, userID int64, text string, editMsgID int) {
 	l := locales.Get()
 
-	prefs, _ := b.db.GetUserPreferences(userID)
+	prefs, ok := b.userPreferences(chatID, userID, editMsgID)
+	if !ok {
+		return
+	}
 	prefs.UserID = userID
 
 	if strings.ToLower(strings.TrimSpace(text)) == "нет" {
@@ -373,11 +592,14 @@ func (b *Bot) handleGoalInput(chatID, userID int64, text string, editMsgID int)
 	b.sendOrEditMessage(chatID, userID, editMsgID, l.GoalMenu.Success, keyboard, models.StateSettings)
 }
 
-// handleAllergiesInput обрабатывает ввод аллергий
+// handleAllergiesInput обрабатывает ввод аллергий, которых нет в справочнике
 func (b *Bot) handleAllergiesInput(chatID, userID int64, text string, editMsgID int) {
 	l := locales.Get()
 
-	prefs, _ := b.db.GetUserPreferences(userID)
+	prefs, ok := b.userPreferences(chatID, userID, editMsgID)
+	if !ok {
+		return
+	}
 	prefs.UserID = userID
 
 	if strings.ToLower(strings.TrimSpace(text)) == "нет" {
@@ -390,15 +612,17 @@ func (b *Bot) handleAllergiesInput(chatID, userID int64, text string, editMsgID
 		log.Printf("Ошибка сохранения предпочтений: %v", err)
 	}
 
-	keyboard := b.getSuccessKeyboard()
-	b.sendOrEditMessage(chatID, userID, editMsgID, l.AllergiesMenu.Success, keyboard, models.StateSettings)
+	b.showAllergiesMenu(chatID, userID, editMsgID, l.AllergiesMenu.Success)
 }
 
 // handleLikesInput обрабатывает ввод любимых продуктов
 func (b *Bot) handleLikesInput(chatID, userID int64, text string, editMsgID int) {
 	l := locales.Get()
 
-	prefs, _ := b.db.GetUserPreferences(userID)
+	prefs, ok := b.userPreferences(chatID, userID, editMsgID)
+	if !ok {
+		return
+	}
 	prefs.UserID = userID
 
 	if strings.ToLower(strings.TrimSpace(text)) == "нет" {
@@ -419,7 +643,10 @@ func (b *Bot) handleLikesInput(chatID, userID int64, text string, editMsgID int)
 func (b *Bot) handleDislikesInput(chatID, userID int64, text string, editMsgID int) {
 	l := locales.Get()
 
-	prefs, _ := b.db.GetUserPreferences(userID)
+	prefs, ok := b.userPreferences(chatID, userID, editMsgID)
+	if !ok {
+		return
+	}
 	prefs.UserID = userID
 
 	if strings.ToLower(strings.TrimSpace(text)) == "нет" {
@@ -456,49 +683,66 @@ func (b *Bot) clearAllSettings(chatID, userID int64, editMsgID int) {
 	b.sendOrEditMessage(chatID, userID, editMsgID, l.ClearSuccess.Text, keyboard, models.StateSettings)
 }
 
-// handleRecipeRequest обрабатывает запрос на генерацию рецепта
-func (b *Bot) handleRecipeRequest(chatID, userID int64, request string, editMsgID int) {
-	// Проверяем rate limit
-	allowed, err := b.db.CheckRateLimit(userID)
-	if err != nil {
-		log.Printf("Ошибка проверки лимита: %v", err)
+// handleRecipeRequest обрабатывает запрос на генерацию рецепта.
+// refinement — уточнение кнопкой доработки; в истории оно хранится отдельно от запроса.
+func (b *Bot) handleRecipeRequest(chatID, userID int64, request, refinement string, editMsgID int) {
+	// Без предпочтений рецепт может нарушить аллергии — не генерируем и не тратим квоту
+	prefs, ok := b.userPreferences(chatID, userID, editMsgID)
+	if !ok {
+		return
 	}
 
-	if !allowed {
-		text := "⏳ *Подождите немного*\n\nСлишком много запросов. Попробуйте через минуту."
-		msg := tgbotapi.NewMessage(chatID, text)
-		msg.ParseMode = "Markdown"
-		b.api.Send(msg)
+	genID := b.reserveGeneration(chatID, userID)
+	if genID == 0 {
 		return
 	}
 
 	// Показываем сообщение о генерации
 	waitMsg := tgbotapi.NewMessage(chatID, "🍳 *Готовлю рецепт...*\n\nЭто займёт несколько секунд.")
 	waitMsg.ParseMode = "Markdown"
-	sentMsg, err := b.api.Send(waitMsg)
+	sentMsg, err := b.send(chatID, waitMsg)
 	if err != nil {
 		log.Printf("Ошибка отправки сообщения: %v", err)
+		b.finishGeneration(genID, err)
 		return
 	}
 
-	// Получаем предпочтения пользователя
-	prefs, _ := b.db.GetUserPreferences(userID)
-
 	// Генерируем рецепт
-	recipe, err := b.gigachat.GenerateRecipe(request, prefs)
+	prompt := request
+	if refinement != "" {
+		prompt += "\n\n" + refinement
+	}
+	recipe, err := b.gigachat.GenerateRecipe(prompt, prefs)
+	b.finishGeneration(genID, err)
 	if err != nil {
 		log.Printf("Ошибка генерации: %v", err)
 		errorText := "❌ *Ошибка генерации*\n\nПопробуйте ещё раз или переформулируйте запрос."
 		editMsg := tgbotapi.NewEditMessageText(chatID, sentMsg.MessageID, errorText)
 		editMsg.ParseMode = "Markdown"
-		b.api.Send(editMsg)
+		if _, err := b.send(chatID, editMsg); err != nil {
+			log.Printf("Ошибка отправки сообщения: %v", err)
+		}
 		return
 	}
 
-	// Редактируем сообщение с результатом
+	// Сверяем калории и БЖУ модели с базой продуктов
+	recipe = verifyNutrition(recipe)
+
+	// Предупреждаем, если модель нарушила выбранные ограничения питания
+	if warning := restrictionWarning(prefs, recipe); warning != "" {
+		recipe += "\n\n" + warning
+	}
+
+	// Сохраняем рецепт в историю и показываем кнопки под ним
 	editMsg := tgbotapi.NewEditMessageText(chatID, sentMsg.MessageID, recipe)
 	editMsg.ParseMode = "Markdown"
-	b.api.Send(editMsg)
+	if recipeID := b.saveRecipe(userID, request, refinement, recipe, prefs); recipeID > 0 {
+		keyboard := recipeKeyboard(&models.Recipe{ID: recipeID}, 0, nil)
+		editMsg.ReplyMarkup = &keyboard
+	}
+	if _, err := b.send(chatID, editMsg); err != nil {
+		log.Printf("Ошибка отправки рецепта: %v", err)
+	}
 
 	// Обновляем состояние
 	state := &models.UserState{
@@ -509,6 +753,62 @@ func (b *Bot) handleRecipeRequest(chatID, userID int64, request string, editMsgI
 	b.db.SaveUserState(state)
 }
 
+// reserveGeneration проверяет rate limit и резервирует генерацию в квоте.
+// Возвращает ID записи в журнале генераций; 0 — генерация запрещена, пользователь уже уведомлён.
+func (b *Bot) reserveGeneration(chatID, userID int64) int64 {
+	// Проверяем rate limit. При ошибке хранилища не пропускаем запрос к модели.
+	limit, err := b.limiter.Allow(userID)
+	if err != nil {
+		log.Printf("Ошибка проверки лимита: %v", err)
+		msg := tgbotapi.NewMessage(chatID, "❌ *Сервис временно недоступен*\n\nПопробуйте ещё раз чуть позже.")
+		msg.ParseMode = "Markdown"
+		if _, err := b.send(chatID, msg); err != nil {
+			log.Printf("Ошибка отправки сообщения: %v", err)
+		}
+		return 0
+	}
+
+	if !limit.Allowed {
+		text := fmt.Sprintf("⏳ *Подождите немного*\n\nСлишком много запросов. Попробуйте через %s.", formatWait(limit.RetryAfter))
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ParseMode = "Markdown"
+		if _, err := b.send(chatID, msg); err != nil {
+			log.Printf("Ошибка отправки сообщения: %v", err)
+		}
+		return 0
+	}
+
+	// Резервируем генерацию в дневной и месячной квоте
+	genID, usage, err := b.reserveQuota(userID)
+	if err != nil {
+		log.Printf("Ошибка проверки квоты: %v", err)
+		msg := tgbotapi.NewMessage(chatID, "❌ *Сервис временно недоступен*\n\nПопробуйте ещё раз чуть позже.")
+		msg.ParseMode = "Markdown"
+		if _, err := b.send(chatID, msg); err != nil {
+			log.Printf("Ошибка отправки сообщения: %v", err)
+		}
+		return 0
+	}
+
+	if genID == 0 {
+		msg := tgbotapi.NewMessage(chatID, quotaExceededText(usage))
+		msg.ParseMode = "Markdown"
+		if _, err := b.send(chatID, msg); err != nil {
+			log.Printf("Ошибка отправки сообщения: %v", err)
+		}
+		return 0
+	}
+
+	return genID
+}
+
+// finishGeneration записывает результат генерации; неудачные не расходуют квоту
+func (b *Bot) finishGeneration(genID int64, genErr error) {
+	if err := b.db.FinishGeneration(genID, genErr); err != nil {
+		log.Printf("Ошибка записи результата генерации: %v", err)
+	}
+}
+
 // sendOrEditMessage отправляет новое или редактирует существующее сообщение
 func (b *Bot) sendOrEditMessage(chatID, userID int64, editMsgID int, text string, keyboard tgbotapi.InlineKeyboardMarkup, newState string) {
 	var msgID int
@@ -519,29 +819,35 @@ func (b *Bot) sendOrEditMessage(chatID, userID int64, editMsgID int, text string
 		editMsg.ParseMode = "Markdown"
 		editMsg.ReplyMarkup = &keyboard
 
-		_, err := b.api.Send(editMsg)
+		_, err := b.send(chatID, editMsg)
 		if err == nil {
 			msgID = editMsgID
+		} else if errors.Is(err, errBotBlocked) {
+			return
 		} else {
 			// Если редактирование не удалось, отправляем новое
 			log.Printf("Не удалось отредактировать сообщение: %v", err)
 			newMsg := tgbotapi.NewMessage(chatID, text)
 			newMsg.ParseMode = "Markdown"
 			newMsg.ReplyMarkup = keyboard
-			sentMsg, err := b.api.Send(newMsg)
-			if err == nil {
-				msgID = sentMsg.MessageID
+			sentMsg, err := b.send(chatID, newMsg)
+			if err != nil {
+				log.Printf("Ошибка отправки сообщения: %v", err)
+				return
 			}
+			msgID = sentMsg.MessageID
 		}
 	} else {
 		// Отправляем новое сообщение
 		newMsg := tgbotapi.NewMessage(chatID, text)
 		newMsg.ParseMode = "Markdown"
 		newMsg.ReplyMarkup = keyboard
-		sentMsg, err := b.api.Send(newMsg)
-		if err == nil {
-			msgID = sentMsg.MessageID
+		sentMsg, err := b.send(chatID, newMsg)
+		if err != nil {
+			log.Printf("Ошибка отправки сообщения: %v", err)
+			return
 		}
+		msgID = sentMsg.MessageID
 	}
 
 	// Сохраняем состояние
@@ -553,6 +859,32 @@ func (b *Bot) sendOrEditMessage(chatID, userID int64, editMsgID int, text string
 	b.db.SaveUserState(state)
 }
 
+// send отправляет сообщение через sender и отмечает пользователей, заблокировавших бота
+func (b *Bot) send(chatID int64, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	msg, err := b.sender.send(b.ctx, chatID, c)
+	b.checkBlocked(chatID, err)
+	return msg, err
+}
+
+// request выполняет запрос, ответ на который не является сообщением (удаление, callback)
+func (b *Bot) request(chatID int64, c tgbotapi.Chattable) error {
+	_, err := b.sender.request(b.ctx, chatID, c)
+	b.checkBlocked(chatID, err)
+	return err
+}
+
+// checkBlocked помечает пользователя неактивным, если Telegram сообщил о блокировке
+func (b *Bot) checkBlocked(chatID int64, err error) {
+	if chatID == 0 || !errors.Is(err, errBotBlocked) {
+		return
+	}
+
+	log.Printf("Пользователь %d недоступен: %v", chatID, err)
+	if dbErr := b.db.MarkUserInactive(chatID, err.Error()); dbErr != nil {
+		log.Printf("Ошибка отметки неактивного пользователя: %v", dbErr)
+	}
+}
+
 // formatSettingsText форматирует текст с текущими настройками
 func (b *Bot) formatSettingsText(prefs *models.UserPreferences) string {
 	l := locales.Get()
@@ -564,13 +896,33 @@ func (b *Bot) formatSettingsText(prefs *models.UserPreferences) string {
 	}
 	parts = append(parts, fmt.Sprintf("• %s: %s", l.SettingsMenu.Fields.Diet, diet))
 
+	var restrictionNames []string
+	for _, r := range prefs.SelectedDietRestrictions() {
+		restrictionNames = append(restrictionNames, r.Name)
+	}
+	restrictions := strings.Join(restrictionNames, ", ")
+	if restrictions == "" {
+		restrictions = "_не указано_"
+	}
+	parts = append(parts, fmt.Sprintf("• %s: %s", l.SettingsMenu.Fields.Restrictions, restrictions))
+
 	goal := prefs.Goal
 	if goal == "" {
 		goal = "_не указано_"
 	}
 	parts = append(parts, fmt.Sprintf("• %s: %s", l.SettingsMenu.Fields.Goal, goal))
 
-	allergies := prefs.Allergies
+	// Аллергены из справочника и «другое»
+	var allergyNames []string
+	for _, a := range models.Allergens {
+		if prefs.HasAllergen(a.Code) {
+			allergyNames = append(allergyNames, a.Name)
+		}
+	}
+	if prefs.Allergies != "" {
+		allergyNames = append(allergyNames, prefs.Allergies)
+	}
+	allergies := strings.Join(allergyNames, ", ")
 	if allergies == "" {
 		allergies = "_не указано_"
 	}
@@ -591,9 +943,35 @@ func (b *Bot) formatSettingsText(prefs *models.UserPreferences) string {
 	}
 	parts = append(parts, fmt.Sprintf("• %s: %s", l.SettingsMenu.Fields.Habits, habits))
 
+	body := "_не указано_"
+	if prefs.Body.Complete() {
+		body = fmt.Sprintf(l.BodyMenu.Summary, formatNumber(prefs.Body.HeightCm), formatNumber(prefs.Body.WeightKg),
+			prefs.Body.DailyTargets(prefs.DietaryType, prefs.DietRestrictions).Calories)
+	}
+	parts = append(parts, fmt.Sprintf("• %s: %s", l.SettingsMenu.Fields.Body, body))
+
 	return strings.Join(parts, "\n")
 }
 
+// formatWait форматирует время ожидания в секундах с правильным склонением
+func formatWait(d time.Duration) string {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	word := "секунд"
+	switch n := seconds % 100; {
+	case n >= 11 && n <= 14:
+	case n%10 == 1:
+		word = "секунду"
+	case n%10 >= 2 && n%10 <= 4:
+		word = "секунды"
+	}
+
+	return fmt.Sprintf("%d %s", seconds, word)
+}
+
 // getSuccessKeyboard возвращает клавиатуру успешного сохранения
 func (b *Bot) getSuccessKeyboard() tgbotapi.InlineKeyboardMarkup {
 	l := locales.Get()
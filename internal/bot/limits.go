@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pinghoyk/neurobot/pkg/locales"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// userQuota возвращает квоту пользователя с учётом индивидуальных настроек администратора.
+// При ошибке вместе с ней возвращается общая квота — годится только для показа.
+func (b *Bot) userQuota(userID int64) (models.Quota, error) {
+	override, err := b.db.GetUserQuota(userID)
+	return override.Apply(b.opts.Quota), err
+}
+
+// reserveQuota резервирует генерацию в квоте пользователя (ID 0 — квота исчерпана).
+// Если индивидуальную квоту прочитать не удалось, генерация не резервируется:
+// администратор мог назначить квоту строже общей.
+func (b *Bot) reserveQuota(userID int64) (int64, models.QuotaUsage, error) {
+	quota, err := b.userQuota(userID)
+	if err != nil {
+		return 0, models.QuotaUsage{}, fmt.Errorf("квота пользователя %d: %w", userID, err)
+	}
+	return b.db.ReserveGeneration(userID, quota, time.Now())
+}
+
+// showLimits отображает лимиты и остаток квот пользователя
+func (b *Bot) showLimits(chatID, userID int64, editMsgID int) {
+	l := locales.Get()
+
+	quota, err := b.userQuota(userID)
+	if err != nil {
+		log.Printf("Ошибка получения квоты: %v", err)
+	}
+	usage, err := b.db.GetQuotaUsage(userID, quota, time.Now())
+	if err != nil {
+		log.Printf("Ошибка получения использования квот: %v", err)
+	}
+
+	text := fmt.Sprintf(l.LimitsMenu.Text,
+		b.opts.RateLimit.PerMinute,
+		formatRemaining(usage.RemainingToday(), usage.Daily),
+		formatRemaining(usage.RemainingMonth(), usage.Monthly),
+	)
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.LimitsMenu.Buttons.BackToSettings, "menu:settings"),
+		),
+	)
+
+	b.sendOrEditMessage(chatID, userID, editMsgID, text, keyboard, models.StateLimits)
+}
+
+// quotaExceededText возвращает сообщение об исчерпанной квоте
+func quotaExceededText(usage models.QuotaUsage) string {
+	l := locales.Get()
+	if usage.RemainingToday() == 0 {
+		return fmt.Sprintf(l.QuotaExceeded.Daily, usage.UsedToday)
+	}
+	return fmt.Sprintf(l.QuotaExceeded.Monthly, usage.UsedMonth)
+}
+
+// formatRemaining форматирует остаток квоты
+func formatRemaining(remaining, limit int) string {
+	l := locales.Get()
+	if remaining < 0 {
+		return l.LimitsMenu.Unlimited
+	}
+	return fmt.Sprintf(l.LimitsMenu.Remaining, remaining, limit)
+}
@@ -0,0 +1,460 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pinghoyk/neurobot/pkg/locales"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+const (
+	notifyPollInterval = 30 * time.Second
+	notifyLease        = 2 * time.Minute
+
+	// notifyMaxDelay — уведомления, опоздавшие сильнее (например, бот был выключен),
+	// не отправляются, а переносятся на следующий раз: ужин в полночь только мешает
+	notifyMaxDelay = 2 * time.Hour
+)
+
+// runNotifications отправляет наступившие уведомления до остановки бота.
+// Расписание хранится в базе, поэтому переживает перезапуск.
+func (b *Bot) runNotifications(ctx context.Context) {
+	ticker := time.NewTicker(notifyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			job, err := b.db.ClaimNotifyJob(time.Now(), notifyLease)
+			if err != nil {
+				log.Printf("Ошибка получения уведомления: %v", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			b.deliverNotification(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverNotification отправляет уведомление и переносит задание на следующий раз.
+// Опоздавшие, попавшие в тихие часы и уведомления недоступным пользователям пропускаются.
+func (b *Bot) deliverNotification(ctx context.Context, job *models.NotifyJob) {
+	settings, err := b.db.GetNotifySettings(job.UserID)
+	if err != nil {
+		// Аренда истечёт, и задание будет подхвачено заново
+		log.Printf("Ошибка получения настроек уведомлений: %v", err)
+		return
+	}
+	if settings == nil {
+		settings = models.DefaultNotifySettings(job.UserID)
+	}
+
+	now := time.Now()
+	if late := now.Sub(job.RunAt); late > notifyMaxDelay {
+		log.Printf("Уведомление %s пользователю %d пропущено: опоздание %s", job.Kind, job.UserID, late.Round(time.Minute))
+	} else if settings.Enabled(job.Kind) && !settings.IsQuiet(now) && b.canNotify(job.UserID) {
+		if msg, ok := b.notifyMessage(job, settings, now); ok {
+			_, err := b.sender.send(ctx, job.UserID, msg)
+			if ctx.Err() != nil {
+				// Остановка бота: уведомление будет отправлено после перезапуска
+				return
+			}
+			b.checkBlocked(job.UserID, err)
+			if err != nil && !errors.Is(err, errBotBlocked) {
+				log.Printf("Ошибка отправки уведомления пользователю %d: %v", job.UserID, err)
+			}
+		}
+	}
+
+	if err := b.db.RescheduleNotifyJob(job.ID, settings.NextRun(job.Kind, now)); err != nil {
+		log.Printf("Ошибка переноса уведомления: %v", err)
+	}
+}
+
+// canNotify проверяет, что пользователь не заблокировал бота и не забанен
+func (b *Bot) canNotify(userID int64) bool {
+	user, err := b.db.GetUser(userID)
+	if err != nil {
+		log.Printf("Ошибка получения пользователя: %v", err)
+		return false
+	}
+	return user == nil || (!user.IsBlocked && !user.IsBanned)
+}
+
+// notifyMessage собирает уведомление. false — сообщать нечего
+// (например, завтра ничего не истекает или на сегодня нет плана).
+func (b *Bot) notifyMessage(job *models.NotifyJob, settings *models.NotifySettings, now time.Time) (tgbotapi.MessageConfig, bool) {
+	l := locales.Get()
+	today, _ := models.QuotaPeriods(now.In(settings.Location()))
+	day := today.Format(models.DayLayout)
+
+	var text string
+	var rows [][]tgbotapi.InlineKeyboardButton
+	switch job.Kind {
+	case models.NotifyExpiry:
+		items, err := b.db.ListPantryItems(job.UserID)
+		if err != nil {
+			log.Printf("Ошибка получения запасов: %v", err)
+			return tgbotapi.MessageConfig{}, false
+		}
+		tomorrow := today.AddDate(0, 0, 1).Format(models.DayLayout)
+		var lines []string
+		for _, item := range items {
+			if item.Expires == tomorrow {
+				lines = append(lines, formatPantryItem(&item, today))
+			}
+		}
+		if len(lines) == 0 {
+			return tgbotapi.MessageConfig{}, false
+		}
+		text = fmt.Sprintf(l.Notify.ExpiryMessage, strings.Join(lines, "\n"))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.Notify.Buttons.Cook, "pantry:cook"),
+		))
+
+	case models.NotifyDinner:
+		meals, err := b.db.GetPlanMeals(job.UserID, day, day)
+		if err != nil {
+			log.Printf("Ошибка получения плана питания: %v", err)
+			return tgbotapi.MessageConfig{}, false
+		}
+		text = l.Notify.DinnerMessage
+		for _, m := range meals {
+			if m.Slot == "dinner" {
+				text = fmt.Sprintf(l.Notify.DinnerPlanMessage, m.Title, m.Calories)
+				rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData(l.Notify.Buttons.PlanRecipe, "plan:recipe:"+day+":"+m.Slot),
+				))
+			}
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.Notify.Buttons.Suggest, "notify:dinner"),
+			tgbotapi.NewInlineKeyboardButtonData(l.Notify.Buttons.Cook, "pantry:cook"),
+		))
+
+	case models.NotifyPlan:
+		meals, err := b.db.GetPlanMeals(job.UserID, day, day)
+		if err != nil {
+			log.Printf("Ошибка получения плана питания: %v", err)
+			return tgbotapi.MessageConfig{}, false
+		}
+		if len(meals) == 0 {
+			return tgbotapi.MessageConfig{}, false
+		}
+		var lines []string
+		for _, slot := range models.PlanSlots {
+			for _, m := range meals {
+				if m.Slot == slot.Code {
+					lines = append(lines, fmt.Sprintf("%s %s — %s (~%d ккал)", slot.Emoji, slot.Name, m.Title, m.Calories))
+				}
+			}
+		}
+		text = fmt.Sprintf(l.Notify.PlanMessage, strings.Join(lines, "\n"))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.Notify.Buttons.OpenPlan, "plan:day:"+day),
+		))
+
+	default:
+		log.Printf("Неизвестный вид уведомления: %q", job.Kind)
+		return tgbotapi.MessageConfig{}, false
+	}
+
+	// Без разметки: в тексте названия блюд и продуктов от пользователя и модели
+	msg := tgbotapi.NewMessage(job.UserID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return msg, true
+}
+
+// showNotify — настройки уведомлений
+func (b *Bot) showNotify(chatID, userID int64, editMsgID int) {
+	b.showNotifySettings(chatID, userID, editMsgID, "")
+}
+
+// showNotifySettings отображает уведомления с переключателями и временем отправки
+func (b *Bot) showNotifySettings(chatID, userID int64, editMsgID int, notice string) {
+	l := locales.Get()
+	settings := b.notifySettings(userID)
+
+	quiet := l.Notify.QuietOff
+	if settings.QuietStart != settings.QuietEnd {
+		quiet = models.FormatClock(settings.QuietStart) + "–" + models.FormatClock(settings.QuietEnd)
+	}
+	now := time.Now().In(settings.Location())
+	lines := []string{
+		fmt.Sprintf("🌍 %s: %s (сейчас %s)", l.Notify.Timezone, timezoneName(settings.Timezone), now.Format("15:04")),
+		fmt.Sprintf("🌙 %s: %s", l.Notify.Quiet, quiet),
+		"",
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, kind := range models.NotifyKinds {
+		name := notifyKindName(kind)
+		at := models.FormatClock(settings.At(kind))
+
+		label, action, status := name, "on", l.Notify.Off
+		if settings.Enabled(kind) {
+			label, action, status = "✅ "+name, "off", at
+		}
+		lines = append(lines, name+": "+status)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, "notify:"+action+":"+kind),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf(l.Notify.Buttons.Time, at), "notify:time:"+kind),
+		))
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.Notify.Buttons.Timezone, "notify:tz"),
+			tgbotapi.NewInlineKeyboardButtonData(l.Notify.Buttons.Quiet, "notify:quiet"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.Notify.Buttons.BackToSettings, "menu:settings"),
+		),
+	)
+
+	text := fmt.Sprintf(l.Notify.Text, strings.Join(lines, "\n"))
+	if notice != "" {
+		text = notice + "\n\n" + text
+	}
+	b.sendOrEditMessage(chatID, userID, editMsgID, text, tgbotapi.NewInlineKeyboardMarkup(rows...), models.StateNotify)
+}
+
+// handleNotifyCallback обрабатывает кнопки уведомлений: show, on:<вид>, off:<вид>, time:<вид>,
+// tz, tz:<пояс>, quiet, quiet:off и dinner — подобрать ужин по уведомлению
+func (b *Bot) handleNotifyCallback(chatID, userID int64, msgID int, data string) {
+	l := locales.Get()
+
+	action, arg, _ := strings.Cut(data, ":")
+	switch action {
+	case "show":
+		b.showNotifySettings(chatID, userID, msgID, "")
+	case "on", "off", "time":
+		if notifyKindName(arg) == "" {
+			log.Printf("Некорректный вид уведомления: %q", data)
+			return
+		}
+		if action == "time" {
+			b.askNotifyTime(chatID, userID, msgID, arg, "")
+			return
+		}
+		settings := b.notifySettings(userID)
+		settings.Set(arg, action == "on", settings.At(arg))
+		if b.saveNotifySettings(settings) {
+			b.showNotifySettings(chatID, userID, msgID, "")
+		}
+	case "tz":
+		if arg == "" {
+			b.askNotifyTimezone(chatID, userID, msgID, "")
+			return
+		}
+		b.setNotifyTimezone(chatID, userID, msgID, arg)
+	case "quiet":
+		if arg == "" {
+			b.askNotifyInput(chatID, userID, msgID, l.Notify.QuietText, models.StateNotifyQuiet, "", l.Notify.Buttons.QuietOff, "notify:quiet:off")
+			return
+		}
+		settings := b.notifySettings(userID)
+		settings.QuietStart, settings.QuietEnd = 0, 0
+		if b.saveNotifySettings(settings) {
+			b.showNotifySettings(chatID, userID, msgID, l.Notify.Saved)
+		}
+	case "dinner":
+		b.handleRecipeRequest(chatID, userID, l.Notify.DinnerRequest, "", msgID)
+	default:
+		log.Printf("Некорректный callback уведомлений: %q", data)
+	}
+}
+
+// handleNotifyInput обрабатывает ввод времени уведомления, часового пояса и тихих часов
+func (b *Bot) handleNotifyInput(chatID, userID int64, text string, state *models.UserState) {
+	l := locales.Get()
+	settings := b.notifySettings(userID)
+
+	switch state.CurrentState {
+	case models.StateNotifyTime:
+		kind := state.InputData
+		at, ok := models.ParseClock(text)
+		if !ok {
+			b.askNotifyTime(chatID, userID, state.LastMessageID, kind, l.Notify.InvalidTime)
+			return
+		}
+		// Заданное время означает, что уведомление нужно
+		settings.Set(kind, true, at)
+	case models.StateNotifyTimezone:
+		b.setNotifyTimezone(chatID, userID, state.LastMessageID, text)
+		return
+	case models.StateNotifyQuiet:
+		start, end, ok := models.ParseQuietHours(text)
+		if !ok {
+			b.askNotifyInput(chatID, userID, state.LastMessageID, l.Notify.InvalidQuiet+"\n\n"+l.Notify.QuietText,
+				models.StateNotifyQuiet, "", l.Notify.Buttons.QuietOff, "notify:quiet:off")
+			return
+		}
+		settings.QuietStart, settings.QuietEnd = start, end
+	}
+
+	if b.saveNotifySettings(settings) {
+		b.showNotifySettings(chatID, userID, state.LastMessageID, l.Notify.Saved)
+	}
+}
+
+// askNotifyTime спрашивает время уведомления вида kind
+func (b *Bot) askNotifyTime(chatID, userID int64, msgID int, kind, notice string) {
+	text := fmt.Sprintf(locales.Get().Notify.TimeText, notifyKindName(kind))
+	if notice != "" {
+		text = notice + "\n\n" + text
+	}
+	b.askNotifyInput(chatID, userID, msgID, text, models.StateNotifyTime, kind, "", "")
+}
+
+// askNotifyTimezone предлагает выбрать часовой пояс кнопкой или ввести его
+func (b *Bot) askNotifyTimezone(chatID, userID int64, msgID int, notice string) {
+	l := locales.Get()
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, tz := range models.Timezones {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(tz.Name, "notify:tz:"+tz.Zone))
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(l.Notify.Buttons.Cancel, "notify:show"),
+	))
+
+	text := l.Notify.TimezoneText
+	if notice != "" {
+		text = notice + "\n\n" + text
+	}
+	b.sendOrEditMessage(chatID, userID, msgID, text, tgbotapi.NewInlineKeyboardMarkup(rows...), models.StateNotifyTimezone)
+}
+
+// askNotifyInput показывает запрос ввода с кнопкой отмены и, если задана, кнопкой extra.
+// inputData сохраняется в состоянии до ответа пользователя.
+func (b *Bot) askNotifyInput(chatID, userID int64, msgID int, text, newState, inputData, extraText, extraData string) {
+	l := locales.Get()
+
+	row := tgbotapi.NewInlineKeyboardRow()
+	if extraText != "" {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(extraText, extraData))
+	}
+	row = append(row, tgbotapi.NewInlineKeyboardButtonData(l.Notify.Buttons.Cancel, "notify:show"))
+	b.sendOrEditMessage(chatID, userID, msgID, text, tgbotapi.NewInlineKeyboardMarkup(row), newState)
+
+	if inputData == "" {
+		return
+	}
+	state, err := b.db.GetUserState(userID)
+	if err != nil {
+		log.Printf("Ошибка получения состояния: %v", err)
+		return
+	}
+	state.InputData = inputData
+	if err := b.db.SaveUserState(state); err != nil {
+		log.Printf("Ошибка сохранения состояния: %v", err)
+	}
+}
+
+// setNotifyTimezone сохраняет часовой пояс: расписание уведомлений пересчитывается
+func (b *Bot) setNotifyTimezone(chatID, userID int64, msgID int, name string) {
+	l := locales.Get()
+
+	_, name, ok := models.LoadTimezone(name)
+	if !ok {
+		b.askNotifyTimezone(chatID, userID, msgID, l.Notify.InvalidTimezone)
+		return
+	}
+
+	settings := b.notifySettings(userID)
+	settings.Timezone = name
+	if b.saveNotifySettings(settings) {
+		b.showNotifySettings(chatID, userID, msgID, l.Notify.Saved)
+	}
+}
+
+// ensureNotifySettings сохраняет настройки по умолчанию, если пользователь их ещё не менял,
+// чтобы заработали напоминания о сроках годности
+func (b *Bot) ensureNotifySettings(userID int64) {
+	settings, err := b.db.GetNotifySettings(userID)
+	if err != nil {
+		log.Printf("Ошибка получения настроек уведомлений: %v", err)
+		return
+	}
+	if settings == nil {
+		b.saveNotifySettings(models.DefaultNotifySettings(userID))
+	}
+}
+
+// saveNotifySettings сохраняет настройки и заново планирует уведомления
+func (b *Bot) saveNotifySettings(settings *models.NotifySettings) bool {
+	if err := b.db.SaveNotifySettings(settings, settings.Jobs(time.Now())); err != nil {
+		log.Printf("Ошибка сохранения настроек уведомлений: %v", err)
+		return false
+	}
+	return true
+}
+
+// notifySettings возвращает настройки уведомлений или настройки по умолчанию
+func (b *Bot) notifySettings(userID int64) *models.NotifySettings {
+	settings, err := b.db.GetNotifySettings(userID)
+	if err != nil {
+		log.Printf("Ошибка получения настроек уведомлений: %v", err)
+	}
+	if settings == nil {
+		settings = models.DefaultNotifySettings(userID)
+	}
+	return settings
+}
+
+// userLocation — часовой пояс пользователя из настроек уведомлений
+func (b *Bot) userLocation(userID int64) *time.Location {
+	return b.notifySettings(userID).Location()
+}
+
+// userToday — начало сегодняшнего дня в часовом поясе пользователя
+func (b *Bot) userToday(userID int64) time.Time {
+	today, _ := models.QuotaPeriods(time.Now().In(b.userLocation(userID)))
+	return today
+}
+
+// notifyKindName — название вида уведомления (пустая строка — неизвестный вид)
+func notifyKindName(kind string) string {
+	l := locales.Get()
+	switch kind {
+	case models.NotifyExpiry:
+		return l.Notify.Kinds.Expiry
+	case models.NotifyDinner:
+		return l.Notify.Kinds.Dinner
+	case models.NotifyPlan:
+		return l.Notify.Kinds.Plan
+	}
+	return ""
+}
+
+// timezoneName — город для поясов из models.Timezones, иначе имя пояса
+// («America/New York»: подчёркивание сломало бы разметку)
+func timezoneName(zone string) string {
+	for _, tz := range models.Timezones {
+		if tz.Zone == zone {
+			return tz.Name
+		}
+	}
+	return strings.ReplaceAll(zone, "_", " ")
+}
@@ -0,0 +1,41 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+
+	"github.com/pinghoyk/neurobot/internal/nutrition"
+	"github.com/pinghoyk/neurobot/pkg/locales"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// verifyNutrition сверяет пищевую ценность рецепта с расчётом по базе продуктов.
+// Если учтены все ингредиенты, значения модели заменяются расчётными; иначе
+// остаётся оценка модели с пометкой, каких продуктов не нашлось.
+func verifyNutrition(recipe string) string {
+	l := locales.Get()
+
+	estimate := nutrition.Calculate(recipe)
+	if estimate.Matched == 0 && len(estimate.Unmatched) == 0 {
+		// В рецепте нет ингредиентов с количеством — сверять не с чем
+		return recipe
+	}
+
+	if !estimate.Confident() {
+		log.Printf("Пищевая ценность не проверена, нет в базе продуктов: %s", strings.Join(estimate.Unmatched, ", "))
+		if estimate.Matched == 0 {
+			return recipe + "\n\n" + l.Nutrition.NotFound
+		}
+		return recipe + "\n\n" + fmt.Sprintf(l.Nutrition.Unverified,
+			strings.Join(estimate.Unmatched, "», «"), int(math.Round(estimate.PerServing.Calories)))
+	}
+
+	if replaced, ok := models.ReplaceNutrition(recipe, estimate.PerServing); ok {
+		return replaced + "\n\n" + l.Nutrition.Verified
+	}
+	n := estimate.PerServing
+	return recipe + "\n\n" + fmt.Sprintf(l.Nutrition.Computed,
+		int(math.Round(n.Calories)), int(math.Round(n.Protein)), int(math.Round(n.Fat)), int(math.Round(n.Carbs)))
+}
@@ -0,0 +1,345 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pinghoyk/neurobot/internal/servings"
+	"github.com/pinghoyk/neurobot/pkg/locales"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// maxPantryButtons — сколько продуктов можно удалить кнопками, остальные видны только в тексте
+const maxPantryButtons = 40
+
+var (
+	// pantrySplitRe — разделители продуктов во вводе: строки, «;» и запятые с пробелом
+	// (запятая без пробела — десятичная: «1,5 л»)
+	pantrySplitRe = regexp.MustCompile(`[\n;]|,\s+`)
+	// expiryRe — срок годности: «до 25.10», «годен до 25.10.2026»
+	expiryRe = regexp.MustCompile(`(?i)\s*(?:годен\s+)?до\s+(\d{1,2})[./](\d{1,2})(?:[./](\d{4}|\d{2}))?`)
+)
+
+// showPantry — запасы продуктов для команды /pantry
+func (b *Bot) showPantry(chatID, userID int64, editMsgID int) {
+	b.showPantryList(chatID, userID, editMsgID, "")
+}
+
+// showPantryList отображает запасы: сначала продукты с ближайшим сроком годности
+func (b *Bot) showPantryList(chatID, userID int64, editMsgID int, notice string) {
+	l := locales.Get()
+
+	items, err := b.db.ListPantryItems(userID)
+	if err != nil {
+		log.Printf("Ошибка получения запасов: %v", err)
+		return
+	}
+
+	text := l.Pantry.Title + "\n\n" + l.Pantry.Empty
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if len(items) > 0 {
+		today := b.userToday(userID)
+		var sb strings.Builder
+		sb.WriteString(l.Pantry.Title + "\n")
+		var row []tgbotapi.InlineKeyboardButton
+		for i, item := range items {
+			sb.WriteString("\n" + formatPantryItem(&item, today))
+			if i < maxPantryButtons {
+				row = append(row, tgbotapi.NewInlineKeyboardButtonData("🗑 "+item.Name,
+					fmt.Sprintf("pantry:del:%d", item.ID)))
+				if len(row) == 2 {
+					rows = append(rows, row)
+					row = nil
+				}
+			}
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+		text = sb.String()
+	}
+	if notice != "" {
+		text = notice + "\n\n" + text
+	}
+
+	actions := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(l.Pantry.Buttons.Add, "pantry:add"),
+	}
+	if len(items) > 0 {
+		actions = append(actions, tgbotapi.NewInlineKeyboardButtonData(l.Pantry.Buttons.Cook, "pantry:cook"))
+	}
+	rows = append(rows, actions, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(l.Pantry.Buttons.BackToMain, "menu:main"),
+	))
+
+	b.sendOrEditMessage(chatID, userID, editMsgID, text, tgbotapi.NewInlineKeyboardMarkup(rows...), models.StatePantry)
+}
+
+// handlePantryCallback обрабатывает кнопки запасов: add, cook, show, del:<id>, deduct:<id рецепта>
+func (b *Bot) handlePantryCallback(chatID, userID int64, msgID int, data string) {
+	l := locales.Get()
+
+	action, arg, _ := strings.Cut(data, ":")
+	switch action {
+	case "add":
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.Pantry.Buttons.Back, "pantry:show"),
+		))
+		b.sendOrEditMessage(chatID, userID, msgID, l.Pantry.AddText, keyboard, models.StatePantryAdd)
+	case "cook":
+		b.cookFromPantry(chatID, userID, msgID)
+	case "show":
+		b.showPantryList(chatID, userID, msgID, "")
+	case "del":
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			log.Printf("Некорректный ID продукта: %q", data)
+			return
+		}
+		if _, err := b.db.DeletePantryItem(userID, id); err != nil {
+			log.Printf("Ошибка удаления продукта из запасов: %v", err)
+		}
+		b.showPantryList(chatID, userID, msgID, "")
+	case "deduct":
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			log.Printf("Некорректный ID рецепта: %q", data)
+			return
+		}
+		b.deductRecipe(chatID, userID, msgID, id)
+	default:
+		log.Printf("Некорректный callback запасов: %q", data)
+	}
+}
+
+// handlePantryInput добавляет в запасы продукты, перечисленные в сообщении
+func (b *Bot) handlePantryInput(chatID, userID int64, text string, editMsgID int) {
+	l := locales.Get()
+
+	items := parsePantryInput(text, b.userToday(userID), time.Now())
+	if len(items) == 0 {
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.Pantry.Buttons.Back, "pantry:show"),
+		))
+		b.sendOrEditMessage(chatID, userID, editMsgID, l.Pantry.NothingAdded+"\n\n"+l.Pantry.AddText, keyboard, models.StatePantryAdd)
+		return
+	}
+
+	if err := b.db.AddPantryItems(userID, items); err != nil {
+		log.Printf("Ошибка добавления в запасы: %v", err)
+		return
+	}
+	for _, item := range items {
+		if item.Expires != "" {
+			b.ensureNotifySettings(userID)
+			break
+		}
+	}
+	b.showPantryList(chatID, userID, editMsgID, fmt.Sprintf(l.Pantry.Added, len(items)))
+}
+
+// cookFromPantry просит модель придумать блюдо из запасов, в первую очередь из того,
+// что скоро испортится. Рецепт приходит отдельным сообщением, список запасов остаётся.
+func (b *Bot) cookFromPantry(chatID, userID int64, msgID int) {
+	items, err := b.db.ListPantryItems(userID)
+	if err != nil {
+		log.Printf("Ошибка получения запасов: %v", err)
+		return
+	}
+
+	today := b.userToday(userID).Format(models.DayLayout)
+	var products []string
+	for _, item := range items {
+		if item.Expires != "" && item.Expires < today {
+			continue // просроченное не предлагаем
+		}
+		var details []string
+		if quantity := formatQuantity(item.Amount, item.Unit); quantity != "" {
+			details = append(details, quantity)
+		}
+		if item.Expires != "" {
+			details = append(details, "срок до "+formatExpiry(item.Expires))
+		}
+		product := item.Name
+		if len(details) > 0 {
+			product += " (" + strings.Join(details, ", ") + ")"
+		}
+		products = append(products, product)
+	}
+	if len(products) == 0 {
+		b.showPantryList(chatID, userID, msgID, locales.Get().Pantry.NothingToCook)
+		return
+	}
+
+	// Список уже отсортирован: продукты с ближайшим сроком идут первыми
+	request := "Что приготовить из продуктов, которые есть дома: " + strings.Join(products, ", ") + ".\n\n" +
+		"Используй как можно больше этих продуктов, в первую очередь те, у которых скоро истекает срок годности. " +
+		"Докупать можно только базовое: соль, сахар, растительное масло, специи."
+	b.handleRecipeRequest(chatID, userID, request, "", 0)
+}
+
+// askPantryDeduct предлагает списать из запасов ингредиенты приготовленного рецепта.
+// Подтверждение приходит отдельным сообщением, чтобы рецепт остался на экране.
+func (b *Bot) askPantryDeduct(chatID, userID, recipeID int64) {
+	l := locales.Get()
+
+	uses, lines, ok := b.pantryUses(chatID, userID, recipeID)
+	if !ok {
+		return
+	}
+	if len(uses) == 0 {
+		b.showPantryList(chatID, userID, 0, l.Pantry.NothingToDeduct)
+		return
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(l.Pantry.Buttons.Deduct, fmt.Sprintf("pantry:deduct:%d", recipeID)),
+		tgbotapi.NewInlineKeyboardButtonData(l.Pantry.Buttons.No, "pantry:show"),
+	))
+	b.sendOrEditMessage(chatID, userID, 0, fmt.Sprintf(l.Pantry.DeductConfirm, strings.Join(lines, "\n")), keyboard, models.StatePantry)
+}
+
+// deductRecipe списывает ингредиенты рецепта после подтверждения
+func (b *Bot) deductRecipe(chatID, userID int64, msgID int, recipeID int64) {
+	l := locales.Get()
+
+	uses, _, ok := b.pantryUses(chatID, userID, recipeID)
+	if !ok {
+		return
+	}
+	notice := l.Pantry.NothingToDeduct
+	if len(uses) > 0 {
+		if err := b.db.DeductPantryItems(userID, uses); err != nil {
+			log.Printf("Ошибка списания запасов: %v", err)
+			return
+		}
+		notice = l.Pantry.Deducted
+	}
+	b.showPantryList(chatID, userID, msgID, notice)
+}
+
+// pantryUses сопоставляет ингредиенты рецепта с запасами: списываются только продукты
+// с известным количеством в той же единице, не больше, чем есть. lines — строки для подтверждения.
+func (b *Bot) pantryUses(chatID, userID, recipeID int64) ([]models.PantryUse, []string, bool) {
+	recipe, err := b.db.GetRecipe(userID, recipeID)
+	if err != nil {
+		log.Printf("Ошибка получения рецепта: %v", err)
+		return nil, nil, false
+	}
+	if recipe == nil {
+		b.sendText(chatID, locales.Get().Recipes.NotFound)
+		return nil, nil, false
+	}
+
+	items, err := b.db.ListPantryItems(userID)
+	if err != nil {
+		log.Printf("Ошибка получения запасов: %v", err)
+		return nil, nil, false
+	}
+
+	var uses []models.PantryUse
+	var lines []string
+	used := make(map[int64]bool)
+	for _, ing := range servings.ParseIngredients(recipe.Text) {
+		if ing.Amount <= 0 {
+			continue
+		}
+		for _, item := range items {
+			if used[item.ID] || item.Amount <= 0 || item.Unit != ing.Unit || !servings.SameProduct(item.Name, ing.Name) {
+				continue
+			}
+			amount := math.Min(ing.Amount, item.Amount)
+			used[item.ID] = true
+			uses = append(uses, models.PantryUse{ItemID: item.ID, Amount: amount})
+			lines = append(lines, "• "+item.Name+" — "+formatQuantity(amount, item.Unit))
+			break
+		}
+	}
+	return uses, lines, true
+}
+
+// parsePantryInput разбирает продукты из сообщения: «молоко 1 л до 25.10, яйца 10 шт, соль»
+func parsePantryInput(text string, today, now time.Time) []models.PantryItem {
+	var items []models.PantryItem
+	for _, chunk := range pantrySplitRe.Split(text, -1) {
+		expires := ""
+		if m := expiryRe.FindStringSubmatch(chunk); m != nil {
+			if day, ok := parseExpiry(m[1], m[2], m[3], today); ok {
+				expires = day
+				chunk = strings.Replace(chunk, m[0], "", 1)
+			}
+		}
+
+		ing, ok := servings.ParseItem(chunk)
+		if !ok {
+			continue
+		}
+		items = append(items, models.PantryItem{
+			Name:      capitalize(ing.Name),
+			Amount:    ing.Amount,
+			Unit:      ing.Unit,
+			Expires:   expires,
+			CreatedAt: now,
+		})
+	}
+	return items
+}
+
+// parseExpiry собирает дату срока годности. Без года берётся ближайшая такая дата:
+// «до 05.01», введённое в конце декабря, — это январь следующего года.
+func parseExpiry(dayText, monthText, yearText string, today time.Time) (string, bool) {
+	day, _ := strconv.Atoi(dayText)
+	month, _ := strconv.Atoi(monthText)
+	year := today.Year()
+	if yearText != "" {
+		year, _ = strconv.Atoi(yearText)
+		if year < 100 {
+			year += 2000
+		}
+	}
+
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, today.Location())
+	if t.Day() != day || int(t.Month()) != month {
+		return "", false // 31.02 и подобное
+	}
+	if yearText == "" && t.Before(today.AddDate(0, -6, 0)) {
+		t = t.AddDate(1, 0, 0)
+	}
+	return t.Format(models.DayLayout), true
+}
+
+// formatPantryItem — «⚠️ Молоко — 1,5 л · до 19.10»; просроченное и то, что истекает
+// сегодня или завтра, отмечено значком
+func formatPantryItem(item *models.PantryItem, today time.Time) string {
+	text := item.Name
+	if quantity := formatQuantity(item.Amount, item.Unit); quantity != "" {
+		text += " — " + quantity
+	}
+	if item.Expires == "" {
+		return "▫️ " + text
+	}
+
+	text += " · до " + formatExpiry(item.Expires)
+	switch {
+	case item.Expires < today.Format(models.DayLayout):
+		return "❌ " + text + " (" + locales.Get().Pantry.Expired + ")"
+	case item.Expires <= today.AddDate(0, 0, 1).Format(models.DayLayout):
+		return "⚠️ " + text
+	}
+	return "▫️ " + text
+}
+
+// formatExpiry — срок годности в виде «19.10»
+func formatExpiry(day string) string {
+	t, err := time.Parse(models.DayLayout, day)
+	if err != nil {
+		return day
+	}
+	return t.Format("02.01")
+}
@@ -0,0 +1,408 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pinghoyk/neurobot/pkg/locales"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// planMaxFixes — сколько блюд можно догенерировать по одному после плана: заполнить
+// пропуски и развести повторяющийся белок. Остальное пользователь заменит сам.
+// Каждая догенерация расходует отдельную генерацию квоты.
+const planMaxFixes = 6
+
+// planMinMeals — меньше блюд в ответе модели означает, что план не распознан
+const planMinMeals = models.PlanDays * 2
+
+var (
+	planWeekdays = [...]string{"Воскресенье", "Понедельник", "Вторник", "Среда", "Четверг", "Пятница", "Суббота"}
+	planMonths   = [...]string{"января", "февраля", "марта", "апреля", "мая", "июня",
+		"июля", "августа", "сентября", "октября", "ноября", "декабря"}
+)
+
+// showPlan — план питания на сегодня для команды /plan
+func (b *Bot) showPlan(chatID, userID int64, editMsgID int) {
+	b.showPlanDay(chatID, userID, editMsgID, b.userToday(userID), "")
+}
+
+// showPlanDay отображает блюда плана на день с переходом к соседним дням.
+// Если на день ничего не запланировано, предлагает составить план.
+func (b *Bot) showPlanDay(chatID, userID int64, editMsgID int, day time.Time, notice string) {
+	l := locales.Get()
+
+	date := day.Format(models.DayLayout)
+	prev, next := day.AddDate(0, 0, -1), day.AddDate(0, 0, 1)
+	meals, err := b.db.GetPlanMeals(userID, prev.Format(models.DayLayout), next.Format(models.DayLayout))
+	if err != nil {
+		log.Printf("Ошибка получения плана питания: %v", err)
+		return
+	}
+
+	byDay := make(map[string]map[string]models.PlanMeal)
+	for _, m := range meals {
+		if byDay[m.Day] == nil {
+			byDay[m.Day] = make(map[string]models.PlanMeal)
+		}
+		byDay[m.Day][m.Slot] = m
+	}
+
+	prefs, ok := b.userPreferences(chatID, userID, editMsgID)
+	if !ok {
+		return
+	}
+	daily := models.PlanDailyCalories(prefs)
+
+	dayMeals := byDay[date]
+	if len(dayMeals) == 0 {
+		text := fmt.Sprintf(l.Plan.Intro, daily)
+		if notice != "" {
+			text = notice + "\n\n" + text
+		}
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(l.Plan.Buttons.Create, "plan:new"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(l.Plan.Buttons.BackToMain, "menu:main"),
+			),
+		)
+		b.sendOrEditMessage(chatID, userID, editMsgID, text, keyboard, models.StatePlan)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(l.Plan.Title + "\n*" + formatPlanDay(day, b.userToday(userID)) + "*")
+	total := 0
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, slot := range models.PlanSlots {
+		sb.WriteString("\n\n" + slot.Emoji + " *" + slot.Name + "*")
+		callback := date + ":" + slot.Code
+
+		m, ok := dayMeals[slot.Code]
+		if !ok {
+			sb.WriteString(" — _" + l.Plan.NotPlanned + "_")
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(l.Plan.Buttons.Swap, "plan:swap:"+callback),
+			))
+			continue
+		}
+
+		total += m.Calories
+		sb.WriteString(" — " + m.Title)
+		sb.WriteString(fmt.Sprintf("\n~%d ккал", m.Calories))
+		if m.Protein != models.ProteinNone {
+			sb.WriteString(" · белок: " + m.Protein)
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf(l.Plan.Buttons.Recipe, slot.Name), "plan:recipe:"+callback),
+			tgbotapi.NewInlineKeyboardButtonData(l.Plan.Buttons.Swap, "plan:swap:"+callback),
+		))
+	}
+	sb.WriteString("\n\n" + fmt.Sprintf(l.Plan.Total, total, daily))
+
+	var nav []tgbotapi.InlineKeyboardButton
+	if len(byDay[prev.Format(models.DayLayout)]) > 0 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(l.Plan.Buttons.Prev, "plan:day:"+prev.Format(models.DayLayout)))
+	}
+	if len(byDay[next.Format(models.DayLayout)]) > 0 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(l.Plan.Buttons.Next, "plan:day:"+next.Format(models.DayLayout)))
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.Plan.Buttons.Renew, "plan:renew:"+date),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.Plan.Buttons.BackToMain, "menu:main"),
+		),
+	)
+
+	text := sb.String()
+	if notice != "" {
+		text = notice + "\n\n" + text
+	}
+	b.sendOrEditMessage(chatID, userID, editMsgID, text, tgbotapi.NewInlineKeyboardMarkup(rows...), models.StatePlan)
+}
+
+// handlePlanCallback обрабатывает кнопки плана: new, renew:<день>, day:<день>,
+// recipe:<день>:<приём> и swap:<день>:<приём>
+func (b *Bot) handlePlanCallback(chatID, userID int64, msgID int, data string) {
+	l := locales.Get()
+
+	parts := strings.Split(data, ":")
+	if parts[0] == "new" {
+		b.generatePlan(chatID, userID, msgID)
+		return
+	}
+	if len(parts) < 2 {
+		log.Printf("Некорректный callback плана: %q", data)
+		return
+	}
+	day, err := time.ParseInLocation(models.DayLayout, parts[1], b.userLocation(userID))
+	if err != nil {
+		log.Printf("Некорректный день плана: %q", data)
+		return
+	}
+
+	switch parts[0] {
+	case "day":
+		b.showPlanDay(chatID, userID, msgID, day, "")
+	case "renew":
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.Plan.Buttons.Yes, "plan:new"),
+			tgbotapi.NewInlineKeyboardButtonData(l.Plan.Buttons.No, "plan:day:"+parts[1]),
+		))
+		b.sendOrEditMessage(chatID, userID, msgID, l.Plan.RenewConfirm, keyboard, models.StatePlan)
+	case "recipe", "swap":
+		var slot models.PlanSlot
+		ok := len(parts) > 2
+		if ok {
+			slot, ok = models.PlanSlotByCode(parts[2])
+		}
+		if !ok {
+			log.Printf("Некорректный приём пищи в плане: %q", data)
+			return
+		}
+		if parts[0] == "swap" {
+			b.swapPlanMeal(chatID, userID, msgID, day, slot)
+		} else {
+			b.planMealRecipe(chatID, userID, day, slot)
+		}
+	default:
+		log.Printf("Неизвестное действие с планом: %q", data)
+	}
+}
+
+// generatePlan составляет план на PlanDays дней начиная с сегодня и показывает первый день
+func (b *Bot) generatePlan(chatID, userID int64, msgID int) {
+	l := locales.Get()
+
+	prefs, ok := b.userPreferences(chatID, userID, msgID)
+	if !ok {
+		return
+	}
+	genID := b.reserveGeneration(chatID, userID)
+	if genID == 0 {
+		return
+	}
+	b.sendOrEditMessage(chatID, userID, msgID, l.Plan.Generating, waitKeyboard(), models.StatePlan)
+
+	start := b.userToday(userID)
+
+	text, err := b.gigachat.GenerateMealPlan(prefs, models.PlanDays)
+	var meals []models.PlanMeal
+	if err == nil {
+		meals = models.ParseMealPlan(text, start)
+		if len(meals) < planMinMeals {
+			err = fmt.Errorf("план не распознан: %d блюд", len(meals))
+		}
+	}
+	b.finishGeneration(genID, err)
+	if err != nil {
+		log.Printf("Ошибка генерации плана: %v", err)
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(l.Plan.Buttons.Create, "plan:new"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(l.Plan.Buttons.BackToMain, "menu:main"),
+			),
+		)
+		b.sendOrEditMessage(chatID, userID, msgID, l.Plan.Failed, keyboard, models.StatePlan)
+		return
+	}
+
+	meals = b.completePlan(userID, prefs, start, meals)
+
+	now := time.Now()
+	for i := range meals {
+		meals[i].UserID = userID
+		meals[i].CreatedAt = now
+	}
+	if err := b.db.SavePlanMeals(userID, meals); err != nil {
+		log.Printf("Ошибка сохранения плана питания: %v", err)
+		b.sendOrEditMessage(chatID, userID, msgID, l.Plan.Failed, tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(l.Plan.Buttons.BackToMain, "menu:main")),
+		), models.StatePlan)
+		return
+	}
+
+	notice := l.Plan.Ready
+	if issues := planIssues(meals, start); len(issues) > 0 {
+		notice = fmt.Sprintf(l.Plan.Unresolved, strings.Join(issues, "\n"))
+	}
+	b.showPlanDay(chatID, userID, msgID, start, notice)
+}
+
+// completePlan догенерирует по одному блюда на пустые места и вместо блюд,
+// белок которых повторяет предыдущий день, — не больше planMaxFixes раз.
+// Каждое блюдо резервируется в квоте; когда квота кончается, догенерация останавливается.
+func (b *Bot) completePlan(userID int64, prefs *models.UserPreferences, start time.Time, meals []models.PlanMeal) []models.PlanMeal {
+	for fixes := 0; fixes < planMaxFixes; fixes++ {
+		i := -1
+		var target models.PlanMeal
+		if gaps := models.PlanGaps(meals, start, models.PlanDays); len(gaps) > 0 {
+			target = gaps[0]
+		} else if conflicts := models.PlanConflicts(meals); len(conflicts) > 0 {
+			i = conflicts[0]
+			target = meals[i]
+		} else {
+			break
+		}
+
+		genID, _, err := b.reserveQuota(userID)
+		if err != nil {
+			log.Printf("Ошибка проверки квоты: %v", err)
+			break
+		}
+		if genID == 0 {
+			log.Printf("Квота пользователя %d исчерпана, план сохранён без догенерации", userID)
+			break
+		}
+
+		meal, err := b.generatePlanMeal(prefs, meals, target)
+		b.finishGeneration(genID, err)
+		if err != nil {
+			log.Printf("Ошибка генерации блюда для плана: %v", err)
+			break
+		}
+		if i >= 0 {
+			meals[i] = meal
+		} else {
+			meals = append(meals, meal)
+		}
+	}
+	return meals
+}
+
+// planIssues перечисляет блюда, которые completePlan не исправил: пустые места
+// и повтор белка предыдущего дня — «Вторник, 21 октября: обед»
+func planIssues(meals []models.PlanMeal, start time.Time) []string {
+	targets := models.PlanGaps(meals, start, models.PlanDays)
+	for _, i := range models.PlanConflicts(meals) {
+		targets = append(targets, meals[i])
+	}
+	sort.SliceStable(targets, func(i, j int) bool { return targets[i].Day < targets[j].Day })
+
+	var issues []string
+	for _, m := range targets {
+		day, err := time.ParseInLocation(models.DayLayout, m.Day, start.Location())
+		slot, ok := models.PlanSlotByCode(m.Slot)
+		if err != nil || !ok {
+			continue
+		}
+		issues = append(issues, "• "+formatPlanDay(day, start)+": "+strings.ToLower(slot.Name))
+	}
+	return issues
+}
+
+// generatePlanMeal предлагает блюдо на место target: белок не из соседних дней,
+// название не повторяет блюда из meals
+func (b *Bot) generatePlanMeal(prefs *models.UserPreferences, meals []models.PlanMeal, target models.PlanMeal) (models.PlanMeal, error) {
+	slot, ok := models.PlanSlotByCode(target.Slot)
+	if !ok {
+		return models.PlanMeal{}, fmt.Errorf("неизвестный приём пищи %q", target.Slot)
+	}
+
+	var others []models.PlanMeal
+	var titles []string
+	for _, m := range meals {
+		if m.Day == target.Day && m.Slot == target.Slot {
+			// Заменяемое блюдо не должно вернуться, но его белок не мешает новому
+			titles = append(titles, m.Title)
+			continue
+		}
+		others = append(others, m)
+		titles = append(titles, m.Title)
+	}
+
+	text, err := b.gigachat.GenerateMeal(prefs, slot, models.PlanNeighborProteins(others, target.Day), titles)
+	if err != nil {
+		return models.PlanMeal{}, err
+	}
+	meal, ok := models.ParsePlanMeal(text)
+	if !ok {
+		return models.PlanMeal{}, fmt.Errorf("блюдо не распознано: %q", text)
+	}
+	meal.Day, meal.Slot = target.Day, target.Slot
+	return meal, nil
+}
+
+// swapPlanMeal заменяет одно блюдо плана, не нарушая правило разнообразия белка
+func (b *Bot) swapPlanMeal(chatID, userID int64, msgID int, day time.Time, slot models.PlanSlot) {
+	l := locales.Get()
+
+	prefs, ok := b.userPreferences(chatID, userID, msgID)
+	if !ok {
+		return
+	}
+	genID := b.reserveGeneration(chatID, userID)
+	if genID == 0 {
+		return
+	}
+	b.sendOrEditMessage(chatID, userID, msgID, l.Plan.Swapping, waitKeyboard(), models.StatePlan)
+
+	date := day.Format(models.DayLayout)
+	meals, err := b.db.GetPlanMeals(userID, day.AddDate(0, 0, -1).Format(models.DayLayout), day.AddDate(0, 0, 1).Format(models.DayLayout))
+	if err != nil {
+		log.Printf("Ошибка получения плана питания: %v", err)
+		b.finishGeneration(genID, err)
+		return
+	}
+
+	meal, err := b.generatePlanMeal(prefs, meals, models.PlanMeal{Day: date, Slot: slot.Code})
+	b.finishGeneration(genID, err)
+	if err != nil {
+		log.Printf("Ошибка замены блюда в плане: %v", err)
+		b.showPlanDay(chatID, userID, msgID, day, l.Plan.SwapFailed)
+		return
+	}
+
+	meal.UserID, meal.CreatedAt = userID, time.Now()
+	if err := b.db.SavePlanMeals(userID, []models.PlanMeal{meal}); err != nil {
+		log.Printf("Ошибка сохранения плана питания: %v", err)
+		b.showPlanDay(chatID, userID, msgID, day, l.Plan.SwapFailed)
+		return
+	}
+	b.showPlanDay(chatID, userID, msgID, day, "")
+}
+
+// planMealRecipe генерирует полный рецепт блюда из плана отдельным сообщением,
+// чтобы план остался на экране
+func (b *Bot) planMealRecipe(chatID, userID int64, day time.Time, slot models.PlanSlot) {
+	date := day.Format(models.DayLayout)
+	meals, err := b.db.GetPlanMeals(userID, date, date)
+	if err != nil {
+		log.Printf("Ошибка получения плана питания: %v", err)
+		return
+	}
+	for _, m := range meals {
+		if m.Slot == slot.Code {
+			request := fmt.Sprintf("%s: %s, ~%d ккал на порцию.", slot.Name, m.Title, m.Calories)
+			b.handleRecipeRequest(chatID, userID, request, "", 0)
+			return
+		}
+	}
+}
+
+// waitKeyboard — пустая клавиатура: убирает кнопки, пока идёт генерация
+func waitKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}
+}
+
+// formatPlanDay — «Понедельник, 20 октября · сегодня»
+func formatPlanDay(day, today time.Time) string {
+	text := fmt.Sprintf("%s, %d %s", planWeekdays[day.Weekday()], day.Day(), planMonths[day.Month()-1])
+	if day.Equal(today) {
+		text += " · сегодня"
+	}
+	return text
+}
@@ -0,0 +1,347 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pinghoyk/neurobot/internal/gigachat"
+	"github.com/pinghoyk/neurobot/internal/servings"
+	"github.com/pinghoyk/neurobot/pkg/locales"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// recipesPageSize — сколько рецептов показывается на одной странице истории и избранного
+const recipesPageSize = 5
+
+// recipeRefinement — доработка рецепта кнопкой под ним: запрос генерируется заново
+// с исходным запросом и уточнением
+type recipeRefinement struct {
+	action string // действие в callback-данных
+	prompt string // уточнение для модели; %s — название исходного блюда
+	label  func(l *locales.Locales) string
+}
+
+var recipeRefinements = []recipeRefinement{
+	{"another", "Предложи другое блюдо — не «%s».", func(l *locales.Locales) string { return l.Recipes.Buttons.Another }},
+	{"spicier", "Сделай блюдо острее.", func(l *locales.Locales) string { return l.Recipes.Buttons.Spicier }},
+	{"lighter", "Сделай блюдо легче: меньше калорий и жира.", func(l *locales.Locales) string { return l.Recipes.Buttons.Lighter }},
+	{"faster", "Сделай блюдо быстрее: не больше 20 минут на всё приготовление.", func(l *locales.Locales) string { return l.Recipes.Buttons.Faster }},
+	{"cheaper", "Сделай блюдо дешевле: только недорогие продукты из обычного магазина.", func(l *locales.Locales) string { return l.Recipes.Buttons.Cheaper }},
+}
+
+func recipeRefinementByAction(action string) (recipeRefinement, bool) {
+	for _, r := range recipeRefinements {
+		if r.action == action {
+			return r, true
+		}
+	}
+	return recipeRefinement{}, false
+}
+
+// recipeList — список, из которого открыт рецепт, и страница в нём.
+// В callback-данных кодируется как "h2" (история, страница 2) или "f0" (избранное).
+type recipeList struct {
+	favorites bool
+	page      int
+}
+
+func (r recipeList) String() string {
+	if r.favorites {
+		return "f" + strconv.Itoa(r.page)
+	}
+	return "h" + strconv.Itoa(r.page)
+}
+
+// callback возвращает callback-данные для показа страницы списка
+func (r recipeList) callback() string {
+	if r.favorites {
+		return "favorites:" + strconv.Itoa(r.page)
+	}
+	return "history:" + strconv.Itoa(r.page)
+}
+
+// listSuffix — окончание callback-данных с исходным списком рецептов
+func listSuffix(list *recipeList) string {
+	if list == nil {
+		return ""
+	}
+	return ":" + list.String()
+}
+
+func parseRecipeList(s string) (recipeList, bool) {
+	if len(s) < 2 || (s[0] != 'h' && s[0] != 'f') {
+		return recipeList{}, false
+	}
+	page, err := strconv.Atoi(s[1:])
+	if err != nil || page < 0 {
+		return recipeList{}, false
+	}
+	return recipeList{favorites: s[0] == 'f', page: page}, true
+}
+
+// saveRecipe сохраняет сгенерированный рецепт в историю; 0 — сохранить не удалось
+func (b *Bot) saveRecipe(userID int64, request, refinement, text string, prefs *models.UserPreferences) int64 {
+	id, err := b.db.SaveRecipe(&models.Recipe{
+		UserID:        userID,
+		Request:       request,
+		Refinement:    refinement,
+		Title:         models.RecipeTitle(text),
+		Text:          text,
+		Preferences:   prefs,
+		Model:         gigachat.Model,
+		PromptVersion: gigachat.PromptVersion,
+		CreatedAt:     time.Now(),
+	})
+	if err != nil {
+		log.Printf("Ошибка сохранения рецепта: %v", err)
+		return 0
+	}
+	return id
+}
+
+// recipeKeyboard — кнопки под рецептом; rating — оценка пользователя (0 — нет), list == nil — рецепт только что сгенерирован
+func recipeKeyboard(recipe *models.Recipe, rating int, list *recipeList) tgbotapi.InlineKeyboardMarkup {
+	l := locales.Get()
+
+	suffix := listSuffix(list)
+	id := strconv.FormatInt(recipe.ID, 10)
+
+	favorite := tgbotapi.NewInlineKeyboardButtonData(l.Recipes.Buttons.Save, "recipe:fav:"+id+suffix)
+	if recipe.IsFavorite {
+		favorite = tgbotapi.NewInlineKeyboardButtonData(l.Recipes.Buttons.Saved, "recipe:unfav:"+id+suffix)
+	}
+
+	// «Другой вариант» отдельной строкой, остальные доработки по две в ряд
+	rows := [][]tgbotapi.InlineKeyboardButton{{}}
+	for i, r := range recipeRefinements {
+		if i%2 == 1 {
+			rows = append(rows, nil)
+		}
+		last := len(rows) - 1
+		rows[last] = append(rows[last], tgbotapi.NewInlineKeyboardButtonData(r.label(l), "recipe:"+r.action+":"+id))
+	}
+
+	rows = append(rows, feedbackRow(id, suffix, rating))
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		favorite,
+		tgbotapi.NewInlineKeyboardButtonData(l.Shopping.Buttons.Add, "recipe:shop:"+id+suffix),
+	))
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(l.Diary.Buttons.Log, "recipe:ate:"+id+suffix),
+	))
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(l.Servings.Buttons.Scale, "scale:"+id+":1:"+string(servings.UnitsOriginal)+suffix),
+		tgbotapi.NewInlineKeyboardButtonData(l.Pantry.Buttons.Used, "recipe:cooked:"+id+suffix),
+	))
+	if list != nil {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.Recipes.Buttons.Delete, "recipe:del:"+id+suffix),
+			tgbotapi.NewInlineKeyboardButtonData(l.Recipes.Buttons.BackToList, list.callback()),
+		))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// showRecipeList отображает страницу истории или избранного
+func (b *Bot) showRecipeList(chatID, userID int64, editMsgID int, list recipeList) {
+	l := locales.Get()
+
+	title, empty, state := l.Recipes.HistoryTitle, l.Recipes.HistoryEmpty, models.StateHistory
+	if list.favorites {
+		title, empty, state = l.Recipes.FavoritesTitle, l.Recipes.FavoritesEmpty, models.StateFavorites
+	}
+
+	recipes, total, err := b.db.ListRecipes(userID, list.favorites, list.page*recipesPageSize, recipesPageSize)
+	if err != nil {
+		log.Printf("Ошибка получения рецептов: %v", err)
+		return
+	}
+
+	// После удаления последнего рецепта на странице возвращаемся на последнюю непустую
+	pages := (total + recipesPageSize - 1) / recipesPageSize
+	if len(recipes) == 0 && list.page > 0 && pages > 0 {
+		b.showRecipeList(chatID, userID, editMsgID, recipeList{favorites: list.favorites, page: pages - 1})
+		return
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	text := title + "\n\n" + empty
+	if total > 0 {
+		var lines []string
+		for i, r := range recipes {
+			mark := ""
+			if r.IsFavorite && !list.favorites {
+				mark = " ⭐"
+			}
+			lines = append(lines, fmt.Sprintf("%d. %s%s — %s", list.page*recipesPageSize+i+1,
+				recipeName(&r), mark, r.CreatedAt.Format("02.01.2006")))
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+				"📖 "+recipeName(&r), fmt.Sprintf("recipe:open:%d:%s", r.ID, list))))
+		}
+		text = fmt.Sprintf("%s\n%s\n\n%s", title, fmt.Sprintf(l.Recipes.Page, list.page+1, pages), strings.Join(lines, "\n"))
+
+		var nav []tgbotapi.InlineKeyboardButton
+		if list.page > 0 {
+			prev := recipeList{favorites: list.favorites, page: list.page - 1}
+			nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(l.Recipes.Buttons.Prev, prev.callback()))
+		}
+		if list.page+1 < pages {
+			next := recipeList{favorites: list.favorites, page: list.page + 1}
+			nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(l.Recipes.Buttons.Next, next.callback()))
+		}
+		if len(nav) > 0 {
+			rows = append(rows, nav)
+		}
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(l.Recipes.Buttons.BackToMain, "menu:main"),
+	))
+
+	b.sendOrEditMessage(chatID, userID, editMsgID, text, tgbotapi.NewInlineKeyboardMarkup(rows...), state)
+}
+
+// showHistory и showFavorites — первые страницы списков для команд /history и /favorites
+func (b *Bot) showHistory(chatID, userID int64, editMsgID int) {
+	b.showRecipeList(chatID, userID, editMsgID, recipeList{})
+}
+
+func (b *Bot) showFavorites(chatID, userID int64, editMsgID int) {
+	b.showRecipeList(chatID, userID, editMsgID, recipeList{favorites: true})
+}
+
+// handleRecipeListCallback обрабатывает переход по страницам: history:<стр> или favorites:<стр>
+func (b *Bot) handleRecipeListCallback(chatID, userID int64, msgID int, favorites bool, pageArg string) {
+	page, err := strconv.Atoi(pageArg)
+	if err != nil || page < 0 {
+		log.Printf("Некорректная страница рецептов: %q", pageArg)
+		return
+	}
+	b.showRecipeList(chatID, userID, msgID, recipeList{favorites: favorites, page: page})
+}
+
+// handleRecipeCallback обрабатывает действия с рецептом: <действие>:<id>[:<список>]
+func (b *Bot) handleRecipeCallback(chatID, userID int64, msgID int, data string) {
+	parts := strings.Split(data, ":")
+	if len(parts) < 2 {
+		log.Printf("Некорректный callback рецепта: %q", data)
+		return
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		log.Printf("Некорректный ID рецепта: %q", data)
+		return
+	}
+
+	var list *recipeList
+	if len(parts) > 2 {
+		parsed, ok := parseRecipeList(parts[2])
+		if !ok {
+			log.Printf("Некорректный список рецептов: %q", data)
+			return
+		}
+		list = &parsed
+	}
+
+	switch parts[0] {
+	case "open":
+		b.openRecipe(chatID, userID, msgID, id, list)
+	case "shop":
+		b.addRecipeToShopping(chatID, userID, id)
+	case "cooked":
+		b.askPantryDeduct(chatID, userID, id)
+	case "ate":
+		b.logRecipe(chatID, userID, id)
+	case "fav", "unfav":
+		b.setRecipeFavorite(chatID, userID, msgID, id, parts[0] == "fav", list)
+	case "del":
+		if _, err := b.db.DeleteRecipe(userID, id); err != nil {
+			log.Printf("Ошибка удаления рецепта: %v", err)
+		}
+		if list == nil {
+			list = &recipeList{}
+		}
+		b.showRecipeList(chatID, userID, msgID, *list)
+	default:
+		if refinement, ok := recipeRefinementByAction(parts[0]); ok {
+			b.refineRecipe(chatID, userID, msgID, id, refinement)
+			return
+		}
+		log.Printf("Неизвестное действие с рецептом: %q", data)
+	}
+}
+
+// refineRecipe генерирует новый рецепт по исходному запросу с уточнением.
+// Доработки не сочетаются: новое уточнение заменяет прежнее.
+func (b *Bot) refineRecipe(chatID, userID int64, msgID int, id int64, refinement recipeRefinement) {
+	recipe, err := b.db.GetRecipe(userID, id)
+	if err != nil {
+		log.Printf("Ошибка получения рецепта: %v", err)
+		return
+	}
+	if recipe == nil {
+		b.sendText(chatID, locales.Get().Recipes.NotFound)
+		return
+	}
+
+	prompt := refinement.prompt
+	if strings.Contains(prompt, "%s") {
+		prompt = fmt.Sprintf(prompt, recipeName(recipe))
+	}
+	// Уточнение заменяет прежнее, а не копится: доработка строится от исходного запроса
+	b.handleRecipeRequest(chatID, userID, recipe.Request, prompt, msgID)
+}
+
+// openRecipe показывает сохранённый рецепт. list == nil — рецепт открыт не из списка
+// (например, возврат к только что сгенерированному рецепту после пересчёта порций).
+func (b *Bot) openRecipe(chatID, userID int64, msgID int, id int64, list *recipeList) {
+	recipe, err := b.db.GetRecipe(userID, id)
+	if err != nil {
+		log.Printf("Ошибка получения рецепта: %v", err)
+		return
+	}
+	if recipe == nil {
+		back := recipeList{}
+		if list != nil {
+			back = *list
+		}
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(locales.Get().Recipes.Buttons.BackToList, back.callback()),
+		))
+		b.sendOrEditMessage(chatID, userID, msgID, locales.Get().Recipes.NotFound, keyboard, models.StateRecipe)
+		return
+	}
+
+	b.sendOrEditMessage(chatID, userID, msgID, recipe.Text, recipeKeyboard(recipe, b.recipeRating(userID, id), list), models.StateRecipe)
+}
+
+// setRecipeFavorite добавляет рецепт в избранное или убирает из него и обновляет кнопки под сообщением
+func (b *Bot) setRecipeFavorite(chatID, userID int64, msgID int, id int64, favorite bool, list *recipeList) {
+	ok, err := b.db.SetRecipeFavorite(userID, id, favorite)
+	if err != nil {
+		log.Printf("Ошибка обновления избранного: %v", err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	recipe := &models.Recipe{ID: id, IsFavorite: favorite}
+	edit := tgbotapi.NewEditMessageReplyMarkup(chatID, msgID, recipeKeyboard(recipe, b.recipeRating(userID, id), list))
+	if err := b.request(chatID, edit); err != nil {
+		log.Printf("Не удалось обновить кнопки рецепта: %v", err)
+	}
+}
+
+// recipeName — название рецепта для списков
+func recipeName(r *models.Recipe) string {
+	if r.Title != "" {
+		return r.Title
+	}
+	return models.RecipeTitle(r.Request)
+}
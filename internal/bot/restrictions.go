@@ -0,0 +1,120 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pinghoyk/neurobot/pkg/locales"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// showRestrictionsMenu отображает справочник ограничений питания с переключателями
+func (b *Bot) showRestrictionsMenu(chatID, userID int64, editMsgID int) {
+	l := locales.Get()
+
+	prefs, ok := b.userPreferences(chatID, userID, editMsgID)
+	if !ok {
+		return
+	}
+
+	// По два ограничения в ряд; в callback передаём желаемое состояние, как и для аллергенов
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, r := range models.DietRestrictions {
+		label, action := r.Name, "on"
+		if prefs.HasDietRestriction(r.Code) {
+			label, action = "✅ "+r.Name, "off"
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, "restriction:"+action+":"+r.Code))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(l.RestrictionsMenu.Buttons.Done, "menu:settings"),
+	))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	b.sendOrEditMessage(chatID, userID, editMsgID, l.RestrictionsMenu.Text, keyboard, models.StateSettingsRestrictions)
+}
+
+// toggleDietRestriction выбирает ограничение или снимает выбор; data — "on:<код>" или "off:<код>"
+func (b *Bot) toggleDietRestriction(chatID, userID int64, msgID int, data string) {
+	action, code, _ := strings.Cut(data, ":")
+	if _, ok := models.DietRestrictionByCode(code); !ok || (action != "on" && action != "off") {
+		log.Printf("Некорректный callback ограничения: %q", data)
+		return
+	}
+
+	if err := b.db.SetDietRestriction(userID, code, action == "on"); err != nil {
+		log.Printf("Ошибка сохранения ограничения питания: %v", err)
+	}
+
+	b.showRestrictionsMenu(chatID, userID, msgID)
+}
+
+// restrictionWarning проверяет ингредиенты рецепта по ключевым словам выбранных ограничений
+// и возвращает предупреждение для пользователя (пустая строка — нарушений не найдено)
+func restrictionWarning(prefs *models.UserPreferences, recipe string) string {
+	if prefs == nil || len(prefs.DietRestrictions) == 0 {
+		return ""
+	}
+
+	ingredients := ingredientsSection(recipe)
+	if ingredients == "" {
+		return ""
+	}
+
+	var problems []string
+	for _, r := range prefs.SelectedDietRestrictions() {
+		if words := r.Violations(ingredients); len(words) > 0 {
+			problems = append(problems, fmt.Sprintf("%s: %s", r.Name, strings.Join(uniqueStrings(words), ", ")))
+		}
+	}
+	if len(problems) == 0 {
+		return ""
+	}
+
+	log.Printf("Рецепт нарушает ограничения питания: %s", strings.Join(problems, "; "))
+	return fmt.Sprintf(locales.Get().RestrictionsMenu.Violation, strings.Join(problems, "; "))
+}
+
+// ingredientsSection вырезает из рецепта блок ингредиентов: от заголовка «Ингредиенты»
+// до пошагового рецепта. Если заголовка нет, возвращает пустую строку.
+func ingredientsSection(recipe string) string {
+	var section []string
+	inside := false
+	for _, line := range strings.Split(recipe, "\n") {
+		lower := strings.ToLower(line)
+		switch {
+		case strings.Contains(lower, "ингредиенты"):
+			inside = true
+		case strings.Contains(lower, "пошаговый рецепт") || strings.Contains(lower, "приготовление"):
+			if inside {
+				return strings.Join(section, "\n")
+			}
+		case inside:
+			section = append(section, line)
+		}
+	}
+	return strings.Join(section, "\n")
+}
+
+// uniqueStrings убирает повторы, сохраняя порядок
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var result []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,265 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Ограничения Telegram: около 30 сообщений в секунду всего и 1 в секунду в один чат
+const (
+	globalSendInterval = time.Second / 30
+	globalSendBurst    = 30
+	chatSendInterval   = time.Second
+	chatSendBurst      = 3
+
+	maxSendAttempts  = 4
+	transientBackoff = time.Second
+	chatThrottleTTL  = time.Minute
+)
+
+// errBotBlocked — пользователь заблокировал бота или удалил аккаунт, повторять бессмысленно
+var errBotBlocked = errors.New("бот недоступен для пользователя")
+
+// throttle — ограничитель по алгоритму GCRA: interval между запросами и допустимый всплеск burst
+type throttle struct {
+	interval time.Duration
+	burst    int
+	tat      time.Time // theoretical arrival time следующего запроса
+}
+
+// reserve резервирует слот и возвращает, сколько нужно подождать до отправки
+func (t *throttle) reserve(now time.Time) time.Duration {
+	tolerance := time.Duration(t.burst-1) * t.interval
+
+	tat := t.tat
+	if tat.Before(now) {
+		tat = now
+	}
+
+	wait := tat.Add(-tolerance).Sub(now)
+	if wait < 0 {
+		wait = 0
+	}
+
+	t.tat = tat.Add(t.interval)
+	return wait
+}
+
+// sender оборачивает Telegram API: ограничивает частоту отправки
+// и повторяет запросы при flood control (429) и временных ошибках (5xx)
+type sender struct {
+	api *tgbotapi.BotAPI
+
+	mu        sync.Mutex
+	global    *throttle
+	chats     map[int64]*throttle
+	lastSweep time.Time
+}
+
+func newSender(api *tgbotapi.BotAPI) *sender {
+	return &sender{
+		api:    api,
+		global: &throttle{interval: globalSendInterval, burst: globalSendBurst},
+		chats:  make(map[int64]*throttle),
+	}
+}
+
+// send отправляет сообщение и возвращает результат как tgbotapi.Message
+func (s *sender) send(ctx context.Context, chatID int64, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	resp, err := s.request(ctx, chatID, c)
+	if err != nil {
+		return tgbotapi.Message{}, err
+	}
+
+	var message tgbotapi.Message
+	if err := json.Unmarshal(resp.Result, &message); err != nil {
+		return tgbotapi.Message{}, fmt.Errorf("неверный ответ Telegram: %w", err)
+	}
+
+	return message, nil
+}
+
+// request выполняет запрос с ожиданием лимитов и повторами.
+// chatID = 0 означает запрос, не привязанный к чату (например, ответ на callback).
+func (s *sender) request(ctx context.Context, chatID int64, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	for attempt := 1; ; attempt++ {
+		if err := sleepCtx(ctx, s.reserve(chatID)); err != nil {
+			return nil, err
+		}
+
+		resp, err := s.api.Request(c)
+		if err == nil {
+			return resp, nil
+		}
+
+		if isBlockedError(err) {
+			return nil, fmt.Errorf("%w: %v", errBotBlocked, err)
+		}
+
+		wait, retry := retryDelay(err, c)
+		if !retry || attempt >= maxSendAttempts {
+			return nil, err
+		}
+
+		log.Printf("Telegram API: попытка %d не удалась (%v), повтор через %s", attempt, err, wait)
+		// Ожидание чата учтёт следующий reserve; без чата ждём здесь
+		if chatID != 0 {
+			s.postpone(chatID, wait)
+		} else if err := sleepCtx(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// reserve резервирует глобальный слот и слот чата, возвращая суммарное ожидание
+func (s *sender) reserve(chatID int64) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	wait := s.global.reserve(now)
+
+	if chatID != 0 {
+		s.sweep(now)
+		t, ok := s.chats[chatID]
+		if !ok {
+			t = &throttle{interval: chatSendInterval, burst: chatSendBurst}
+			s.chats[chatID] = t
+		}
+		if chatWait := t.reserve(now.Add(wait)); chatWait > 0 {
+			wait += chatWait
+		}
+	}
+
+	return wait
+}
+
+// postpone сдвигает очередь чата так, чтобы следующий запрос в него ушёл не раньше чем через d
+// (после ответа retry_after от Telegram или временной ошибки)
+func (s *sender) postpone(chatID int64, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.chats[chatID]
+	if !ok {
+		t = &throttle{interval: chatSendInterval, burst: chatSendBurst}
+		s.chats[chatID] = t
+	}
+	// reserve пропускает запрос на tolerance раньше tat, поэтому сдвигаем с запасом
+	tolerance := time.Duration(t.burst-1) * t.interval
+	if until := time.Now().Add(d + tolerance); t.tat.Before(until) {
+		t.tat = until
+	}
+}
+
+// sweep удаляет ограничители давно неактивных чатов, чтобы карта не росла бесконечно
+func (s *sender) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < chatThrottleTTL {
+		return
+	}
+	s.lastSweep = now
+
+	for chatID, t := range s.chats {
+		if now.Sub(t.tat) > chatThrottleTTL {
+			delete(s.chats, chatID)
+		}
+	}
+}
+
+// retryDelay определяет, стоит ли повторять запрос c и через сколько
+func retryDelay(err error, c tgbotapi.Chattable) (time.Duration, bool) {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		// Без ответа Telegram неизвестно, выполнен ли запрос: таймаут чтения бывает
+		// и после того, как сообщение уже отправлено. Повторяем, только если соединение
+		// не установилось или повтор ничего не испортит.
+		if notSent(err) || idempotent(c) {
+			return transientBackoff, true
+		}
+		return 0, false
+	}
+
+	switch {
+	case apiErr.Code == http.StatusTooManyRequests:
+		if apiErr.RetryAfter > 0 {
+			return time.Duration(apiErr.RetryAfter) * time.Second, true
+		}
+		return transientBackoff, true
+	case apiErr.Code >= http.StatusInternalServerError:
+		return transientBackoff, true
+	default:
+		return 0, false
+	}
+}
+
+// notSent — ошибка соединения до отправки запроса: DNS или установка TCP/TLS
+func notSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// idempotent — запрос, повтор которого не создаёт дубликатов: правка и удаление
+// сообщения, ответ на callback. Отправка сообщений и файлов к ним не относится.
+func idempotent(c tgbotapi.Chattable) bool {
+	switch c.(type) {
+	case tgbotapi.EditMessageTextConfig, tgbotapi.EditMessageReplyMarkupConfig,
+		tgbotapi.DeleteMessageConfig, tgbotapi.CallbackConfig:
+		return true
+	}
+	return false
+}
+
+// blockedDescriptions — ответы 400, после которых писать в чат тоже бессмысленно
+var blockedDescriptions = []string{"chat not found", "user is deactivated"}
+
+// isBlockedError — бот заблокирован, удалён из чата, аккаунт удалён или чата не существует:
+// 403 от Telegram или 400 с одним из blockedDescriptions
+func isBlockedError(err error) bool {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.Code {
+	case http.StatusForbidden:
+		return true
+	case http.StatusBadRequest:
+		message := strings.ToLower(apiErr.Message)
+		for _, d := range blockedDescriptions {
+			if strings.Contains(message, d) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sleepCtx ждёт d или отмены контекста
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,200 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pinghoyk/neurobot/internal/servings"
+	"github.com/pinghoyk/neurobot/pkg/locales"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// scaleFactors — готовые множители порций на кнопках
+var scaleFactors = []float64{0.5, 2, 3, 4}
+
+// scaleCurrent — callback уже выбранной кнопки: повторное нажатие ничего не меняет
+const scaleCurrent = "scale:current"
+
+// formatFactor — множитель для callback-данных: «0.5», «2»
+func formatFactor(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// scaleKeyboard — выбор множителя и мер; текущий выбор отмечен галочкой
+func scaleKeyboard(id, suffix string, factor float64, units servings.Units) tgbotapi.InlineKeyboardMarkup {
+	l := locales.Get()
+
+	button := func(label string, selected bool, f float64, u servings.Units) tgbotapi.InlineKeyboardButton {
+		if selected {
+			return tgbotapi.NewInlineKeyboardButtonData("✅ "+label, scaleCurrent)
+		}
+		return tgbotapi.NewInlineKeyboardButtonData(label, "scale:"+id+":"+formatFactor(f)+":"+string(u)+suffix)
+	}
+
+	var factors []tgbotapi.InlineKeyboardButton
+	for _, f := range scaleFactors {
+		label := "×" + formatFactor(f)
+		if f == 0.5 {
+			label = "×½"
+		}
+		factors = append(factors, button(label, f == factor, f, units))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		factors,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.Servings.Buttons.Custom, "scale:"+id+":custom:"+string(units)+suffix),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			button(l.Servings.Buttons.Original, units == servings.UnitsOriginal, factor, servings.UnitsOriginal),
+			button(l.Servings.Buttons.Metric, units == servings.UnitsMetric, factor, servings.UnitsMetric),
+			button(l.Servings.Buttons.Kitchen, units == servings.UnitsKitchen, factor, servings.UnitsKitchen),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.Servings.Buttons.Back, "recipe:open:"+id+suffix),
+		),
+	)
+}
+
+// scaledText пересчитывает рецепт и добавляет заголовок с множителем и мерами
+func scaledText(recipe *models.Recipe, factor float64, units servings.Units) string {
+	if factor == 1 && units == servings.UnitsOriginal {
+		return recipe.Text
+	}
+
+	l := locales.Get()
+	unitsName := l.Servings.Units.Original
+	switch units {
+	case servings.UnitsMetric:
+		unitsName = l.Servings.Units.Metric
+	case servings.UnitsKitchen:
+		unitsName = l.Servings.Units.Kitchen
+	}
+
+	header := fmt.Sprintf(l.Servings.Header, strings.ReplaceAll(formatFactor(factor), ".", ","), unitsName)
+	return header + "\n\n" + servings.Scale(recipe.Text, factor, units)
+}
+
+// handleScaleCallback обрабатывает пересчёт порций: <id>:<множитель|custom>:<меры>[:<список>]
+func (b *Bot) handleScaleCallback(chatID, userID int64, msgID int, data string) {
+	if data == "current" {
+		return
+	}
+
+	parts := strings.Split(data, ":")
+	if len(parts) < 3 {
+		log.Printf("Некорректный callback пересчёта порций: %q", data)
+		return
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		log.Printf("Некорректный ID рецепта: %q", data)
+		return
+	}
+	units, ok := servings.ParseUnits(parts[2])
+	if !ok {
+		log.Printf("Некорректные меры: %q", data)
+		return
+	}
+	var list *recipeList
+	if len(parts) > 3 {
+		parsed, ok := parseRecipeList(parts[3])
+		if !ok {
+			log.Printf("Некорректный список рецептов: %q", data)
+			return
+		}
+		list = &parsed
+	}
+
+	recipe, err := b.db.GetRecipe(userID, id)
+	if err != nil {
+		log.Printf("Ошибка получения рецепта: %v", err)
+		return
+	}
+	if recipe == nil {
+		b.sendText(chatID, locales.Get().Recipes.NotFound)
+		return
+	}
+
+	if parts[1] == "custom" {
+		b.askScaleFactor(chatID, userID, msgID, recipe, units, list, "")
+		return
+	}
+
+	factor, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || factor < servings.MinFactor || factor > servings.MaxFactor {
+		log.Printf("Некорректный множитель порций: %q", data)
+		return
+	}
+	b.showScaledRecipe(chatID, userID, msgID, recipe, factor, units, list)
+}
+
+// showScaledRecipe показывает рецепт, пересчитанный в factor раз
+func (b *Bot) showScaledRecipe(chatID, userID int64, msgID int, recipe *models.Recipe, factor float64, units servings.Units, list *recipeList) {
+	keyboard := scaleKeyboard(strconv.FormatInt(recipe.ID, 10), listSuffix(list), factor, units)
+	b.sendOrEditMessage(chatID, userID, msgID, scaledText(recipe, factor, units), keyboard, models.StateRecipe)
+}
+
+// askScaleFactor просит ввести свой множитель. Рецепт и выбранные меры
+// запоминаются в состоянии до ответа пользователя.
+func (b *Bot) askScaleFactor(chatID, userID int64, msgID int, recipe *models.Recipe, units servings.Units, list *recipeList, notice string) {
+	l := locales.Get()
+
+	id, suffix := strconv.FormatInt(recipe.ID, 10), listSuffix(list)
+	text := l.Servings.CustomText
+	if notice != "" {
+		text = notice + "\n\n" + text
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(l.Servings.Buttons.Cancel, "scale:"+id+":1:"+string(units)+suffix),
+	))
+	b.sendOrEditMessage(chatID, userID, msgID, text+"\n\n"+recipe.Text, keyboard, models.StateScaleFactor)
+
+	state, err := b.db.GetUserState(userID)
+	if err != nil {
+		log.Printf("Ошибка получения состояния: %v", err)
+		return
+	}
+	state.InputData = id + ":" + string(units) + suffix
+	if err := b.db.SaveUserState(state); err != nil {
+		log.Printf("Ошибка сохранения состояния: %v", err)
+	}
+}
+
+// handleScaleFactorInput пересчитывает рецепт на введённый множитель
+func (b *Bot) handleScaleFactorInput(chatID, userID int64, text string, state *models.UserState) {
+	parts := strings.Split(state.InputData, ":")
+	id, _ := strconv.ParseInt(parts[0], 10, 64)
+	units := servings.UnitsOriginal
+	if len(parts) > 1 {
+		if u, ok := servings.ParseUnits(parts[1]); ok {
+			units = u
+		}
+	}
+	var list *recipeList
+	if len(parts) > 2 {
+		if parsed, ok := parseRecipeList(parts[2]); ok {
+			list = &parsed
+		}
+	}
+
+	recipe, err := b.db.GetRecipe(userID, id)
+	if err != nil {
+		log.Printf("Ошибка получения рецепта: %v", err)
+		return
+	}
+	if recipe == nil {
+		b.sendText(chatID, locales.Get().Recipes.NotFound)
+		return
+	}
+
+	factor, ok := servings.ParseFactor(text)
+	if !ok {
+		b.askScaleFactor(chatID, userID, state.LastMessageID, recipe, units, list, locales.Get().Servings.Invalid)
+		return
+	}
+	b.showScaledRecipe(chatID, userID, state.LastMessageID, recipe, factor, units, list)
+}
@@ -0,0 +1,226 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pinghoyk/neurobot/internal/servings"
+	"github.com/pinghoyk/neurobot/pkg/locales"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// maxShoppingButtons — сколько продуктов можно отмечать кнопками: у Telegram лимит
+// на число кнопок в сообщении, остальные продукты видны только в тексте
+const maxShoppingButtons = 80
+
+// showShopping — список покупок для команды /shopping
+func (b *Bot) showShopping(chatID, userID int64, editMsgID int) {
+	b.showShoppingList(chatID, userID, editMsgID, "")
+}
+
+// showShoppingList отображает список покупок по отделам магазина с переключателями «куплено».
+// notice выводится над списком (например, сколько продуктов добавлено).
+func (b *Bot) showShoppingList(chatID, userID int64, editMsgID int, notice string) {
+	l := locales.Get()
+
+	items, err := b.db.ListShoppingItems(userID)
+	if err != nil {
+		log.Printf("Ошибка получения списка покупок: %v", err)
+		return
+	}
+	sortShoppingItems(items)
+
+	text := l.Shopping.Title + "\n\n" + l.Shopping.Empty
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if len(items) > 0 {
+		var sb strings.Builder
+		sb.WriteString(l.Shopping.Title)
+		section := ""
+		var row []tgbotapi.InlineKeyboardButton
+		for i, item := range items {
+			if item.Section != section {
+				section = item.Section
+				sb.WriteString("\n\n*" + models.StoreSectionName(section) + "*")
+			}
+
+			mark, action := "▫️", "on"
+			if item.Checked {
+				mark, action = "✅", "off"
+			}
+			sb.WriteString("\n" + mark + " " + formatShoppingItem(&item))
+
+			if i < maxShoppingButtons {
+				row = append(row, tgbotapi.NewInlineKeyboardButtonData(mark+" "+item.Name,
+					fmt.Sprintf("shopping:%s:%d", action, item.ID)))
+				if len(row) == 2 {
+					rows = append(rows, row)
+					row = nil
+				}
+			}
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.Shopping.Buttons.ClearChecked, "shopping:clear:checked"),
+			tgbotapi.NewInlineKeyboardButtonData(l.Shopping.Buttons.ClearAll, "shopping:clear"),
+		))
+		text = sb.String()
+	}
+	if notice != "" {
+		text = notice + "\n\n" + text
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(l.Shopping.Buttons.BackToMain, "menu:main"),
+	))
+
+	b.sendOrEditMessage(chatID, userID, editMsgID, text, tgbotapi.NewInlineKeyboardMarkup(rows...), models.StateShopping)
+}
+
+// handleShoppingCallback обрабатывает кнопки списка: on|off:<id>, clear, clear:checked, clear:all
+func (b *Bot) handleShoppingCallback(chatID, userID int64, msgID int, data string) {
+	l := locales.Get()
+
+	action, arg, _ := strings.Cut(data, ":")
+	switch action {
+	case "on", "off":
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			log.Printf("Некорректный ID продукта: %q", data)
+			return
+		}
+		if _, err := b.db.SetShoppingItemChecked(userID, id, action == "on"); err != nil {
+			log.Printf("Ошибка отметки продукта: %v", err)
+		}
+	case "clear":
+		if arg == "" {
+			keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(l.Shopping.Buttons.Yes, "shopping:clear:all"),
+				tgbotapi.NewInlineKeyboardButtonData(l.Shopping.Buttons.No, "shopping:show"),
+			))
+			b.sendOrEditMessage(chatID, userID, msgID, l.Shopping.ClearConfirm, keyboard, models.StateShopping)
+			return
+		}
+		if err := b.db.ClearShoppingList(userID, arg == "checked"); err != nil {
+			log.Printf("Ошибка очистки списка покупок: %v", err)
+		}
+	case "show":
+	default:
+		log.Printf("Некорректный callback списка покупок: %q", data)
+		return
+	}
+
+	b.showShoppingList(chatID, userID, msgID, "")
+}
+
+// addRecipeToShopping добавляет ингредиенты рецепта в список покупок и показывает
+// список отдельным сообщением, чтобы рецепт остался на экране
+func (b *Bot) addRecipeToShopping(chatID, userID int64, id int64) {
+	l := locales.Get()
+
+	recipe, err := b.db.GetRecipe(userID, id)
+	if err != nil {
+		log.Printf("Ошибка получения рецепта: %v", err)
+		return
+	}
+	if recipe == nil {
+		b.sendText(chatID, l.Recipes.NotFound)
+		return
+	}
+
+	now := time.Now()
+	var items []models.ShoppingItem
+	for _, ing := range servings.ParseIngredients(recipe.Text) {
+		items = append(items, models.ShoppingItem{
+			Name:      capitalize(ing.Name),
+			Amount:    ing.Amount,
+			Unit:      ing.Unit,
+			Section:   models.StoreSectionFor(ing.Name),
+			CreatedAt: now,
+		})
+	}
+	if len(items) == 0 {
+		b.showShoppingList(chatID, userID, 0, l.Shopping.NothingAdded)
+		return
+	}
+
+	if err := b.db.AddShoppingItems(userID, items); err != nil {
+		log.Printf("Ошибка добавления в список покупок: %v", err)
+		return
+	}
+	b.showShoppingList(chatID, userID, 0, fmt.Sprintf(l.Shopping.Added, len(items)))
+}
+
+// sortShoppingItems упорядочивает продукты по отделам в порядке обхода магазина
+func sortShoppingItems(items []models.ShoppingItem) {
+	order := make(map[string]int, len(models.StoreSections))
+	for i, s := range models.StoreSections {
+		order[s.Code] = i
+	}
+	for i := range items {
+		if items[i].Section == "" {
+			items[i].Section = models.SectionOther
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		oi, ok := order[items[i].Section]
+		if !ok {
+			oi = len(order)
+		}
+		oj, ok := order[items[j].Section]
+		if !ok {
+			oj = len(order)
+		}
+		return oi < oj
+	})
+}
+
+// formatShoppingItem — «Рис — 350 г», «Мука — 1,2 кг», «Соль»
+func formatShoppingItem(item *models.ShoppingItem) string {
+	if quantity := formatQuantity(item.Amount, item.Unit); quantity != "" {
+		return item.Name + " — " + quantity
+	}
+	return item.Name
+}
+
+// formatQuantity — количество с округлением вверх: «350 г», «1,2 кг», «2,5 шт»; пусто, если не указано
+func formatQuantity(amount float64, unit string) string {
+	if amount <= 0 {
+		return ""
+	}
+
+	switch {
+	case unit == servings.UnitGrams && amount >= 1000:
+		amount, unit = math.Ceil(amount/100)/10, "кг"
+	case unit == servings.UnitMl && amount >= 1000:
+		amount, unit = math.Ceil(amount/100)/10, "л"
+	case unit == servings.UnitGrams || unit == servings.UnitMl:
+		amount = math.Ceil(amount)
+	default:
+		// Штуки и прочее докупаем с запасом до половинки
+		amount = math.Ceil(amount*2) / 2
+	}
+
+	text := formatNumber(amount)
+	if unit != "" {
+		text += " " + unit
+	}
+	return text
+}
+
+// capitalize делает первую букву заглавной
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
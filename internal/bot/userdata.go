@@ -0,0 +1,76 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pinghoyk/neurobot/pkg/locales"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// cmdMyData — /mydata: отправляет JSON-файл со всеми данными пользователя
+func (b *Bot) cmdMyData(msg *tgbotapi.Message, _ *models.UserState) {
+	l := locales.Get()
+	userID := msg.From.ID
+
+	data, err := b.db.ExportUserData(userID)
+	if err != nil {
+		log.Printf("Ошибка выгрузки данных пользователя: %v", err)
+		b.sendText(msg.Chat.ID, l.MyData.Error)
+		return
+	}
+
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		log.Printf("Ошибка сериализации данных пользователя: %v", err)
+		b.sendText(msg.Chat.ID, l.MyData.Error)
+		return
+	}
+
+	doc := tgbotapi.NewDocument(msg.Chat.ID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("neurobot-data-%d.json", userID),
+		Bytes: content,
+	})
+	doc.Caption = l.MyData.Caption
+
+	if _, err := b.send(msg.Chat.ID, doc); err != nil {
+		log.Printf("Ошибка отправки выгрузки: %v", err)
+		return
+	}
+	log.Printf("Пользователь %d выгрузил свои данные", userID)
+}
+
+// showDeleteConfirm показывает подтверждение удаления всех данных
+func (b *Bot) showDeleteConfirm(chatID, userID int64, editMsgID int) {
+	l := locales.Get()
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.DeleteConfirm.Buttons.Yes, "deleteme:yes"),
+			tgbotapi.NewInlineKeyboardButtonData(l.DeleteConfirm.Buttons.No, "deleteme:no"),
+		),
+	)
+
+	b.sendOrEditMessage(chatID, userID, editMsgID, l.DeleteConfirm.Text, keyboard, models.StateDeleteConfirm)
+}
+
+// deleteUserData безвозвратно удаляет все данные пользователя.
+// Состояние после удаления не сохраняется, иначе в базе снова появилась бы запись.
+func (b *Bot) deleteUserData(chatID, userID int64, editMsgID int) {
+	l := locales.Get()
+
+	if err := b.db.DeleteUserData(userID, time.Now()); err != nil {
+		log.Printf("Ошибка удаления данных пользователя: %v", err)
+		b.sendText(chatID, l.DeleteSuccess.Error)
+		return
+	}
+	log.Printf("Аудит: данные пользователя %d удалены по его запросу", userID)
+
+	edit := tgbotapi.NewEditMessageText(chatID, editMsgID, l.DeleteSuccess.Text)
+	edit.ParseMode = "Markdown"
+	if _, err := b.send(chatID, edit); err != nil {
+		log.Printf("Не удалось отредактировать сообщение: %v", err)
+	}
+}
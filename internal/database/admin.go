@@ -0,0 +1,73 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// BanUser блокирует пользователя по решению администратора
+func (db *DB) BanUser(userID, adminID int64, reason string) error {
+	_, err := db.exec(`
+		INSERT INTO users (user_id, is_banned, banned_by, ban_reason, banned_at)
+		VALUES (?, TRUE, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			is_banned = TRUE,
+			banned_by = excluded.banned_by,
+			ban_reason = excluded.ban_reason,
+			banned_at = excluded.banned_at
+	`, userID, adminID, reason, time.Now().UnixMilli())
+
+	return err
+}
+
+// UnbanUser снимает блокировку
+func (db *DB) UnbanUser(userID int64) error {
+	_, err := db.exec(`
+		UPDATE users SET is_banned = FALSE, banned_by = 0, ban_reason = '', banned_at = 0
+		WHERE user_id = ?
+	`, userID)
+	return err
+}
+
+// IsBanned проверяет, заблокирован ли пользователь администратором
+func (db *DB) IsBanned(userID int64) (bool, error) {
+	var banned bool
+	err := db.queryRow(`SELECT is_banned FROM users WHERE user_id = ?`, userID).Scan(&banned)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return banned, err
+}
+
+// GetStats собирает сводку для администратора за сутки, начинающиеся в dayStart
+func (db *DB) GetStats(dayStart time.Time) (*models.Stats, error) {
+	stats := &models.Stats{}
+
+	err := db.queryRow(`
+		SELECT
+			COUNT(CASE WHEN first_seen > 0 THEN 1 END),
+			COUNT(CASE WHEN is_banned THEN 1 END),
+			COUNT(CASE WHEN is_blocked THEN 1 END)
+		FROM users
+	`).Scan(&stats.TotalUsers, &stats.BannedUsers, &stats.InactiveUsers)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.queryRow(`
+		SELECT
+			COUNT(DISTINCT user_id),
+			COUNT(CASE WHEN status = ? THEN 1 END),
+			COUNT(CASE WHEN status = ? THEN 1 END)
+		FROM generations
+		WHERE created_at >= ?
+	`, models.GenerationOK, models.GenerationError, dayStart.UnixMilli()).
+		Scan(&stats.ActiveToday, &stats.GenerationsToday, &stats.ErrorsToday)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
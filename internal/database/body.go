@@ -0,0 +1,44 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// SaveBodyProfile сохраняет параметры тела пользователя (в том числе неполные)
+func (db *DB) SaveBodyProfile(profile *models.BodyProfile) error {
+	_, err := db.exec(`
+		INSERT INTO body_profiles (user_id, sex, age, height_cm, weight_kg, activity, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			sex = excluded.sex,
+			age = excluded.age,
+			height_cm = excluded.height_cm,
+			weight_kg = excluded.weight_kg,
+			activity = excluded.activity,
+			updated_at = excluded.updated_at
+	`, profile.UserID, profile.Sex, profile.Age, profile.HeightCm, profile.WeightKg, profile.Activity, time.Now().UnixMilli())
+	return err
+}
+
+// GetBodyProfile возвращает параметры тела пользователя (nil, если не заполнялись)
+func (db *DB) GetBodyProfile(userID int64) (*models.BodyProfile, error) {
+	profile := &models.BodyProfile{UserID: userID}
+	var updatedAt int64
+
+	err := db.queryRow(`
+		SELECT sex, age, height_cm, weight_kg, activity, updated_at
+		FROM body_profiles WHERE user_id = ?
+	`, userID).Scan(&profile.Sex, &profile.Age, &profile.HeightCm, &profile.WeightKg, &profile.Activity, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	profile.UpdatedAt = time.UnixMilli(updatedAt)
+	return profile, nil
+}
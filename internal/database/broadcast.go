@@ -0,0 +1,160 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+const broadcastColumns = `id, admin_id, admin_chat_id, text, buttons, segment, status,
+	last_user_id, delivered, failed, blocked, created_at, finished_at`
+
+// CreateBroadcast ставит рассылку в очередь и возвращает её ID
+func (db *DB) CreateBroadcast(b *models.Broadcast) (int64, error) {
+	buttons, err := json.Marshal(b.Buttons)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = db.queryRow(`
+		INSERT INTO broadcasts (admin_id, admin_chat_id, text, buttons, segment, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, b.AdminID, b.AdminChatID, b.Text, string(buttons), b.Segment, models.BroadcastPending,
+		b.CreatedAt.UnixMilli()).Scan(&id)
+
+	return id, err
+}
+
+// GetBroadcast возвращает рассылку по ID (nil — не найдена)
+func (db *DB) GetBroadcast(id int64) (*models.Broadcast, error) {
+	b, err := scanBroadcast(db.queryRow(`SELECT `+broadcastColumns+` FROM broadcasts WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return b, err
+}
+
+// ClaimBroadcast берёт в работу самую старую незавершённую рассылку, аренда которой истекла,
+// и продлевает аренду до now+lease. Возвращает nil, если брать нечего.
+// Аренда не даёт двум репликам слать одну рассылку, а после падения
+// рассылку подхватит следующий вызов.
+func (db *DB) ClaimBroadcast(now time.Time, lease time.Duration) (*models.Broadcast, error) {
+	b, err := scanBroadcast(db.queryRow(`
+		UPDATE broadcasts SET status = ?, locked_until = ?
+		WHERE id = (
+			SELECT id FROM broadcasts
+			WHERE status IN (?, ?) AND locked_until < ?
+			ORDER BY id LIMIT 1
+		) AND locked_until < ?
+		RETURNING `+broadcastColumns,
+		models.BroadcastRunning, now.Add(lease).UnixMilli(),
+		models.BroadcastPending, models.BroadcastRunning, now.UnixMilli(), now.UnixMilli()))
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return b, err
+}
+
+// SaveBroadcastProgress сохраняет счётчики и последнего получателя, продлевая аренду.
+// Возвращает false, если рассылка больше не выполняется (например, отменена).
+func (db *DB) SaveBroadcastProgress(b *models.Broadcast, lockedUntil time.Time) (bool, error) {
+	res, err := db.exec(`
+		UPDATE broadcasts
+		SET last_user_id = ?, delivered = ?, failed = ?, blocked = ?, locked_until = ?
+		WHERE id = ? AND status = ?
+	`, b.LastUserID, b.Delivered, b.Failed, b.Blocked, lockedUntil.UnixMilli(), b.ID, models.BroadcastRunning)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// FinishBroadcast отмечает рассылку завершённой
+func (db *DB) FinishBroadcast(id int64, now time.Time) error {
+	_, err := db.exec(`
+		UPDATE broadcasts SET status = ?, finished_at = ?, locked_until = 0
+		WHERE id = ? AND status = ?
+	`, models.BroadcastDone, now.UnixMilli(), id, models.BroadcastRunning)
+	return err
+}
+
+// CancelBroadcast отменяет ещё не завершённую рассылку
+func (db *DB) CancelBroadcast(id int64, now time.Time) (bool, error) {
+	res, err := db.exec(`
+		UPDATE broadcasts SET status = ?, finished_at = ?
+		WHERE id = ? AND status IN (?, ?)
+	`, models.BroadcastCancelled, now.UnixMilli(), id, models.BroadcastPending, models.BroadcastRunning)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListBroadcastRecipients возвращает до limit получателей с ID больше afterUserID
+// по возрастанию ID. Заблокировавшие бота и забаненные пропускаются.
+func (db *DB) ListBroadcastRecipients(filter models.RecipientFilter, afterUserID int64, limit int) ([]int64, error) {
+	var activeSince int64
+	if !filter.ActiveSince.IsZero() {
+		activeSince = filter.ActiveSince.UnixMilli()
+	}
+
+	rows, err := db.query(`
+		SELECT u.user_id FROM users u
+		LEFT JOIN user_preferences p ON p.user_id = u.user_id
+		WHERE u.user_id > ?
+		  AND u.first_seen > 0
+		  AND NOT u.is_blocked
+		  AND NOT u.is_banned
+		  AND (? = '' OR p.dietary_type = ?)
+		  AND (? = 0 OR EXISTS (
+			SELECT 1 FROM generations g WHERE g.user_id = u.user_id AND g.created_at >= ?
+		  ))
+		ORDER BY u.user_id
+		LIMIT ?
+	`, afterUserID, filter.DietaryType, filter.DietaryType, activeSince, activeSince, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func scanBroadcast(row *sql.Row) (*models.Broadcast, error) {
+	var b models.Broadcast
+	var buttons string
+	var createdAt, finishedAt int64
+
+	err := row.Scan(&b.ID, &b.AdminID, &b.AdminChatID, &b.Text, &buttons, &b.Segment, &b.Status,
+		&b.LastUserID, &b.Delivered, &b.Failed, &b.Blocked, &createdAt, &finishedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(buttons), &b.Buttons); err != nil {
+		return nil, err
+	}
+	b.CreatedAt = time.UnixMilli(createdAt)
+	if finishedAt > 0 {
+		b.FinishedAt = time.UnixMilli(finishedAt)
+	}
+
+	return &b, nil
+}
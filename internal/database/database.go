@@ -2,40 +2,120 @@ package database
 
 import (
 	"database/sql"
-	_ "embed"
 	"fmt"
+	"strconv"
+	"strings"
 
+	"github.com/pinghoyk/neurobot/internal/fieldcrypt"
+
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-//go:embed schema.sql
-var schemaSQL string
+// dialect — диалект SQL. Запросы пишутся с плейсхолдерами "?",
+// для PostgreSQL они переписываются в "$1, $2, ...".
+type dialect int
+
+const (
+	dialectSQLite dialect = iota
+	dialectPostgres
+)
 
 type DB struct {
-	conn *sql.DB
+	conn       *sql.DB
+	dialect    dialect
+	migrations []Migration
+	keys       *fieldcrypt.Keyring // nil — шифрование полей выключено
 }
 
+// New открывает базу SQLite и применяет все недостающие миграции
 func New(dbPath string) (*DB, error) {
-	conn, err := sql.Open("sqlite3", dbPath)
+	db, err := Open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return db.migrated()
+}
+
+// NewPostgres подключается к PostgreSQL по DSN и применяет все недостающие миграции
+func NewPostgres(dsn string) (*DB, error) {
+	db, err := OpenPostgres(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return db.migrated()
+}
+
+// Open открывает базу SQLite без применения миграций (для служебных команд)
+func Open(dbPath string) (*DB, error) {
+	return open("sqlite3", dbPath, dialectSQLite, "migrations/sqlite")
+}
+
+// OpenPostgres подключается к PostgreSQL без применения миграций (для служебных команд)
+func OpenPostgres(dsn string) (*DB, error) {
+	return open("postgres", dsn, dialectPostgres, "migrations/postgres")
+}
+
+func open(driver, dsn string, d dialect, migrationsDir string) (*DB, error) {
+	migrations, err := loadMigrations(migrationsFS, migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := sql.Open(driver, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("не удалось открыть базу данных: %w", err)
 	}
 
-	db := &DB{conn: conn}
-	if err := db.applySchema(); err != nil {
+	if err := conn.Ping(); err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("не удалось применить схему: %w", err)
+		return nil, fmt.Errorf("база данных недоступна: %w", err)
 	}
 
-	return db, nil
+	return &DB{conn: conn, dialect: d, migrations: migrations}, nil
 }
 
-// applySchema читает и выполняет schema.sql
-func (db *DB) applySchema() error {
-	_, err := db.conn.Exec(schemaSQL)
-	return err
+// migrated применяет миграции и возвращает готовую к работе базу
+func (db *DB) migrated() (*DB, error) {
+	if err := db.MigrateUp(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("не удалось применить миграции: %w", err)
+	}
+	return db, nil
 }
 
 func (db *DB) Close() error {
 	return db.conn.Close()
 }
+
+// rebind переписывает плейсхолдеры "?" под диалект базы
+func (db *DB) rebind(query string) string {
+	if db.dialect != dialectPostgres {
+		return query
+	}
+
+	var sb strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			sb.WriteByte('$')
+			sb.WriteString(strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func (db *DB) exec(query string, args ...any) (sql.Result, error) {
+	return db.conn.Exec(db.rebind(query), args...)
+}
+
+func (db *DB) query(query string, args ...any) (*sql.Rows, error) {
+	return db.conn.Query(db.rebind(query), args...)
+}
+
+func (db *DB) queryRow(query string, args ...any) *sql.Row {
+	return db.conn.QueryRow(db.rebind(query), args...)
+}
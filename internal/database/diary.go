@@ -0,0 +1,56 @@
+package database
+
+import (
+	"time"
+
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// AddDiaryEntry записывает съеденную порцию в дневник и возвращает ID записи
+func (db *DB) AddDiaryEntry(e *models.DiaryEntry) (int64, error) {
+	var id int64
+	err := db.queryRow(`
+		INSERT INTO diary_entries (user_id, day, recipe_id, title, calories, protein, fat, carbs, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, e.UserID, e.Day, e.RecipeID, e.Title, e.Nutrition.Calories, e.Nutrition.Protein,
+		e.Nutrition.Fat, e.Nutrition.Carbs, e.CreatedAt.UnixMilli()).Scan(&id)
+	return id, err
+}
+
+// ListDiaryEntries возвращает записи дневника с дня from по день to включительно (формат models.DayLayout)
+func (db *DB) ListDiaryEntries(userID int64, from, to string) ([]models.DiaryEntry, error) {
+	rows, err := db.query(`
+		SELECT id, user_id, day, recipe_id, title, calories, protein, fat, carbs, created_at
+		FROM diary_entries WHERE user_id = ? AND day >= ? AND day <= ? ORDER BY day, id
+	`, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.DiaryEntry
+	for rows.Next() {
+		var e models.DiaryEntry
+		var createdAt int64
+		err := rows.Scan(&e.ID, &e.UserID, &e.Day, &e.RecipeID, &e.Title, &e.Nutrition.Calories,
+			&e.Nutrition.Protein, &e.Nutrition.Fat, &e.Nutrition.Carbs, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+		e.CreatedAt = time.UnixMilli(createdAt)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// DeleteDiaryEntry удаляет запись дневника. Возвращает false, если запись не найдена.
+func (db *DB) DeleteDiaryEntry(userID, id int64) (bool, error) {
+	res, err := db.exec(`DELETE FROM diary_entries WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,252 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pinghoyk/neurobot/internal/fieldcrypt"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// encryptBatchSize — сколько строк перешифровывается за один проход EncryptPreferences
+const encryptBatchSize = 500
+
+// errNoKeyring — в базе есть зашифрованные значения, а ключи не настроены
+var errNoKeyring = errors.New("данные зашифрованы, но ключи шифрования не заданы")
+
+// UseKeyring включает шифрование чувствительных полей предпочтений.
+// Без ключей значения пишутся открытым текстом.
+func (db *DB) UseKeyring(keys *fieldcrypt.Keyring) {
+	db.keys = keys
+}
+
+type preferenceField struct {
+	column string
+	value  *string
+}
+
+// sensitiveFields возвращает поля предпочтений, которые хранятся зашифрованными.
+// Тип питания остаётся открытым: по нему выбираются получатели рассылок.
+func sensitiveFields(prefs *models.UserPreferences) []preferenceField {
+	return []preferenceField{
+		{"goal", &prefs.Goal},
+		{"allergies", &prefs.Allergies},
+		{"likes", &prefs.Likes},
+		{"dislikes", &prefs.Dislikes},
+	}
+}
+
+// preferenceAAD привязывает шифротекст к колонке и пользователю
+func preferenceAAD(column string, userID int64) string {
+	return fmt.Sprintf("user_preferences.%s:%d", column, userID)
+}
+
+// encryptPreferences возвращает копию предпочтений с зашифрованными полями.
+// Шифруется каждое непустое значение, даже похожее на шифротекст: его ввёл пользователь.
+// Без ключей значения пишутся открыто, а префикс "enc:" экранируется.
+func (db *DB) encryptPreferences(prefs *models.UserPreferences) (*models.UserPreferences, error) {
+	sealed := *prefs
+	for _, f := range sensitiveFields(&sealed) {
+		if *f.value == "" {
+			continue
+		}
+		if db.keys == nil {
+			*f.value = fieldcrypt.Escape(*f.value)
+			continue
+		}
+		value, err := db.keys.Encrypt(*f.value, preferenceAAD(f.column, sealed.UserID))
+		if err != nil {
+			return nil, err
+		}
+		*f.value = value
+	}
+	return &sealed, nil
+}
+
+// decryptPreferences расшифровывает поля на месте; с открытых значений снимается экранирование.
+// Если поле не удалось расшифровать, возвращается первая ошибка.
+func (db *DB) decryptPreferences(prefs *models.UserPreferences) error {
+	var firstErr error
+	for _, f := range sensitiveFields(prefs) {
+		if !fieldcrypt.IsEncrypted(*f.value) {
+			*f.value = fieldcrypt.Unescape(*f.value)
+			continue
+		}
+
+		err := errNoKeyring
+		if db.keys != nil {
+			var value string
+			if value, err = db.keys.Decrypt(*f.value, preferenceAAD(f.column, prefs.UserID)); err == nil {
+				*f.value = value
+				continue
+			}
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("user_preferences.%s пользователя %d: %w", f.column, prefs.UserID, err)
+		}
+	}
+	return firstErr
+}
+
+// EncryptPreferences шифрует текущим ключом предпочтения и снимки предпочтений в рецептах,
+// которые хранятся открыто или зашифрованы старым ключом. Возвращает число перезаписанных строк
+// и строк, которые не удалось расшифровать (например, ключ удалён из конфигурации) — они пропускаются.
+// Операция идемпотентна: её можно запускать при каждом старте и после добавления нового ключа.
+func (db *DB) EncryptPreferences() (rewritten, failed int, err error) {
+	if db.keys == nil {
+		return 0, 0, errors.New("ключи шифрования не заданы")
+	}
+
+	if rewritten, failed, err = db.encryptUserPreferences(); err != nil {
+		return rewritten, failed, err
+	}
+	snapshots, snapshotsFailed, err := db.encryptSnapshots()
+	return rewritten + snapshots, failed + snapshotsFailed, err
+}
+
+// encryptUserPreferences перешифровывает поля таблицы user_preferences
+func (db *DB) encryptUserPreferences() (rewritten, failed int, err error) {
+	var afterUserID int64
+	for {
+		batch, err := db.preferencesBatch(afterUserID)
+		if err != nil {
+			return rewritten, failed, err
+		}
+		if len(batch) == 0 {
+			return rewritten, failed, nil
+		}
+		afterUserID = batch[len(batch)-1].UserID
+
+		for i := range batch {
+			stored := batch[i]
+			if !db.needsRewrite(&stored) {
+				continue
+			}
+
+			plain := stored
+			if err := db.decryptPreferences(&plain); err != nil {
+				failed++
+				continue
+			}
+			sealed, err := db.encryptPreferences(&plain)
+			if err != nil {
+				return rewritten, failed, err
+			}
+
+			// Строку могли изменить параллельно: тогда её уже записали текущим ключом
+			res, err := db.exec(`
+				UPDATE user_preferences SET goal = ?, allergies = ?, likes = ?, dislikes = ?
+				WHERE user_id = ? AND goal = ? AND allergies = ? AND likes = ? AND dislikes = ?
+			`, sealed.Goal, sealed.Allergies, sealed.Likes, sealed.Dislikes,
+				stored.UserID, stored.Goal, stored.Allergies, stored.Likes, stored.Dislikes)
+			if err != nil {
+				return rewritten, failed, err
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				rewritten++
+			}
+		}
+	}
+}
+
+func (db *DB) needsRewrite(prefs *models.UserPreferences) bool {
+	for _, f := range sensitiveFields(prefs) {
+		if db.keys.NeedsRewrite(*f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// preferencesBatch читает сырые (незашифрованные и зашифрованные) строки предпочтений
+func (db *DB) preferencesBatch(afterUserID int64) ([]models.UserPreferences, error) {
+	rows, err := db.query(`
+		SELECT user_id, goal, allergies, likes, dislikes
+		FROM user_preferences WHERE user_id > ?
+		ORDER BY user_id LIMIT ?
+	`, afterUserID, encryptBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batch []models.UserPreferences
+	for rows.Next() {
+		var p models.UserPreferences
+		if err := rows.Scan(&p.UserID, &p.Goal, &p.Allergies, &p.Likes, &p.Dislikes); err != nil {
+			return nil, err
+		}
+		batch = append(batch, p)
+	}
+	return batch, rows.Err()
+}
+
+// encryptSnapshots перешифровывает recipes.prefs_snapshot: снимки, сохранённые
+// до включения шифрования, и снимки, зашифрованные старым ключом
+func (db *DB) encryptSnapshots() (rewritten, failed int, err error) {
+	var afterID int64
+	for {
+		batch, err := db.snapshotsBatch(afterID)
+		if err != nil {
+			return rewritten, failed, err
+		}
+		if len(batch) == 0 {
+			return rewritten, failed, nil
+		}
+		afterID = batch[len(batch)-1].id
+
+		for _, r := range batch {
+			if !db.keys.NeedsRewrite(r.snapshot) {
+				continue
+			}
+
+			plain, err := db.keys.Decrypt(r.snapshot, snapshotAAD(r.userID))
+			if err != nil {
+				failed++
+				continue
+			}
+			sealed, err := db.keys.Encrypt(plain, snapshotAAD(r.userID))
+			if err != nil {
+				return rewritten, failed, err
+			}
+
+			res, err := db.exec(`UPDATE recipes SET prefs_snapshot = ? WHERE id = ? AND prefs_snapshot = ?`,
+				sealed, r.id, r.snapshot)
+			if err != nil {
+				return rewritten, failed, err
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				rewritten++
+			}
+		}
+	}
+}
+
+type storedSnapshot struct {
+	id       int64
+	userID   int64
+	snapshot string
+}
+
+// snapshotsBatch читает непустые снимки предпочтений в том виде, в котором они хранятся
+func (db *DB) snapshotsBatch(afterID int64) ([]storedSnapshot, error) {
+	rows, err := db.query(`
+		SELECT id, user_id, prefs_snapshot
+		FROM recipes WHERE id > ? AND prefs_snapshot <> ''
+		ORDER BY id LIMIT ?
+	`, afterID, encryptBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batch []storedSnapshot
+	for rows.Next() {
+		var r storedSnapshot
+		if err := rows.Scan(&r.id, &r.userID, &r.snapshot); err != nil {
+			return nil, err
+		}
+		batch = append(batch, r)
+	}
+	return batch, rows.Err()
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+const feedbackColumns = `recipe_id, user_id, rating, reason, comment, diet_type, prompt_version, model, created_at`
+
+// SaveRecipeFeedback сохраняет оценку рецепта; повторная оценка заменяет предыдущую
+func (db *DB) SaveRecipeFeedback(f *models.RecipeFeedback) error {
+	_, err := db.exec(`
+		INSERT INTO recipe_feedback (`+feedbackColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(recipe_id) DO UPDATE SET
+			rating = excluded.rating,
+			reason = excluded.reason,
+			comment = excluded.comment,
+			created_at = excluded.created_at
+	`, f.RecipeID, f.UserID, f.Rating, f.Reason, f.Comment, f.DietType, f.PromptVersion, f.Model, f.CreatedAt.UnixMilli())
+	return err
+}
+
+// GetRecipeFeedback возвращает оценку рецепта пользователем (nil — не оценивал)
+func (db *DB) GetRecipeFeedback(userID, recipeID int64) (*models.RecipeFeedback, error) {
+	f, err := scanFeedback(db.queryRow(`
+		SELECT `+feedbackColumns+` FROM recipe_feedback WHERE recipe_id = ? AND user_id = ?
+	`, recipeID, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return f, err
+}
+
+// FeedbackReport собирает оценки с момента since по типу питания, версии промпта и модели,
+// а также причины отрицательных оценок (самые частые первыми)
+func (db *DB) FeedbackReport(since time.Time) ([]models.FeedbackStat, []models.FeedbackReasonStat, error) {
+	rows, err := db.query(`
+		SELECT diet_type, prompt_version, model,
+			SUM(CASE WHEN rating > 0 THEN 1 ELSE 0 END),
+			SUM(CASE WHEN rating < 0 THEN 1 ELSE 0 END)
+		FROM recipe_feedback
+		WHERE created_at >= ?
+		GROUP BY diet_type, prompt_version, model
+		ORDER BY diet_type, prompt_version, model
+	`, since.UnixMilli())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var stats []models.FeedbackStat
+	for rows.Next() {
+		var s models.FeedbackStat
+		if err := rows.Scan(&s.DietType, &s.PromptVersion, &s.Model, &s.Likes, &s.Dislikes); err != nil {
+			return nil, nil, err
+		}
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	reasonRows, err := db.query(`
+		SELECT reason, COUNT(*) FROM recipe_feedback
+		WHERE created_at >= ? AND rating < 0 AND reason <> ''
+		GROUP BY reason
+		ORDER BY COUNT(*) DESC, reason
+	`, since.UnixMilli())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer reasonRows.Close()
+
+	var reasons []models.FeedbackReasonStat
+	for reasonRows.Next() {
+		var r models.FeedbackReasonStat
+		if err := reasonRows.Scan(&r.Reason, &r.Count); err != nil {
+			return nil, nil, err
+		}
+		reasons = append(reasons, r)
+	}
+	return stats, reasons, reasonRows.Err()
+}
+
+func scanFeedback(row rowScanner) (*models.RecipeFeedback, error) {
+	var f models.RecipeFeedback
+	var createdAt int64
+
+	err := row.Scan(&f.RecipeID, &f.UserID, &f.Rating, &f.Reason, &f.Comment,
+		&f.DietType, &f.PromptVersion, &f.Model, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	f.CreatedAt = time.UnixMilli(createdAt)
+	return &f, nil
+}
@@ -0,0 +1,1240 @@
+package database
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// Memory — потокобезопасное хранилище в памяти для тестов и эфемерных развёртываний.
+// Данные теряются при перезапуске.
+type Memory struct {
+	mu        sync.RWMutex
+	states    map[int64]models.UserState
+	prefs     map[int64]models.UserPreferences
+	allergens map[int64]map[string]bool // отмеченные аллергены из справочника
+	diets     map[int64]map[string]bool // выбранные ограничения питания
+	bodies    map[int64]models.BodyProfile
+	limits    map[int64]time.Time // theoretical arrival time для GCRA
+	users     map[int64]models.User
+
+	generations      []memoryGeneration
+	lastGenerationID int64
+	quotas           map[int64]models.QuotaOverride
+
+	broadcasts      map[int64]*memoryBroadcast
+	lastBroadcastID int64
+
+	recipes      []models.Recipe // по возрастанию ID
+	lastRecipeID int64
+	feedback     map[int64]models.RecipeFeedback // по ID рецепта
+
+	shopping       []models.ShoppingItem // по возрастанию ID
+	lastShoppingID int64
+
+	plan []models.PlanMeal
+
+	pantry       []models.PantryItem // по возрастанию ID
+	lastPantryID int64
+
+	diary       []models.DiaryEntry // по возрастанию ID
+	lastDiaryID int64
+
+	notify          map[int64]models.NotifySettings
+	notifyJobs      []memoryNotifyJob
+	lastNotifyJobID int64
+
+	deletions []memoryDeletion // журнал аудита удалений
+}
+
+type memoryGeneration struct {
+	id        int64
+	userID    int64
+	status    string
+	errText   string
+	createdAt time.Time
+}
+
+type memoryDeletion struct {
+	userID    int64
+	deletedAt time.Time
+}
+
+type memoryBroadcast struct {
+	models.Broadcast
+	lockedUntil time.Time
+}
+
+type memoryNotifyJob struct {
+	models.NotifyJob
+	lockedUntil time.Time
+}
+
+func (b *memoryBroadcast) copy() *models.Broadcast {
+	c := b.Broadcast
+	c.Buttons = append([]models.BroadcastButton{}, b.Buttons...)
+	return &c
+}
+
+// NewMemory создаёт пустое хранилище в памяти
+func NewMemory() *Memory {
+	return &Memory{
+		states:    make(map[int64]models.UserState),
+		prefs:     make(map[int64]models.UserPreferences),
+		allergens: make(map[int64]map[string]bool),
+		diets:     make(map[int64]map[string]bool),
+		bodies:    make(map[int64]models.BodyProfile),
+		limits:    make(map[int64]time.Time),
+		users:     make(map[int64]models.User),
+		quotas:    make(map[int64]models.QuotaOverride),
+
+		broadcasts: make(map[int64]*memoryBroadcast),
+		feedback:   make(map[int64]models.RecipeFeedback),
+		notify:     make(map[int64]models.NotifySettings),
+	}
+}
+
+// SaveUserState сохраняет состояние пользователя
+func (m *Memory) SaveUserState(state *models.UserState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	saved := *state
+	saved.StateHistory = append([]string{}, state.StateHistory...)
+	m.states[state.UserID] = saved
+	return nil
+}
+
+// GetUserState получает состояние пользователя
+func (m *Memory) GetUserState(userID int64) (*models.UserState, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	saved, ok := m.states[userID]
+	if !ok {
+		// Возвращаем начальное состояние для нового пользователя
+		return &models.UserState{
+			UserID:       userID,
+			CurrentState: models.StateMain,
+			StateHistory: []string{},
+		}, nil
+	}
+
+	state := saved
+	state.StateHistory = append([]string{}, saved.StateHistory...)
+	return &state, nil
+}
+
+// CheckRateLimit проверяет и расходует лимит запросов (GCRA, как в SQL-реализации)
+func (m *Memory) CheckRateLimit(userID int64, limit models.RateLimit) (models.RateLimitResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tat, result := limit.Take(m.limits[userID], time.Now())
+	m.limits[userID] = tat
+	return result, nil
+}
+
+// SaveUserPreferences сохраняет предпочтения пользователя
+func (m *Memory) SaveUserPreferences(prefs *models.UserPreferences) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prefs[prefs.UserID] = *prefs
+	return nil
+}
+
+// GetUserPreferences получает предпочтения пользователя
+func (m *Memory) GetUserPreferences(userID int64) (*models.UserPreferences, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	prefs, ok := m.prefs[userID]
+	if !ok {
+		prefs = models.UserPreferences{UserID: userID}
+	}
+
+	prefs.Allergens = sortedCodes(m.allergens[userID])
+	prefs.DietRestrictions = sortedCodes(m.diets[userID])
+	prefs.Body = nil
+	if body, ok := m.bodies[userID]; ok {
+		prefs.Body = &body
+	}
+	return &prefs, nil
+}
+
+// sortedCodes возвращает отмеченные коды по алфавиту (nil, если их нет)
+func sortedCodes(set map[string]bool) []string {
+	var codes []string
+	for code := range set {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// ClearUserPreferences очищает все предпочтения пользователя
+func (m *Memory) ClearUserPreferences(userID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prefs[userID] = models.UserPreferences{UserID: userID}
+	delete(m.allergens, userID)
+	delete(m.diets, userID)
+	delete(m.bodies, userID)
+	return nil
+}
+
+// SaveBodyProfile сохраняет параметры тела пользователя
+func (m *Memory) SaveBodyProfile(profile *models.BodyProfile) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := *profile
+	p.UpdatedAt = time.Now()
+	m.bodies[profile.UserID] = p
+	return nil
+}
+
+// GetBodyProfile возвращает параметры тела пользователя
+func (m *Memory) GetBodyProfile(userID int64) (*models.BodyProfile, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p, ok := m.bodies[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+// SetAllergen отмечает аллерген или снимает отметку
+func (m *Memory) SetAllergen(userID int64, code string, selected bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	setCode(m.allergens, userID, code, selected)
+	return nil
+}
+
+// SetDietRestriction выбирает ограничение питания или снимает выбор
+func (m *Memory) SetDietRestriction(userID int64, code string, selected bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	setCode(m.diets, userID, code, selected)
+	return nil
+}
+
+// setCode отмечает код пользователя в наборе или снимает отметку
+func setCode(sets map[int64]map[string]bool, userID int64, code string, selected bool) {
+	if !selected {
+		delete(sets[userID], code)
+		return
+	}
+	if sets[userID] == nil {
+		sets[userID] = make(map[string]bool)
+	}
+	sets[userID][code] = true
+}
+
+// ReserveGeneration записывает начатую генерацию, если квота позволяет
+func (m *Memory) ReserveGeneration(userID int64, quota models.Quota, now time.Time) (int64, models.QuotaUsage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	usage := m.quotaUsage(userID, quota, now)
+	if usage.RemainingToday() == 0 || usage.RemainingMonth() == 0 {
+		return 0, usage, nil
+	}
+
+	m.lastGenerationID++
+	m.generations = append(m.generations, memoryGeneration{
+		id:        m.lastGenerationID,
+		userID:    userID,
+		status:    models.GenerationPending,
+		createdAt: now,
+	})
+	usage.UsedToday++
+	usage.UsedMonth++
+
+	return m.lastGenerationID, usage, nil
+}
+
+// FinishGeneration отмечает результат генерации
+func (m *Memory) FinishGeneration(id int64, genErr error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.generations {
+		if m.generations[i].id != id {
+			continue
+		}
+		m.generations[i].status = models.GenerationOK
+		if genErr != nil {
+			m.generations[i].status = models.GenerationError
+			m.generations[i].errText = genErr.Error()
+		}
+		break
+	}
+	return nil
+}
+
+// GetQuotaUsage считает использованные генерации за текущие сутки и месяц
+func (m *Memory) GetQuotaUsage(userID int64, quota models.Quota, now time.Time) (models.QuotaUsage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.quotaUsage(userID, quota, now), nil
+}
+
+func (m *Memory) quotaUsage(userID int64, quota models.Quota, now time.Time) models.QuotaUsage {
+	dayStart, monthStart := models.QuotaPeriods(now)
+	usage := models.QuotaUsage{Quota: quota}
+
+	for _, g := range m.generations {
+		if g.userID != userID || g.status == models.GenerationError || g.createdAt.Before(monthStart) {
+			continue
+		}
+		usage.UsedMonth++
+		if !g.createdAt.Before(dayStart) {
+			usage.UsedToday++
+		}
+	}
+	return usage
+}
+
+// GetUserQuota возвращает индивидуальную квоту пользователя (nil — не назначена)
+func (m *Memory) GetUserQuota(userID int64) (*models.QuotaOverride, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	override, ok := m.quotas[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &override, nil
+}
+
+// SetUserQuota сохраняет индивидуальную квоту; если оба лимита nil, запись удаляется
+func (m *Memory) SetUserQuota(override *models.QuotaOverride) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if override.Daily == nil && override.Monthly == nil {
+		delete(m.quotas, override.UserID)
+		return nil
+	}
+	m.quotas[override.UserID] = *override
+	return nil
+}
+
+// UpsertUser обновляет профиль пользователя и снимает отметку блокировки
+func (m *Memory) UpsertUser(user *models.User, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	saved := m.users[user.ID]
+	saved.ID = user.ID
+	saved.Username, saved.FirstName, saved.LanguageCode = user.Username, user.FirstName, user.LanguageCode
+	if saved.FirstSeen.IsZero() {
+		saved.FirstSeen = now
+	}
+	saved.LastSeen = now
+	saved.IsBlocked = false
+	m.users[user.ID] = saved
+	return nil
+}
+
+// GetUser возвращает профиль пользователя (nil — пользователь неизвестен)
+func (m *Memory) GetUser(userID int64) (*models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+// MarkUserInactive отмечает пользователя, которому бот больше не может писать
+func (m *Memory) MarkUserInactive(userID int64, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user := m.users[userID]
+	user.ID = userID
+	user.IsBlocked = true
+	m.users[userID] = user
+	return nil
+}
+
+// BanUser блокирует пользователя по решению администратора
+func (m *Memory) BanUser(userID, adminID int64, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user := m.users[userID]
+	user.ID = userID
+	user.IsBanned, user.BannedBy, user.BanReason = true, adminID, reason
+	m.users[userID] = user
+	return nil
+}
+
+// UnbanUser снимает блокировку
+func (m *Memory) UnbanUser(userID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if user, ok := m.users[userID]; ok {
+		user.IsBanned, user.BannedBy, user.BanReason = false, 0, ""
+		m.users[userID] = user
+	}
+	return nil
+}
+
+// IsBanned проверяет, заблокирован ли пользователь администратором
+func (m *Memory) IsBanned(userID int64) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.users[userID].IsBanned, nil
+}
+
+// GetStats собирает сводку для администратора за сутки, начинающиеся в dayStart
+func (m *Memory) GetStats(dayStart time.Time) (*models.Stats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := &models.Stats{}
+	for _, user := range m.users {
+		if !user.FirstSeen.IsZero() {
+			stats.TotalUsers++
+		}
+		if user.IsBanned {
+			stats.BannedUsers++
+		}
+		if user.IsBlocked {
+			stats.InactiveUsers++
+		}
+	}
+
+	active := make(map[int64]bool)
+	for _, g := range m.generations {
+		if g.createdAt.Before(dayStart) {
+			continue
+		}
+		active[g.userID] = true
+		switch g.status {
+		case models.GenerationOK:
+			stats.GenerationsToday++
+		case models.GenerationError:
+			stats.ErrorsToday++
+		}
+	}
+	stats.ActiveToday = len(active)
+
+	return stats, nil
+}
+
+// CreateBroadcast ставит рассылку в очередь и возвращает её ID
+func (m *Memory) CreateBroadcast(b *models.Broadcast) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastBroadcastID++
+	saved := *b
+	saved.ID = m.lastBroadcastID
+	saved.Status = models.BroadcastPending
+	saved.Buttons = append([]models.BroadcastButton{}, b.Buttons...)
+	m.broadcasts[saved.ID] = &memoryBroadcast{Broadcast: saved}
+	return saved.ID, nil
+}
+
+// GetBroadcast возвращает рассылку по ID (nil — не найдена)
+func (m *Memory) GetBroadcast(id int64) (*models.Broadcast, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	saved, ok := m.broadcasts[id]
+	if !ok {
+		return nil, nil
+	}
+	return saved.copy(), nil
+}
+
+// ClaimBroadcast берёт в работу самую старую незавершённую рассылку, аренда которой истекла
+func (m *Memory) ClaimBroadcast(now time.Time, lease time.Duration) (*models.Broadcast, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var claimed *memoryBroadcast
+	for _, b := range m.broadcasts {
+		if b.Status != models.BroadcastPending && b.Status != models.BroadcastRunning {
+			continue
+		}
+		if !b.lockedUntil.Before(now) {
+			continue
+		}
+		if claimed == nil || b.ID < claimed.ID {
+			claimed = b
+		}
+	}
+	if claimed == nil {
+		return nil, nil
+	}
+
+	claimed.Status = models.BroadcastRunning
+	claimed.lockedUntil = now.Add(lease)
+	return claimed.copy(), nil
+}
+
+// SaveBroadcastProgress сохраняет счётчики и последнего получателя, продлевая аренду
+func (m *Memory) SaveBroadcastProgress(b *models.Broadcast, lockedUntil time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	saved, ok := m.broadcasts[b.ID]
+	if !ok || saved.Status != models.BroadcastRunning {
+		return false, nil
+	}
+
+	saved.LastUserID = b.LastUserID
+	saved.Delivered, saved.Failed, saved.Blocked = b.Delivered, b.Failed, b.Blocked
+	saved.lockedUntil = lockedUntil
+	return true, nil
+}
+
+// FinishBroadcast отмечает рассылку завершённой
+func (m *Memory) FinishBroadcast(id int64, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if saved, ok := m.broadcasts[id]; ok && saved.Status == models.BroadcastRunning {
+		saved.Status = models.BroadcastDone
+		saved.FinishedAt = now
+		saved.lockedUntil = time.Time{}
+	}
+	return nil
+}
+
+// CancelBroadcast отменяет ещё не завершённую рассылку
+func (m *Memory) CancelBroadcast(id int64, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	saved, ok := m.broadcasts[id]
+	if !ok || (saved.Status != models.BroadcastPending && saved.Status != models.BroadcastRunning) {
+		return false, nil
+	}
+	saved.Status = models.BroadcastCancelled
+	saved.FinishedAt = now
+	return true, nil
+}
+
+// ListBroadcastRecipients возвращает до limit получателей с ID больше afterUserID по возрастанию ID
+func (m *Memory) ListBroadcastRecipients(filter models.RecipientFilter, afterUserID int64, limit int) ([]int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var active map[int64]bool
+	if !filter.ActiveSince.IsZero() {
+		active = make(map[int64]bool)
+		for _, g := range m.generations {
+			if !g.createdAt.Before(filter.ActiveSince) {
+				active[g.userID] = true
+			}
+		}
+	}
+
+	var ids []int64
+	for userID, user := range m.users {
+		if userID <= afterUserID || user.FirstSeen.IsZero() || user.IsBlocked || user.IsBanned {
+			continue
+		}
+		if filter.DietaryType != "" && m.prefs[userID].DietaryType != filter.DietaryType {
+			continue
+		}
+		if active != nil && !active[userID] {
+			continue
+		}
+		ids = append(ids, userID)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+// SaveRecipe сохраняет рецепт и возвращает его ID
+func (m *Memory) SaveRecipe(r *models.Recipe) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastRecipeID++
+	saved := *r
+	saved.ID = m.lastRecipeID
+	m.recipes = append(m.recipes, saved)
+	return saved.ID, nil
+}
+
+// GetRecipe возвращает рецепт пользователя
+func (m *Memory) GetRecipe(userID, id int64) (*models.Recipe, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, r := range m.recipes {
+		if r.ID == id && r.UserID == userID {
+			return &r, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListRecipes возвращает страницу рецептов пользователя, новые первыми
+func (m *Memory) ListRecipes(userID int64, favoritesOnly bool, offset, limit int) ([]models.Recipe, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var matched []models.Recipe
+	for i := len(m.recipes) - 1; i >= 0; i-- {
+		r := m.recipes[i]
+		if r.UserID == userID && (!favoritesOnly || r.IsFavorite) {
+			matched = append(matched, r)
+		}
+	}
+
+	total := len(matched)
+	if offset >= total {
+		return nil, total, nil
+	}
+	return matched[offset:min(offset+limit, total)], total, nil
+}
+
+// SetRecipeFavorite добавляет рецепт в избранное или убирает из него
+func (m *Memory) SetRecipeFavorite(userID, id int64, favorite bool) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.recipes {
+		if m.recipes[i].ID == id && m.recipes[i].UserID == userID {
+			m.recipes[i].IsFavorite = favorite
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DeleteRecipe удаляет рецепт пользователя
+func (m *Memory) DeleteRecipe(userID, id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := len(m.recipes)
+	m.recipes = removeRecipes(m.recipes, func(r *models.Recipe) bool { return r.ID == id && r.UserID == userID })
+	return len(m.recipes) < n, nil
+}
+
+// removeRecipes удаляет рецепты, подходящие под условие, сохраняя порядок
+func removeRecipes(recipes []models.Recipe, match func(r *models.Recipe) bool) []models.Recipe {
+	kept := recipes[:0]
+	for i := range recipes {
+		if !match(&recipes[i]) {
+			kept = append(kept, recipes[i])
+		}
+	}
+	return kept
+}
+
+// SaveRecipeFeedback сохраняет оценку рецепта, заменяя предыдущую
+func (m *Memory) SaveRecipeFeedback(f *models.RecipeFeedback) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	saved := *f
+	if old, ok := m.feedback[f.RecipeID]; ok {
+		// Как и в SQL: контекст генерации фиксируется при первой оценке
+		saved.UserID, saved.DietType, saved.PromptVersion, saved.Model = old.UserID, old.DietType, old.PromptVersion, old.Model
+	}
+	m.feedback[f.RecipeID] = saved
+	return nil
+}
+
+// GetRecipeFeedback возвращает оценку рецепта пользователем
+func (m *Memory) GetRecipeFeedback(userID, recipeID int64) (*models.RecipeFeedback, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	f, ok := m.feedback[recipeID]
+	if !ok || f.UserID != userID {
+		return nil, nil
+	}
+	return &f, nil
+}
+
+// FeedbackReport собирает оценки с момента since
+func (m *Memory) FeedbackReport(since time.Time) ([]models.FeedbackStat, []models.FeedbackReasonStat, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	groups := make(map[[3]string]*models.FeedbackStat)
+	reasonCounts := make(map[string]int)
+	for _, f := range m.feedback {
+		if f.CreatedAt.Before(since) {
+			continue
+		}
+		key := [3]string{f.DietType, f.PromptVersion, f.Model}
+		if groups[key] == nil {
+			groups[key] = &models.FeedbackStat{DietType: f.DietType, PromptVersion: f.PromptVersion, Model: f.Model}
+		}
+		if f.Rating > 0 {
+			groups[key].Likes++
+		} else if f.Rating < 0 {
+			groups[key].Dislikes++
+			if f.Reason != "" {
+				reasonCounts[f.Reason]++
+			}
+		}
+	}
+
+	var stats []models.FeedbackStat
+	for _, s := range groups {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if a.DietType != b.DietType {
+			return a.DietType < b.DietType
+		}
+		if a.PromptVersion != b.PromptVersion {
+			return a.PromptVersion < b.PromptVersion
+		}
+		return a.Model < b.Model
+	})
+
+	var reasons []models.FeedbackReasonStat
+	for reason, count := range reasonCounts {
+		reasons = append(reasons, models.FeedbackReasonStat{Reason: reason, Count: count})
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if reasons[i].Count != reasons[j].Count {
+			return reasons[i].Count > reasons[j].Count
+		}
+		return reasons[i].Reason < reasons[j].Reason
+	})
+
+	return stats, reasons, nil
+}
+
+// AddShoppingItems добавляет продукты в список покупок, складывая одинаковые
+func (m *Memory) AddShoppingItems(userID int64, items []models.ShoppingItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, item := range items {
+		key := models.ShoppingKey(item.Name)
+		merged := false
+		for i := range m.shopping {
+			old := &m.shopping[i]
+			if old.UserID != userID || old.Unit != item.Unit || models.ShoppingKey(old.Name) != key {
+				continue
+			}
+			if old.Checked {
+				old.Amount = 0
+			}
+			old.Amount += item.Amount
+			old.Checked = false
+			merged = true
+			break
+		}
+		if merged {
+			continue
+		}
+
+		m.lastShoppingID++
+		saved := item
+		saved.ID, saved.UserID, saved.Checked = m.lastShoppingID, userID, false
+		m.shopping = append(m.shopping, saved)
+	}
+	return nil
+}
+
+// ListShoppingItems возвращает список покупок пользователя в порядке добавления
+func (m *Memory) ListShoppingItems(userID int64) ([]models.ShoppingItem, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	items := m.userShopping(userID)
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return items, nil
+}
+
+// SetShoppingItemChecked отмечает продукт купленным или снимает отметку
+func (m *Memory) SetShoppingItemChecked(userID, id int64, checked bool) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.shopping {
+		if m.shopping[i].ID == id && m.shopping[i].UserID == userID {
+			m.shopping[i].Checked = checked
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ClearShoppingList удаляет из списка купленные продукты (checkedOnly) или все
+func (m *Memory) ClearShoppingList(userID int64, checkedOnly bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.removeShopping(func(item *models.ShoppingItem) bool {
+		return item.UserID == userID && (item.Checked || !checkedOnly)
+	})
+	return nil
+}
+
+func (m *Memory) userShopping(userID int64) []models.ShoppingItem {
+	items := []models.ShoppingItem{}
+	for _, item := range m.shopping {
+		if item.UserID == userID {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// removeShopping удаляет продукты, подходящие под условие, сохраняя порядок
+func (m *Memory) removeShopping(match func(item *models.ShoppingItem) bool) {
+	kept := m.shopping[:0]
+	for i := range m.shopping {
+		if !match(&m.shopping[i]) {
+			kept = append(kept, m.shopping[i])
+		}
+	}
+	m.shopping = kept
+}
+
+// SavePlanMeals сохраняет блюда плана питания, заменяя блюда на те же дни и приёмы пищи
+func (m *Memory) SavePlanMeals(userID int64, meals []models.PlanMeal) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, meal := range meals {
+		saved := meal
+		saved.UserID = userID
+		replaced := false
+		for i := range m.plan {
+			if old := &m.plan[i]; old.UserID == userID && old.Day == meal.Day && old.Slot == meal.Slot {
+				*old = saved
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			m.plan = append(m.plan, saved)
+		}
+	}
+	return nil
+}
+
+// GetPlanMeals возвращает блюда плана с дня from по день to включительно
+func (m *Memory) GetPlanMeals(userID int64, from, to string) ([]models.PlanMeal, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var meals []models.PlanMeal
+	for _, meal := range m.plan {
+		if meal.UserID == userID && meal.Day >= from && meal.Day <= to {
+			meals = append(meals, meal)
+		}
+	}
+	sort.Slice(meals, func(i, j int) bool {
+		if meals[i].Day != meals[j].Day {
+			return meals[i].Day < meals[j].Day
+		}
+		return meals[i].Slot < meals[j].Slot
+	})
+	return meals, nil
+}
+
+// AddPantryItems добавляет продукты в запасы, складывая одинаковые
+func (m *Memory) AddPantryItems(userID int64, items []models.PantryItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, item := range items {
+		key := models.ShoppingKey(item.Name)
+		merged := false
+		for i := range m.pantry {
+			old := &m.pantry[i]
+			if old.UserID != userID || old.Unit != item.Unit || models.ShoppingKey(old.Name) != key {
+				continue
+			}
+			old.Amount += item.Amount
+			if old.Expires == "" || item.Expires != "" && item.Expires < old.Expires {
+				old.Expires = item.Expires
+			}
+			merged = true
+			break
+		}
+		if merged {
+			continue
+		}
+
+		m.lastPantryID++
+		saved := item
+		saved.ID, saved.UserID = m.lastPantryID, userID
+		m.pantry = append(m.pantry, saved)
+	}
+	return nil
+}
+
+// ListPantryItems возвращает запасы пользователя: сначала с ближайшим сроком годности, потом без срока
+func (m *Memory) ListPantryItems(userID int64) ([]models.PantryItem, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	items := m.userPantry(userID)
+	if len(items) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].Expires, items[j].Expires
+		if (a == "") != (b == "") {
+			return b == ""
+		}
+		return a < b
+	})
+	return items, nil
+}
+
+// DeletePantryItem убирает продукт из запасов
+func (m *Memory) DeletePantryItem(userID, id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := len(m.pantry)
+	m.removePantry(func(item *models.PantryItem) bool { return item.ID == id && item.UserID == userID })
+	return len(m.pantry) < n, nil
+}
+
+// DeductPantryItems списывает израсходованное количество; закончившиеся продукты удаляются
+func (m *Memory) DeductPantryItems(userID int64, uses []models.PantryUse) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range uses {
+		m.removePantry(func(item *models.PantryItem) bool {
+			if item.ID != u.ItemID || item.UserID != userID {
+				return false
+			}
+			item.Amount -= u.Amount
+			return item.Amount <= 0
+		})
+	}
+	return nil
+}
+
+func (m *Memory) userPantry(userID int64) []models.PantryItem {
+	items := []models.PantryItem{}
+	for _, item := range m.pantry {
+		if item.UserID == userID {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// removePantry удаляет продукты, подходящие под условие, сохраняя порядок
+func (m *Memory) removePantry(match func(item *models.PantryItem) bool) {
+	kept := m.pantry[:0]
+	for i := range m.pantry {
+		if !match(&m.pantry[i]) {
+			kept = append(kept, m.pantry[i])
+		}
+	}
+	m.pantry = kept
+}
+
+// ExportUserData собирает всё, что хранится о пользователе
+// AddDiaryEntry записывает съеденную порцию в дневник и возвращает ID записи
+func (m *Memory) AddDiaryEntry(e *models.DiaryEntry) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastDiaryID++
+	saved := *e
+	saved.ID = m.lastDiaryID
+	m.diary = append(m.diary, saved)
+	return saved.ID, nil
+}
+
+// ListDiaryEntries возвращает записи дневника с дня from по день to включительно
+func (m *Memory) ListDiaryEntries(userID int64, from, to string) ([]models.DiaryEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.userDiary(userID, from, to), nil
+}
+
+// DeleteDiaryEntry удаляет запись дневника. Возвращает false, если запись не найдена.
+func (m *Memory) DeleteDiaryEntry(userID, id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before := len(m.diary)
+	m.removeDiary(func(e *models.DiaryEntry) bool { return e.ID == id && e.UserID == userID })
+	return len(m.diary) < before, nil
+}
+
+func (m *Memory) userDiary(userID int64, from, to string) []models.DiaryEntry {
+	entries := []models.DiaryEntry{}
+	for _, e := range m.diary {
+		if e.UserID == userID && e.Day >= from && e.Day <= to {
+			entries = append(entries, e)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Day < entries[j].Day })
+	return entries
+}
+
+func (m *Memory) removeDiary(match func(e *models.DiaryEntry) bool) {
+	entries := m.diary[:0]
+	for i := range m.diary {
+		if !match(&m.diary[i]) {
+			entries = append(entries, m.diary[i])
+		}
+	}
+	m.diary = entries
+}
+
+// GetNotifySettings возвращает настройки уведомлений (nil, если пользователь их не менял)
+func (m *Memory) GetNotifySettings(userID int64) (*models.NotifySettings, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.notify[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+// SaveNotifySettings сохраняет настройки уведомлений и заменяет задания пользователя на jobs
+func (m *Memory) SaveNotifySettings(s *models.NotifySettings, jobs []models.NotifyJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	saved := *s
+	saved.UpdatedAt = time.Now()
+	m.notify[s.UserID] = saved
+
+	m.removeNotifyJobs(s.UserID)
+	for _, job := range jobs {
+		m.lastNotifyJobID++
+		job.ID = m.lastNotifyJobID
+		job.UserID = s.UserID
+		m.notifyJobs = append(m.notifyJobs, memoryNotifyJob{NotifyJob: job})
+	}
+	return nil
+}
+
+// ClaimNotifyJob берёт в работу самое раннее наступившее задание, аренда которого истекла
+func (m *Memory) ClaimNotifyJob(now time.Time, lease time.Duration) (*models.NotifyJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var claimed *memoryNotifyJob
+	for i := range m.notifyJobs {
+		job := &m.notifyJobs[i]
+		if job.RunAt.After(now) || !job.lockedUntil.Before(now) {
+			continue
+		}
+		if claimed == nil || job.RunAt.Before(claimed.RunAt) {
+			claimed = job
+		}
+	}
+	if claimed == nil {
+		return nil, nil
+	}
+
+	claimed.lockedUntil = now.Add(lease)
+	job := claimed.NotifyJob
+	return &job, nil
+}
+
+// RescheduleNotifyJob переносит задание на runAt и снимает аренду
+func (m *Memory) RescheduleNotifyJob(id int64, runAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.notifyJobs {
+		if job := &m.notifyJobs[i]; job.ID == id {
+			job.RunAt = runAt
+			job.lockedUntil = time.Time{}
+		}
+	}
+	return nil
+}
+
+// ListNotifyJobs возвращает задания пользователя по времени отправки
+func (m *Memory) ListNotifyJobs(userID int64) ([]models.NotifyJob, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.userNotifyJobs(userID), nil
+}
+
+func (m *Memory) userNotifyJobs(userID int64) []models.NotifyJob {
+	jobs := []models.NotifyJob{}
+	for _, job := range m.notifyJobs {
+		if job.UserID == userID {
+			jobs = append(jobs, job.NotifyJob)
+		}
+	}
+	sort.SliceStable(jobs, func(i, j int) bool { return jobs[i].RunAt.Before(jobs[j].RunAt) })
+	return jobs
+}
+
+func (m *Memory) removeNotifyJobs(userID int64) {
+	jobs := m.notifyJobs[:0]
+	for _, job := range m.notifyJobs {
+		if job.UserID != userID {
+			jobs = append(jobs, job)
+		}
+	}
+	m.notifyJobs = jobs
+}
+
+func (m *Memory) ExportUserData(userID int64) (*models.UserData, error) {
+	data := &models.UserData{UserID: userID, ExportedAt: time.Now()}
+
+	// Методы ниже берут блокировку сами
+	data.Profile, _ = m.GetUser(userID)
+	data.State, _ = m.GetUserState(userID)
+	data.Preferences, _ = m.GetUserPreferences(userID)
+	data.Quota, _ = m.GetUserQuota(userID)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if tat, ok := m.limits[userID]; ok {
+		data.RateLimitTAT = &tat
+	}
+
+	data.Generations = []models.GenerationRecord{}
+	for _, g := range m.generations {
+		if g.userID != userID {
+			continue
+		}
+		data.Generations = append(data.Generations, models.GenerationRecord{
+			ID:        g.id,
+			Status:    g.status,
+			Error:     g.errText,
+			CreatedAt: g.createdAt,
+		})
+	}
+
+	data.Recipes = []models.Recipe{}
+	for _, r := range m.recipes {
+		if r.UserID == userID {
+			data.Recipes = append(data.Recipes, r)
+		}
+	}
+
+	data.Feedback = []models.RecipeFeedback{}
+	for _, f := range m.feedback {
+		if f.UserID == userID {
+			data.Feedback = append(data.Feedback, f)
+		}
+	}
+	sort.Slice(data.Feedback, func(i, j int) bool { return data.Feedback[i].RecipeID < data.Feedback[j].RecipeID })
+
+	data.Shopping = m.userShopping(userID)
+
+	data.Pantry = m.userPantry(userID)
+
+	data.Plan = []models.PlanMeal{}
+	for _, meal := range m.plan {
+		if meal.UserID == userID {
+			data.Plan = append(data.Plan, meal)
+		}
+	}
+	sort.Slice(data.Plan, func(i, j int) bool {
+		if data.Plan[i].Day != data.Plan[j].Day {
+			return data.Plan[i].Day < data.Plan[j].Day
+		}
+		return data.Plan[i].Slot < data.Plan[j].Slot
+	})
+
+	data.Diary = m.userDiary(userID, "", "9999-12-31")
+
+	if s, ok := m.notify[userID]; ok {
+		data.Notify = &s
+	}
+	data.NotifyJobs = m.userNotifyJobs(userID)
+
+	return data, nil
+}
+
+// DeleteUserData безвозвратно удаляет все данные пользователя и записывает удаление в журнал
+func (m *Memory) DeleteUserData(userID int64, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Бан переживает удаление данных, как и в DB.DeleteUserData
+	if user := m.users[userID]; user.IsBanned {
+		m.users[userID] = models.User{ID: userID, IsBanned: true, BannedBy: user.BannedBy, BanReason: user.BanReason}
+	} else {
+		delete(m.users, userID)
+	}
+	delete(m.states, userID)
+	delete(m.prefs, userID)
+	delete(m.allergens, userID)
+	delete(m.diets, userID)
+	delete(m.bodies, userID)
+	m.recipes = removeRecipes(m.recipes, func(r *models.Recipe) bool { return r.UserID == userID })
+	for id, f := range m.feedback {
+		if f.UserID == userID {
+			delete(m.feedback, id)
+		}
+	}
+	m.removeShopping(func(item *models.ShoppingItem) bool { return item.UserID == userID })
+	m.removePantry(func(item *models.PantryItem) bool { return item.UserID == userID })
+	plan := m.plan[:0]
+	for _, meal := range m.plan {
+		if meal.UserID != userID {
+			plan = append(plan, meal)
+		}
+	}
+	m.plan = plan
+	m.removeDiary(func(e *models.DiaryEntry) bool { return e.UserID == userID })
+	delete(m.notify, userID)
+	m.removeNotifyJobs(userID)
+	delete(m.limits, userID)
+	delete(m.quotas, userID)
+
+	generations := m.generations[:0]
+	for _, g := range m.generations {
+		if g.userID != userID {
+			generations = append(generations, g)
+		}
+	}
+	m.generations = generations
+
+	m.deletions = append(m.deletions, memoryDeletion{userID: userID, deletedAt: now})
+	return nil
+}
+
+// Close ничего не делает: хранилищу в памяти нечего закрывать
+func (m *Memory) Close() error {
+	return nil
+}
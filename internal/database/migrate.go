@@ -0,0 +1,280 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/sqlite/*.sql migrations/postgres/*.sql
+var migrationsFS embed.FS
+
+// migrationLockID — ключ advisory-блокировки PostgreSQL на время применения миграций
+const migrationLockID = 727312
+
+// Migration — одна версия схемы: SQL для применения и отката
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// loadMigrations читает файлы вида 0001_name.up.sql / 0001_name.down.sql и сортирует их по версии
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать миграции: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(fileName, "."+direction+".sql")
+		versionStr, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("некорректное имя миграции: %s", fileName)
+		}
+		version, err := strconv.Atoi(versionStr)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("некорректная версия миграции: %s", fileName)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, fileName))
+		if err != nil {
+			return nil, fmt.Errorf("не удалось прочитать %s: %w", fileName, err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("у миграции %d нет up-файла", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// ensureMigrationsTable создаёт таблицу учёта применённых миграций
+func (db *DB) ensureMigrationsTable() error {
+	_, err := db.exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	return err
+}
+
+// MigrationVersion возвращает текущую версию схемы (0 — миграции не применялись)
+func (db *DB) MigrationVersion() (int, error) {
+	if err := db.ensureMigrationsTable(); err != nil {
+		return 0, err
+	}
+
+	var version int
+	err := db.queryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// LatestMigrationVersion возвращает версию последней встроенной миграции
+func (db *DB) LatestMigrationVersion() int {
+	if len(db.migrations) == 0 {
+		return 0
+	}
+	return db.migrations[len(db.migrations)-1].Version
+}
+
+// MigrateUp применяет все ещё не применённые миграции
+func (db *DB) MigrateUp() error {
+	return db.MigrateTo(db.LatestMigrationVersion())
+}
+
+// MigrateDown откатывает steps последних миграций
+func (db *DB) MigrateDown(steps int) error {
+	current, err := db.MigrationVersion()
+	if err != nil {
+		return err
+	}
+
+	target := 0
+	for i := len(db.migrations) - 1; i >= 0; i-- {
+		if db.migrations[i].Version > current {
+			continue
+		}
+		if steps == 0 {
+			target = db.migrations[i].Version
+			break
+		}
+		steps--
+	}
+
+	return db.MigrateTo(target)
+}
+
+// MigrateTo приводит схему к версии target, применяя или откатывая миграции по одной
+func (db *DB) MigrateTo(target int) error {
+	current, err := db.MigrationVersion()
+	if err != nil {
+		return err
+	}
+
+	if target > db.LatestMigrationVersion() {
+		return fmt.Errorf("миграции версии %d не существует", target)
+	}
+
+	if target >= current {
+		for _, m := range db.migrations {
+			if m.Version <= current || m.Version > target {
+				continue
+			}
+			if err := db.applyMigration(m, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i := len(db.migrations) - 1; i >= 0; i-- {
+		m := db.migrations[i]
+		if m.Version > current || m.Version <= target {
+			continue
+		}
+		if err := db.applyMigration(m, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyMigration применяет (up) или откатывает миграцию в одной транзакции
+func (db *DB) applyMigration(m Migration, up bool) error {
+	script := m.Up
+	if !up {
+		if m.Down == "" {
+			return fmt.Errorf("миграция %d_%s не поддерживает откат", m.Version, m.Name)
+		}
+		script = m.Down
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if db.dialect == dialectPostgres {
+		// Несколько реплик могут стартовать одновременно: сериализуем миграции
+		// и перепроверяем версию уже под блокировкой
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+			return fmt.Errorf("не удалось получить блокировку миграций: %w", err)
+		}
+
+		var applied int
+		err := tx.QueryRow(db.rebind(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`), m.Version).Scan(&applied)
+		if err != nil {
+			return err
+		}
+		if (applied > 0) == up {
+			return nil
+		}
+	}
+
+	if _, err := tx.Exec(script); err != nil {
+		return fmt.Errorf("миграция %d_%s: %w", m.Version, m.Name, err)
+	}
+
+	if up {
+		_, err = tx.Exec(db.rebind(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`),
+			m.Version, m.Name, time.Now())
+	} else {
+		_, err = tx.Exec(db.rebind(`DELETE FROM schema_migrations WHERE version = ?`), m.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось записать версию %d: %w", m.Version, err)
+	}
+
+	return tx.Commit()
+}
+
+// appliedMigrations возвращает применённые версии с датой применения
+func (db *DB) appliedMigrations() (map[int]time.Time, error) {
+	rows, err := db.query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt sql.NullTime
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt.Time
+	}
+
+	return applied, rows.Err()
+}
+
+// MigrationState — строка отчёта о миграциях
+type MigrationState struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// MigrationStatus возвращает список встроенных миграций с отметками о применении
+func (db *DB) MigrationStatus() ([]MigrationState, error) {
+	if err := db.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(db.migrations))
+	for _, m := range db.migrations {
+		appliedAt, ok := applied[m.Version]
+		states = append(states, MigrationState{Migration: m, Applied: ok, AppliedAt: appliedAt})
+	}
+
+	return states, nil
+}
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS user_states (
+    user_id BIGINT PRIMARY KEY,
+    current_state TEXT NOT NULL,
+    last_message_id INTEGER,
+    input_data TEXT,
+    state_history TEXT,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS rate_limits (
+    user_id BIGINT PRIMARY KEY,
+    last_request_at TIMESTAMPTZ,
+    request_count INTEGER DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id BIGINT PRIMARY KEY,
+    dietary_type TEXT DEFAULT '',
+    goal TEXT DEFAULT '',
+    allergies TEXT DEFAULT '',
+    likes TEXT DEFAULT '',
+    dislikes TEXT DEFAULT '',
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS inactive_users (
+    user_id BIGINT PRIMARY KEY,
+    reason TEXT DEFAULT '',
+    marked_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
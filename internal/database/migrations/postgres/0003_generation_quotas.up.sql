@@ -0,0 +1,18 @@
+-- Журнал генераций: по нему считаются дневные и месячные квоты
+CREATE TABLE generations (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending', -- pending, ok, error
+    error TEXT DEFAULT '',
+    created_at BIGINT NOT NULL -- unix ms
+);
+
+CREATE INDEX idx_generations_user_created ON generations (user_id, created_at);
+
+-- Индивидуальные квоты, назначенные администратором (NULL — квота по умолчанию)
+CREATE TABLE user_quotas (
+    user_id BIGINT PRIMARY KEY,
+    daily_limit INTEGER,
+    monthly_limit INTEGER,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
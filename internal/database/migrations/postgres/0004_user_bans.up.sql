@@ -0,0 +1,6 @@
+CREATE TABLE user_bans (
+    user_id BIGINT PRIMARY KEY,
+    banned_by BIGINT NOT NULL,
+    reason TEXT DEFAULT '',
+    banned_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,45 @@
+-- Пользователи: профиль из Telegram и жизненный цикл.
+-- Заменяет inactive_users (is_blocked) и user_bans (is_banned).
+-- first_seen = 0 — пользователь ещё не писал боту (например, забанен заранее по ID).
+CREATE TABLE users (
+    user_id BIGINT PRIMARY KEY,
+    username TEXT NOT NULL DEFAULT '',
+    first_name TEXT NOT NULL DEFAULT '',
+    language_code TEXT NOT NULL DEFAULT '',
+    first_seen BIGINT NOT NULL DEFAULT 0, -- unix ms
+    last_seen BIGINT NOT NULL DEFAULT 0, -- unix ms
+    is_blocked BOOLEAN NOT NULL DEFAULT FALSE, -- пользователь заблокировал бота
+    blocked_reason TEXT NOT NULL DEFAULT '',
+    is_banned BOOLEAN NOT NULL DEFAULT FALSE, -- забанен администратором
+    banned_by BIGINT NOT NULL DEFAULT 0,
+    ban_reason TEXT NOT NULL DEFAULT '',
+    banned_at BIGINT NOT NULL DEFAULT 0 -- unix ms
+);
+
+-- Точное время первого визита неизвестно: берём время последнего изменения состояния
+INSERT INTO users (user_id, first_seen, last_seen)
+SELECT
+    user_id,
+    (EXTRACT(EPOCH FROM COALESCE(updated_at, now())) * 1000)::BIGINT,
+    (EXTRACT(EPOCH FROM COALESCE(updated_at, now())) * 1000)::BIGINT
+FROM user_states;
+
+INSERT INTO users (user_id, is_blocked, blocked_reason)
+SELECT user_id, TRUE, COALESCE(reason, '') FROM inactive_users
+ON CONFLICT(user_id) DO UPDATE SET
+    is_blocked = TRUE,
+    blocked_reason = excluded.blocked_reason;
+
+INSERT INTO users (user_id, is_banned, banned_by, ban_reason, banned_at)
+SELECT
+    user_id, TRUE, banned_by, COALESCE(reason, ''),
+    COALESCE((EXTRACT(EPOCH FROM banned_at) * 1000)::BIGINT, 0)
+FROM user_bans
+ON CONFLICT(user_id) DO UPDATE SET
+    is_banned = TRUE,
+    banned_by = excluded.banned_by,
+    ban_reason = excluded.ban_reason,
+    banned_at = excluded.banned_at;
+
+DROP TABLE inactive_users;
+DROP TABLE user_bans;
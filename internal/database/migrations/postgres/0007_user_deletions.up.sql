@@ -0,0 +1,7 @@
+-- Журнал удалений данных по запросу пользователя (/deleteme).
+-- Для аудита хранятся только ID пользователя и время удаления.
+CREATE TABLE user_deletions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    deleted_at BIGINT NOT NULL -- unix ms
+);
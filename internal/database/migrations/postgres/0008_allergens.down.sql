@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_allergens;
+DROP TABLE IF EXISTS allergens;
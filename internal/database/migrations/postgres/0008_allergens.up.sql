@@ -0,0 +1,26 @@
+-- Справочник аллергенов и связь «пользователь — аллерген».
+-- Коды совпадают с models.Allergens; свободный текст «другое» остаётся в user_preferences.allergies.
+CREATE TABLE allergens (
+    code TEXT PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+INSERT INTO allergens (code, name) VALUES
+    ('gluten', 'Глютен'),
+    ('lactose', 'Лактоза'),
+    ('nuts', 'Орехи'),
+    ('peanuts', 'Арахис'),
+    ('eggs', 'Яйца'),
+    ('fish', 'Рыба'),
+    ('shellfish', 'Морепродукты'),
+    ('soy', 'Соя'),
+    ('sesame', 'Кунжут'),
+    ('celery', 'Сельдерей'),
+    ('mustard', 'Горчица'),
+    ('sulfites', 'Сульфиты');
+
+CREATE TABLE user_allergens (
+    user_id BIGINT NOT NULL,
+    allergen_code TEXT NOT NULL REFERENCES allergens (code),
+    PRIMARY KEY (user_id, allergen_code)
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS body_profiles;
@@ -0,0 +1,10 @@
+-- Параметры тела для расчёта нормы калорий (мастер заполняет их по очереди)
+CREATE TABLE body_profiles (
+    user_id BIGINT PRIMARY KEY,
+    sex TEXT NOT NULL DEFAULT '', -- m или f
+    age INTEGER NOT NULL DEFAULT 0,
+    height_cm DOUBLE PRECISION NOT NULL DEFAULT 0,
+    weight_kg DOUBLE PRECISION NOT NULL DEFAULT 0,
+    activity TEXT NOT NULL DEFAULT '', -- код из models.ActivityLevels
+    updated_at BIGINT NOT NULL -- unix ms
+);
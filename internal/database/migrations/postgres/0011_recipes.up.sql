@@ -0,0 +1,16 @@
+-- Сгенерированные рецепты: история и избранное.
+-- prefs_snapshot — предпочтения в момент генерации (JSON, шифруется вместе с предпочтениями).
+CREATE TABLE recipes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    request TEXT NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    text TEXT NOT NULL,
+    prefs_snapshot TEXT NOT NULL DEFAULT '',
+    model TEXT NOT NULL DEFAULT '',
+    prompt_version TEXT NOT NULL DEFAULT '',
+    is_favorite BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at BIGINT NOT NULL -- unix ms
+);
+
+CREATE INDEX idx_recipes_user ON recipes (user_id, id);
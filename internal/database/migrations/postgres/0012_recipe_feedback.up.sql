@@ -0,0 +1,16 @@
+-- Оценки рецептов. Тип питания, версия промпта и модель копируются из рецепта,
+-- поэтому отзыв остаётся в отчёте и после удаления рецепта из истории.
+CREATE TABLE recipe_feedback (
+    recipe_id BIGINT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    rating INTEGER NOT NULL, -- 1 или -1
+    reason TEXT NOT NULL DEFAULT '', -- код из models.FeedbackReasons или other
+    comment TEXT NOT NULL DEFAULT '',
+    diet_type TEXT NOT NULL DEFAULT '',
+    prompt_version TEXT NOT NULL DEFAULT '',
+    model TEXT NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL -- unix ms
+);
+
+CREATE INDEX idx_recipe_feedback_created ON recipe_feedback (created_at);
+CREATE INDEX idx_recipe_feedback_user ON recipe_feedback (user_id);
@@ -0,0 +1,14 @@
+-- Список покупок. Одинаковые продукты складываются по (user_id, name_key, unit):
+-- name_key — название в нижнем регистре без «ё» и лишних пробелов.
+CREATE TABLE shopping_items (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    name TEXT NOT NULL,
+    name_key TEXT NOT NULL,
+    amount DOUBLE PRECISION NOT NULL DEFAULT 0, -- 0 — количество не указано
+    unit TEXT NOT NULL DEFAULT '',
+    section TEXT NOT NULL DEFAULT '',
+    checked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at BIGINT NOT NULL, -- unix ms
+    UNIQUE (user_id, name_key, unit)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS notify_jobs;
+DROP TABLE IF EXISTS notify_settings;
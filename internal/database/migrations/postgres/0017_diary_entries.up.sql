@@ -0,0 +1,15 @@
+-- Дневник питания: съеденные порции с пищевой ценностью на момент записи
+CREATE TABLE diary_entries (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    day TEXT NOT NULL, -- YYYY-MM-DD по часовому поясу пользователя
+    recipe_id BIGINT NOT NULL DEFAULT 0,
+    title TEXT NOT NULL,
+    calories DOUBLE PRECISION NOT NULL DEFAULT 0,
+    protein DOUBLE PRECISION NOT NULL DEFAULT 0,
+    fat DOUBLE PRECISION NOT NULL DEFAULT 0,
+    carbs DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at BIGINT NOT NULL -- unix ms
+);
+
+CREATE INDEX idx_diary_entries_user_day ON diary_entries (user_id, day);
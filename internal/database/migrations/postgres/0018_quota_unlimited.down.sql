@@ -0,0 +1,6 @@
+-- Запрет генераций (0) в старой схеме не выразить: он снимается вместе с квотой
+UPDATE user_quotas SET daily_limit = NULL WHERE daily_limit = 0;
+UPDATE user_quotas SET monthly_limit = NULL WHERE monthly_limit = 0;
+UPDATE user_quotas SET daily_limit = 0 WHERE daily_limit = -1;
+UPDATE user_quotas SET monthly_limit = 0 WHERE monthly_limit = -1;
+DELETE FROM user_quotas WHERE daily_limit IS NULL AND monthly_limit IS NULL;
@@ -0,0 +1,2 @@
+UPDATE recipes SET request = request || E'\n\n' || refinement WHERE refinement <> '';
+ALTER TABLE recipes DROP COLUMN refinement;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS inactive_users;
+DROP TABLE IF EXISTS user_preferences;
+DROP TABLE IF EXISTS rate_limits;
+DROP TABLE IF EXISTS user_states;
@@ -1,3 +1,5 @@
+-- Исходная схема. IF NOT EXISTS оставлен, чтобы миграция применялась к базам,
+-- созданным до появления миграций (через schema.sql).
 CREATE TABLE IF NOT EXISTS user_states (
     user_id INTEGER PRIMARY KEY,
     current_state TEXT NOT NULL,
@@ -21,4 +23,10 @@ CREATE TABLE IF NOT EXISTS user_preferences (
     likes TEXT DEFAULT '',
     dislikes TEXT DEFAULT '',
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-);
\ No newline at end of file
+);
+
+CREATE TABLE IF NOT EXISTS inactive_users (
+    user_id INTEGER PRIMARY KEY,
+    reason TEXT DEFAULT '',
+    marked_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
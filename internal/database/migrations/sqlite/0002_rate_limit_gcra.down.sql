@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS rate_limits;
+
+CREATE TABLE rate_limits (
+    user_id INTEGER PRIMARY KEY,
+    last_request_at DATETIME,
+    request_count INTEGER DEFAULT 0
+);
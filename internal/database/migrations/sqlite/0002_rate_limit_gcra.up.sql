@@ -0,0 +1,11 @@
+-- Ограничитель частоты переходит на алгоритм token bucket (GCRA):
+-- достаточно одного значения tat (theoretical arrival time, unix ms),
+-- которое обновляется атомарно одним INSERT ... ON CONFLICT.
+DROP TABLE IF EXISTS rate_limits;
+
+CREATE TABLE rate_limits (
+    user_id INTEGER PRIMARY KEY,
+    tat INTEGER NOT NULL,
+    allowed INTEGER NOT NULL DEFAULT 1,
+    updated_at INTEGER NOT NULL
+);
@@ -0,0 +1,18 @@
+-- Журнал генераций: по нему считаются дневные и месячные квоты
+CREATE TABLE generations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending', -- pending, ok, error
+    error TEXT DEFAULT '',
+    created_at INTEGER NOT NULL -- unix ms
+);
+
+CREATE INDEX idx_generations_user_created ON generations (user_id, created_at);
+
+-- Индивидуальные квоты, назначенные администратором (NULL — квота по умолчанию)
+CREATE TABLE user_quotas (
+    user_id INTEGER PRIMARY KEY,
+    daily_limit INTEGER,
+    monthly_limit INTEGER,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,6 @@
+CREATE TABLE user_bans (
+    user_id INTEGER PRIMARY KEY,
+    banned_by INTEGER NOT NULL,
+    reason TEXT DEFAULT '',
+    banned_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,21 @@
+-- Задания рассылки. Фоновый обработчик берёт задание под аренду (locked_until)
+-- и после каждого сообщения сохраняет last_user_id, поэтому после перезапуска
+-- рассылка продолжается с места остановки.
+CREATE TABLE broadcasts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    admin_id INTEGER NOT NULL,
+    admin_chat_id INTEGER NOT NULL,
+    text TEXT NOT NULL,
+    buttons TEXT NOT NULL DEFAULT '[]', -- JSON: [{"text": "...", "url": "..."}]
+    segment TEXT NOT NULL DEFAULT 'all',
+    status TEXT NOT NULL DEFAULT 'pending', -- pending, running, done, cancelled
+    last_user_id INTEGER NOT NULL DEFAULT 0,
+    delivered INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    blocked INTEGER NOT NULL DEFAULT 0,
+    locked_until INTEGER NOT NULL DEFAULT 0, -- unix ms
+    created_at INTEGER NOT NULL, -- unix ms
+    finished_at INTEGER NOT NULL DEFAULT 0 -- unix ms
+);
+
+CREATE INDEX idx_broadcasts_status ON broadcasts (status);
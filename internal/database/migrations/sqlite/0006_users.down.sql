@@ -0,0 +1,20 @@
+CREATE TABLE inactive_users (
+    user_id INTEGER PRIMARY KEY,
+    reason TEXT DEFAULT '',
+    marked_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE user_bans (
+    user_id INTEGER PRIMARY KEY,
+    banned_by INTEGER NOT NULL,
+    reason TEXT DEFAULT '',
+    banned_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO inactive_users (user_id, reason)
+SELECT user_id, blocked_reason FROM users WHERE is_blocked;
+
+INSERT INTO user_bans (user_id, banned_by, reason)
+SELECT user_id, banned_by, ban_reason FROM users WHERE is_banned;
+
+DROP TABLE users;
@@ -0,0 +1,45 @@
+-- Пользователи: профиль из Telegram и жизненный цикл.
+-- Заменяет inactive_users (is_blocked) и user_bans (is_banned).
+-- first_seen = 0 — пользователь ещё не писал боту (например, забанен заранее по ID).
+CREATE TABLE users (
+    user_id INTEGER PRIMARY KEY,
+    username TEXT NOT NULL DEFAULT '',
+    first_name TEXT NOT NULL DEFAULT '',
+    language_code TEXT NOT NULL DEFAULT '',
+    first_seen INTEGER NOT NULL DEFAULT 0, -- unix ms
+    last_seen INTEGER NOT NULL DEFAULT 0, -- unix ms
+    is_blocked BOOLEAN NOT NULL DEFAULT FALSE, -- пользователь заблокировал бота
+    blocked_reason TEXT NOT NULL DEFAULT '',
+    is_banned BOOLEAN NOT NULL DEFAULT FALSE, -- забанен администратором
+    banned_by INTEGER NOT NULL DEFAULT 0,
+    ban_reason TEXT NOT NULL DEFAULT '',
+    banned_at INTEGER NOT NULL DEFAULT 0 -- unix ms
+);
+
+-- Точное время первого визита неизвестно: берём время последнего изменения состояния
+INSERT INTO users (user_id, first_seen, last_seen)
+SELECT
+    user_id,
+    COALESCE(CAST(strftime('%s', updated_at) AS INTEGER), CAST(strftime('%s', 'now') AS INTEGER)) * 1000,
+    COALESCE(CAST(strftime('%s', updated_at) AS INTEGER), CAST(strftime('%s', 'now') AS INTEGER)) * 1000
+FROM user_states;
+
+INSERT INTO users (user_id, is_blocked, blocked_reason)
+SELECT user_id, TRUE, COALESCE(reason, '') FROM inactive_users WHERE TRUE
+ON CONFLICT(user_id) DO UPDATE SET
+    is_blocked = TRUE,
+    blocked_reason = excluded.blocked_reason;
+
+INSERT INTO users (user_id, is_banned, banned_by, ban_reason, banned_at)
+SELECT
+    user_id, TRUE, banned_by, COALESCE(reason, ''),
+    COALESCE(CAST(strftime('%s', banned_at) AS INTEGER), 0) * 1000
+FROM user_bans WHERE TRUE
+ON CONFLICT(user_id) DO UPDATE SET
+    is_banned = TRUE,
+    banned_by = excluded.banned_by,
+    ban_reason = excluded.ban_reason,
+    banned_at = excluded.banned_at;
+
+DROP TABLE inactive_users;
+DROP TABLE user_bans;
@@ -0,0 +1,7 @@
+-- Журнал удалений данных по запросу пользователя (/deleteme).
+-- Для аудита хранятся только ID пользователя и время удаления.
+CREATE TABLE user_deletions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    deleted_at INTEGER NOT NULL -- unix ms
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_diet_restrictions;
+DROP TABLE IF EXISTS diet_restrictions;
@@ -0,0 +1,21 @@
+-- Справочник ограничений питания и связь «пользователь — ограничение».
+-- Коды совпадают с models.DietRestrictions; цель по весу остаётся в user_preferences.dietary_type.
+CREATE TABLE diet_restrictions (
+    code TEXT PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+INSERT INTO diet_restrictions (code, name) VALUES
+    ('vegetarian', 'Вегетарианское'),
+    ('vegan', 'Веганское'),
+    ('keto', 'Кето'),
+    ('halal', 'Халяль'),
+    ('kosher', 'Кошерное'),
+    ('low_fodmap', 'Low-FODMAP'),
+    ('diabetic', 'Для диабетиков');
+
+CREATE TABLE user_diet_restrictions (
+    user_id INTEGER NOT NULL,
+    restriction_code TEXT NOT NULL REFERENCES diet_restrictions (code),
+    PRIMARY KEY (user_id, restriction_code)
+);
@@ -0,0 +1,12 @@
+-- План питания: одно блюдо на день (YYYY-MM-DD) и приём пищи.
+-- Новый план перезаписывает блюда на свои дни, прошлые дни остаются в календаре.
+CREATE TABLE plan_meals (
+    user_id INTEGER NOT NULL,
+    day TEXT NOT NULL,
+    slot TEXT NOT NULL, -- breakfast, lunch, dinner
+    title TEXT NOT NULL,
+    protein TEXT NOT NULL DEFAULT '',
+    calories INTEGER NOT NULL DEFAULT 0,
+    created_at INTEGER NOT NULL, -- unix ms
+    PRIMARY KEY (user_id, day, slot)
+);
@@ -0,0 +1,13 @@
+-- Запасы продуктов. Одинаковые продукты складываются по (user_id, name_key, unit),
+-- как в списке покупок; срок годности при сложении берётся более ранний.
+CREATE TABLE pantry_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    name_key TEXT NOT NULL,
+    amount REAL NOT NULL DEFAULT 0, -- 0 — количество не указано
+    unit TEXT NOT NULL DEFAULT '',
+    expires TEXT NOT NULL DEFAULT '', -- YYYY-MM-DD, '' — без срока годности
+    created_at INTEGER NOT NULL, -- unix ms
+    UNIQUE (user_id, name_key, unit)
+);
@@ -0,0 +1,29 @@
+-- Настройки уведомлений. Время — минуты от полуночи в часовом поясе пользователя.
+CREATE TABLE notify_settings (
+    user_id INTEGER PRIMARY KEY,
+    timezone TEXT NOT NULL, -- IANA или смещение вида UTC+3
+    quiet_start INTEGER NOT NULL DEFAULT 0, -- тихие часы; quiet_start = quiet_end — без них
+    quiet_end INTEGER NOT NULL DEFAULT 0,
+    expiry BOOLEAN NOT NULL DEFAULT FALSE,
+    expiry_at INTEGER NOT NULL DEFAULT 0,
+    dinner BOOLEAN NOT NULL DEFAULT FALSE,
+    dinner_at INTEGER NOT NULL DEFAULT 0,
+    plan BOOLEAN NOT NULL DEFAULT FALSE,
+    plan_at INTEGER NOT NULL DEFAULT 0,
+    updated_at INTEGER NOT NULL -- unix ms
+);
+
+-- Запланированные уведомления: одно задание на пользователя и вид.
+-- Фоновый обработчик берёт наступившее задание под аренду (locked_until)
+-- и после отправки переносит run_at на следующий раз, поэтому расписание
+-- переживает перезапуск.
+CREATE TABLE notify_jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    kind TEXT NOT NULL, -- expiry, dinner, plan
+    run_at INTEGER NOT NULL, -- unix ms
+    locked_until INTEGER NOT NULL DEFAULT 0, -- unix ms
+    UNIQUE (user_id, kind)
+);
+
+CREATE INDEX idx_notify_jobs_run_at ON notify_jobs (run_at);
@@ -0,0 +1,15 @@
+-- Дневник питания: съеденные порции с пищевой ценностью на момент записи
+CREATE TABLE diary_entries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    day TEXT NOT NULL, -- YYYY-MM-DD по часовому поясу пользователя
+    recipe_id INTEGER NOT NULL DEFAULT 0,
+    title TEXT NOT NULL,
+    calories REAL NOT NULL DEFAULT 0,
+    protein REAL NOT NULL DEFAULT 0,
+    fat REAL NOT NULL DEFAULT 0,
+    carbs REAL NOT NULL DEFAULT 0,
+    created_at INTEGER NOT NULL -- unix ms
+);
+
+CREATE INDEX idx_diary_entries_user_day ON diary_entries (user_id, day);
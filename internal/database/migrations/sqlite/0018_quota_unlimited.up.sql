@@ -0,0 +1,3 @@
+-- Раньше 0 в user_quotas означал «без ограничения»; теперь это -1, а 0 запрещает генерации
+UPDATE user_quotas SET daily_limit = -1 WHERE daily_limit = 0;
+UPDATE user_quotas SET monthly_limit = -1 WHERE monthly_limit = 0;
@@ -0,0 +1,2 @@
+UPDATE recipes SET request = request || char(10) || char(10) || refinement WHERE refinement <> '';
+ALTER TABLE recipes DROP COLUMN refinement;
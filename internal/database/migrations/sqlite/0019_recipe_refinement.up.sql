@@ -0,0 +1,3 @@
+-- refinement — уточнение кнопкой доработки, с которым сгенерирован рецепт; request — исходный запрос без него.
+-- В старых записях уточнения уже склеены с запросом: отделить их надёжно нельзя, они остаются как есть.
+ALTER TABLE recipes ADD COLUMN refinement TEXT NOT NULL DEFAULT '';
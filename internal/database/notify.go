@@ -0,0 +1,133 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// GetNotifySettings возвращает настройки уведомлений (nil, если пользователь их не менял)
+func (db *DB) GetNotifySettings(userID int64) (*models.NotifySettings, error) {
+	s := &models.NotifySettings{UserID: userID}
+	var updatedAt int64
+
+	err := db.queryRow(`
+		SELECT timezone, quiet_start, quiet_end, expiry, expiry_at, dinner, dinner_at, plan, plan_at, updated_at
+		FROM notify_settings WHERE user_id = ?
+	`, userID).Scan(&s.Timezone, &s.QuietStart, &s.QuietEnd, &s.Expiry, &s.ExpiryAt,
+		&s.Dinner, &s.DinnerAt, &s.Plan, &s.PlanAt, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.UpdatedAt = time.UnixMilli(updatedAt)
+	return s, nil
+}
+
+// SaveNotifySettings сохраняет настройки уведомлений и заменяет задания пользователя на jobs
+func (db *DB) SaveNotifySettings(s *models.NotifySettings, jobs []models.NotifyJob) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(db.rebind(`
+		INSERT INTO notify_settings (user_id, timezone, quiet_start, quiet_end, expiry, expiry_at,
+			dinner, dinner_at, plan, plan_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			timezone = excluded.timezone,
+			quiet_start = excluded.quiet_start,
+			quiet_end = excluded.quiet_end,
+			expiry = excluded.expiry,
+			expiry_at = excluded.expiry_at,
+			dinner = excluded.dinner,
+			dinner_at = excluded.dinner_at,
+			plan = excluded.plan,
+			plan_at = excluded.plan_at,
+			updated_at = excluded.updated_at
+	`), s.UserID, s.Timezone, s.QuietStart, s.QuietEnd, s.Expiry, s.ExpiryAt,
+		s.Dinner, s.DinnerAt, s.Plan, s.PlanAt, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(db.rebind(`DELETE FROM notify_jobs WHERE user_id = ?`), s.UserID); err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		_, err := tx.Exec(db.rebind(`
+			INSERT INTO notify_jobs (user_id, kind, run_at) VALUES (?, ?, ?)
+		`), s.UserID, job.Kind, job.RunAt.UnixMilli())
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ClaimNotifyJob берёт в работу самое раннее наступившее задание, аренда которого истекла,
+// и продлевает аренду до now+lease. Возвращает nil, если брать нечего.
+// Как и у рассылок, аренда не даёт двум репликам отправить одно уведомление,
+// а после падения задание подхватит следующий вызов.
+func (db *DB) ClaimNotifyJob(now time.Time, lease time.Duration) (*models.NotifyJob, error) {
+	var job models.NotifyJob
+	var runAt int64
+	err := db.queryRow(`
+		UPDATE notify_jobs SET locked_until = ?
+		WHERE id = (
+			SELECT id FROM notify_jobs
+			WHERE run_at <= ? AND locked_until < ?
+			ORDER BY run_at, id LIMIT 1
+		) AND locked_until < ?
+		RETURNING id, user_id, kind, run_at
+	`, now.Add(lease).UnixMilli(), now.UnixMilli(), now.UnixMilli(), now.UnixMilli()).
+		Scan(&job.ID, &job.UserID, &job.Kind, &runAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job.RunAt = time.UnixMilli(runAt)
+	return &job, nil
+}
+
+// RescheduleNotifyJob переносит задание на runAt и снимает аренду.
+// Задание, заменённое новыми настройками, уже удалено — тогда ничего не происходит.
+func (db *DB) RescheduleNotifyJob(id int64, runAt time.Time) error {
+	_, err := db.exec(`
+		UPDATE notify_jobs SET run_at = ?, locked_until = 0 WHERE id = ?
+	`, runAt.UnixMilli(), id)
+	return err
+}
+
+// ListNotifyJobs возвращает задания пользователя по времени отправки
+func (db *DB) ListNotifyJobs(userID int64) ([]models.NotifyJob, error) {
+	rows, err := db.query(`
+		SELECT id, user_id, kind, run_at FROM notify_jobs WHERE user_id = ? ORDER BY run_at, id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []models.NotifyJob
+	for rows.Next() {
+		var job models.NotifyJob
+		var runAt int64
+		if err := rows.Scan(&job.ID, &job.UserID, &job.Kind, &runAt); err != nil {
+			return nil, err
+		}
+		job.RunAt = time.UnixMilli(runAt)
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
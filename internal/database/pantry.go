@@ -0,0 +1,92 @@
+package database
+
+import (
+	"time"
+
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// AddPantryItems добавляет продукты в запасы. Продукт с тем же названием и единицей
+// складывается с уже добавленным, срок годности остаётся более ранний.
+func (db *DB) AddPantryItems(userID int64, items []models.PantryItem) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, item := range items {
+		_, err := tx.Exec(db.rebind(`
+			INSERT INTO pantry_items (user_id, name, name_key, amount, unit, expires, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(user_id, name_key, unit) DO UPDATE SET
+				amount = pantry_items.amount + excluded.amount,
+				expires = CASE
+					WHEN pantry_items.expires = '' OR (excluded.expires <> '' AND excluded.expires < pantry_items.expires)
+					THEN excluded.expires ELSE pantry_items.expires END
+		`), userID, item.Name, models.ShoppingKey(item.Name), item.Amount, item.Unit, item.Expires, item.CreatedAt.UnixMilli())
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListPantryItems возвращает запасы пользователя: сначала с ближайшим сроком годности, потом без срока
+func (db *DB) ListPantryItems(userID int64) ([]models.PantryItem, error) {
+	rows, err := db.query(`
+		SELECT id, user_id, name, amount, unit, expires, created_at
+		FROM pantry_items WHERE user_id = ?
+		ORDER BY CASE WHEN expires = '' THEN 1 ELSE 0 END, expires, id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.PantryItem
+	for rows.Next() {
+		var item models.PantryItem
+		var createdAt int64
+		if err := rows.Scan(&item.ID, &item.UserID, &item.Name, &item.Amount, &item.Unit, &item.Expires, &createdAt); err != nil {
+			return nil, err
+		}
+		item.CreatedAt = time.UnixMilli(createdAt)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// DeletePantryItem убирает продукт из запасов. Возвращает false, если продукт не найден.
+func (db *DB) DeletePantryItem(userID, id int64) (bool, error) {
+	res, err := db.exec(`DELETE FROM pantry_items WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeductPantryItems списывает израсходованное количество; закончившиеся продукты удаляются
+func (db *DB) DeductPantryItems(userID int64, uses []models.PantryUse) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, u := range uses {
+		_, err := tx.Exec(db.rebind(`UPDATE pantry_items SET amount = amount - ? WHERE id = ? AND user_id = ?`),
+			u.Amount, u.ItemID, userID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(db.rebind(`DELETE FROM pantry_items WHERE id = ? AND user_id = ? AND amount <= 0`),
+			u.ItemID, userID)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
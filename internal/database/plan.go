@@ -0,0 +1,56 @@
+package database
+
+import (
+	"time"
+
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// SavePlanMeals сохраняет блюда плана питания, заменяя блюда на те же дни и приёмы пищи
+func (db *DB) SavePlanMeals(userID int64, meals []models.PlanMeal) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, m := range meals {
+		_, err := tx.Exec(db.rebind(`
+			INSERT INTO plan_meals (user_id, day, slot, title, protein, calories, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(user_id, day, slot) DO UPDATE SET
+				title = excluded.title,
+				protein = excluded.protein,
+				calories = excluded.calories,
+				created_at = excluded.created_at
+		`), userID, m.Day, m.Slot, m.Title, m.Protein, m.Calories, m.CreatedAt.UnixMilli())
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetPlanMeals возвращает блюда плана с дня from по день to включительно (формат models.DayLayout)
+func (db *DB) GetPlanMeals(userID int64, from, to string) ([]models.PlanMeal, error) {
+	rows, err := db.query(`
+		SELECT user_id, day, slot, title, protein, calories, created_at
+		FROM plan_meals WHERE user_id = ? AND day >= ? AND day <= ? ORDER BY day, slot
+	`, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var meals []models.PlanMeal
+	for rows.Next() {
+		var m models.PlanMeal
+		var createdAt int64
+		if err := rows.Scan(&m.UserID, &m.Day, &m.Slot, &m.Title, &m.Protein, &m.Calories, &createdAt); err != nil {
+			return nil, err
+		}
+		m.CreatedAt = time.UnixMilli(createdAt)
+		meals = append(meals, m)
+	}
+	return meals, rows.Err()
+}
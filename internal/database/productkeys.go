@@ -0,0 +1,151 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// rekeyBatchSize — сколько строк читается за один проход RekeyProducts
+const rekeyBatchSize = 500
+
+// productRow — строка списка покупок или запасов с сохранённым ключом названия
+type productRow struct {
+	id     int64
+	userID int64
+	name   string
+	key    string
+	unit   string
+}
+
+// RekeyProducts пересчитывает name_key в списке покупок и запасах по текущему
+// models.ShoppingKey. Строки, ключи которых совпали («Курица» и «курицы»), складываются
+// так же, как при добавлении. Возвращает число изменённых строк; повторный запуск ничего не меняет.
+func (db *DB) RekeyProducts() (int, error) {
+	shopping, err := db.rekeyTable("shopping_items", db.mergeShoppingItem)
+	if err != nil {
+		return shopping, err
+	}
+	pantry, err := db.rekeyTable("pantry_items", db.mergePantryItem)
+	return shopping + pantry, err
+}
+
+// rekeyTable обновляет ключи одной таблицы; merge складывает строку с уже существующей
+func (db *DB) rekeyTable(table string, merge func(tx *sql.Tx, from, into int64) error) (int, error) {
+	var changed int
+	var afterID int64
+	for {
+		batch, err := db.productsBatch(table, afterID)
+		if err != nil {
+			return changed, err
+		}
+		if len(batch) == 0 {
+			return changed, nil
+		}
+		afterID = batch[len(batch)-1].id
+
+		for _, row := range batch {
+			key := models.ShoppingKey(row.name)
+			if key == row.key {
+				continue
+			}
+			ok, err := db.rekeyProduct(table, row, key, merge)
+			if err != nil {
+				return changed, err
+			}
+			if ok {
+				changed++
+			}
+		}
+	}
+}
+
+// rekeyProduct записывает строке новый ключ или, если продукт с таким ключом и единицей
+// уже есть, складывает строку с ним. false — строку успели изменить или удалить.
+func (db *DB) rekeyProduct(table string, row productRow, key string, merge func(tx *sql.Tx, from, into int64) error) (bool, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var into int64
+	err = tx.QueryRow(db.rebind(`SELECT id FROM `+table+` WHERE user_id = ? AND name_key = ? AND unit = ?`),
+		row.userID, key, row.unit).Scan(&into)
+	switch {
+	case err == sql.ErrNoRows:
+		res, err := tx.Exec(db.rebind(`UPDATE `+table+` SET name_key = ? WHERE id = ? AND name_key = ?`), key, row.id, row.key)
+		if err != nil {
+			return false, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return false, nil
+		}
+	case err != nil:
+		return false, err
+	default:
+		if err := merge(tx, row.id, into); err != nil {
+			return false, err
+		}
+		res, err := tx.Exec(db.rebind(`DELETE FROM `+table+` WHERE id = ? AND name_key = ?`), row.id, row.key)
+		if err != nil {
+			return false, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return false, nil
+		}
+	}
+	return true, tx.Commit()
+}
+
+// mergeShoppingItem складывает продукт списка покупок: купленный продукт не добавляет
+// количества к некупленному, отметка остаётся, только если куплены оба
+func (db *DB) mergeShoppingItem(tx *sql.Tx, from, into int64) error {
+	_, err := tx.Exec(db.rebind(`
+		UPDATE shopping_items SET
+			amount = CASE
+				WHEN shopping_items.checked = src.checked THEN shopping_items.amount + src.amount
+				WHEN shopping_items.checked THEN src.amount
+				ELSE shopping_items.amount END,
+			checked = shopping_items.checked AND src.checked
+		FROM (SELECT amount, checked FROM shopping_items WHERE id = ?) AS src
+		WHERE shopping_items.id = ?
+	`), from, into)
+	return err
+}
+
+// mergePantryItem складывает запасы; срок годности остаётся более ранний
+func (db *DB) mergePantryItem(tx *sql.Tx, from, into int64) error {
+	_, err := tx.Exec(db.rebind(`
+		UPDATE pantry_items SET
+			amount = pantry_items.amount + src.amount,
+			expires = CASE
+				WHEN pantry_items.expires = '' OR (src.expires <> '' AND src.expires < pantry_items.expires)
+				THEN src.expires ELSE pantry_items.expires END
+		FROM (SELECT amount, expires FROM pantry_items WHERE id = ?) AS src
+		WHERE pantry_items.id = ?
+	`), from, into)
+	return err
+}
+
+// productsBatch читает названия и сохранённые ключи строк таблицы после afterID
+func (db *DB) productsBatch(table string, afterID int64) ([]productRow, error) {
+	rows, err := db.query(`
+		SELECT id, user_id, name, name_key, unit FROM `+table+`
+		WHERE id > ? ORDER BY id LIMIT ?
+	`, afterID, rekeyBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batch []productRow
+	for rows.Next() {
+		var r productRow
+		if err := rows.Scan(&r.id, &r.userID, &r.name, &r.key, &r.unit); err != nil {
+			return nil, err
+		}
+		batch = append(batch, r)
+	}
+	return batch, rows.Err()
+}
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// quotaLockClass — первый ключ advisory-блокировки резервирования, второй — пользователь.
+// Двухключевые блокировки PostgreSQL не пересекаются с одноключевыми, как migrationLockID.
+const quotaLockClass = 1
+
+// ReserveGeneration записывает начатую генерацию, если дневная и месячная квоты позволяют.
+// Неудачные генерации в квоту не засчитываются.
+//
+// Проверка и вставка — один запрос. В SQLite он атомарен, так как запись одна на всю базу;
+// в PostgreSQL при READ COMMITTED две реплики увидели бы одинаковый счётчик, поэтому
+// резервирования одного пользователя сериализуются advisory-блокировкой до конца транзакции.
+func (db *DB) ReserveGeneration(userID int64, quota models.Quota, now time.Time) (int64, models.QuotaUsage, error) {
+	dayStart, monthStart := models.QuotaPeriods(now)
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, models.QuotaUsage{}, err
+	}
+	defer tx.Rollback()
+
+	if db.dialect == dialectPostgres {
+		// Второй ключ — int4: ID пользователя берётся по модулю, совпадение лишь сериализует двоих
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1, CAST(CAST($2 AS BIGINT) % 2147483647 AS INTEGER))`,
+			quotaLockClass, userID); err != nil {
+			return 0, models.QuotaUsage{}, err
+		}
+	}
+
+	var id int64
+	err = tx.QueryRow(db.rebind(`
+		INSERT INTO generations (user_id, status, created_at)
+		SELECT CAST(? AS BIGINT), ?, CAST(? AS BIGINT)
+		WHERE (? < 0 OR (
+			SELECT COUNT(*) FROM generations
+			WHERE user_id = ? AND status <> ? AND created_at >= ?
+		) < ?)
+		AND (? < 0 OR (
+			SELECT COUNT(*) FROM generations
+			WHERE user_id = ? AND status <> ? AND created_at >= ?
+		) < ?)
+		RETURNING id
+	`), userID, models.GenerationPending, now.UnixMilli(),
+		quota.Daily, userID, models.GenerationError, dayStart.UnixMilli(), quota.Daily,
+		quota.Monthly, userID, models.GenerationError, monthStart.UnixMilli(), quota.Monthly,
+	).Scan(&id)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, models.QuotaUsage{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, models.QuotaUsage{}, err
+	}
+
+	usage, err := db.GetQuotaUsage(userID, quota, now)
+	return id, usage, err
+}
+
+// FinishGeneration отмечает результат генерации
+func (db *DB) FinishGeneration(id int64, genErr error) error {
+	status, errText := models.GenerationOK, ""
+	if genErr != nil {
+		status, errText = models.GenerationError, genErr.Error()
+	}
+
+	_, err := db.exec(`UPDATE generations SET status = ?, error = ? WHERE id = ?`, status, errText, id)
+	return err
+}
+
+// GetQuotaUsage считает использованные генерации за текущие сутки и месяц
+func (db *DB) GetQuotaUsage(userID int64, quota models.Quota, now time.Time) (models.QuotaUsage, error) {
+	dayStart, monthStart := models.QuotaPeriods(now)
+	usage := models.QuotaUsage{Quota: quota}
+
+	err := db.queryRow(`
+		SELECT
+			COUNT(CASE WHEN created_at >= ? THEN 1 END),
+			COUNT(*)
+		FROM generations
+		WHERE user_id = ? AND status <> ? AND created_at >= ?
+	`, dayStart.UnixMilli(), userID, models.GenerationError, monthStart.UnixMilli()).Scan(&usage.UsedToday, &usage.UsedMonth)
+
+	return usage, err
+}
+
+// GetUserQuota возвращает индивидуальную квоту пользователя (nil — не назначена)
+func (db *DB) GetUserQuota(userID int64) (*models.QuotaOverride, error) {
+	var daily, monthly sql.NullInt64
+
+	err := db.queryRow(`
+		SELECT daily_limit, monthly_limit FROM user_quotas WHERE user_id = ?
+	`, userID).Scan(&daily, &monthly)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	override := &models.QuotaOverride{UserID: userID}
+	if daily.Valid {
+		v := int(daily.Int64)
+		override.Daily = &v
+	}
+	if monthly.Valid {
+		v := int(monthly.Int64)
+		override.Monthly = &v
+	}
+	return override, nil
+}
+
+// SetUserQuota сохраняет индивидуальную квоту; если оба лимита nil, запись удаляется
+func (db *DB) SetUserQuota(override *models.QuotaOverride) error {
+	if override.Daily == nil && override.Monthly == nil {
+		_, err := db.exec(`DELETE FROM user_quotas WHERE user_id = ?`, override.UserID)
+		return err
+	}
+
+	_, err := db.exec(`
+		INSERT INTO user_quotas (user_id, daily_limit, monthly_limit, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			daily_limit = excluded.daily_limit,
+			monthly_limit = excluded.monthly_limit,
+			updated_at = excluded.updated_at
+	`, override.UserID, nullInt(override.Daily), nullInt(override.Monthly), time.Now())
+
+	return err
+}
+
+// nullInt превращает *int в значение для SQL (nil → NULL)
+func nullInt(v *int) sql.NullInt64 {
+	if v == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*v), Valid: true}
+}
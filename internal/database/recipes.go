@@ -0,0 +1,152 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pinghoyk/neurobot/internal/fieldcrypt"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+const recipeColumns = `id, user_id, request, refinement, title, text, prefs_snapshot, model, prompt_version, is_favorite, created_at`
+
+// rowScanner — общее у *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// snapshotAAD привязывает шифротекст снимка предпочтений к пользователю
+func snapshotAAD(userID int64) string {
+	return fmt.Sprintf("recipes.prefs_snapshot:%d", userID)
+}
+
+// SaveRecipe сохраняет сгенерированный рецепт и возвращает его ID.
+// Снимок предпочтений содержит аллергии, поэтому шифруется, если заданы ключи.
+func (db *DB) SaveRecipe(r *models.Recipe) (int64, error) {
+	var snapshot string
+	if r.Preferences != nil {
+		data, err := json.Marshal(r.Preferences)
+		if err != nil {
+			return 0, err
+		}
+		snapshot = string(data)
+		if db.keys != nil {
+			if snapshot, err = db.keys.Encrypt(snapshot, snapshotAAD(r.UserID)); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	var id int64
+	err := db.queryRow(`
+		INSERT INTO recipes (user_id, request, refinement, title, text, prefs_snapshot, model, prompt_version, is_favorite, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, r.UserID, r.Request, r.Refinement, r.Title, r.Text, snapshot, r.Model, r.PromptVersion, r.IsFavorite,
+		r.CreatedAt.UnixMilli()).Scan(&id)
+
+	return id, err
+}
+
+// GetRecipe возвращает рецепт пользователя (nil — не найден или принадлежит другому)
+func (db *DB) GetRecipe(userID, id int64) (*models.Recipe, error) {
+	r, err := db.scanRecipe(db.queryRow(`SELECT `+recipeColumns+` FROM recipes WHERE id = ? AND user_id = ?`, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+// ListRecipes возвращает страницу рецептов пользователя, новые первыми,
+// и общее число рецептов в выборке. favoritesOnly — только избранные.
+func (db *DB) ListRecipes(userID int64, favoritesOnly bool, offset, limit int) ([]models.Recipe, int, error) {
+	where := `user_id = ?`
+	if favoritesOnly {
+		where += ` AND is_favorite = TRUE`
+	}
+
+	var total int
+	if err := db.queryRow(`SELECT COUNT(*) FROM recipes WHERE `+where, userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.query(`
+		SELECT `+recipeColumns+` FROM recipes WHERE `+where+`
+		ORDER BY id DESC LIMIT ? OFFSET ?
+	`, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var recipes []models.Recipe
+	for rows.Next() {
+		r, err := db.scanRecipe(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		recipes = append(recipes, *r)
+	}
+	return recipes, total, rows.Err()
+}
+
+// SetRecipeFavorite добавляет рецепт в избранное или убирает из него.
+// Возвращает false, если рецепт не найден.
+func (db *DB) SetRecipeFavorite(userID, id int64, favorite bool) (bool, error) {
+	res, err := db.exec(`UPDATE recipes SET is_favorite = ? WHERE id = ? AND user_id = ?`, favorite, id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteRecipe удаляет рецепт пользователя. Возвращает false, если рецепт не найден.
+func (db *DB) DeleteRecipe(userID, id int64) (bool, error) {
+	res, err := db.exec(`DELETE FROM recipes WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// scanRecipe читает строку рецепта. Снимок предпочтений, который не удалось
+// расшифровать или разобрать, пропускается с записью в лог: сам рецепт при этом доступен.
+func (db *DB) scanRecipe(row rowScanner) (*models.Recipe, error) {
+	r := &models.Recipe{}
+	var snapshot string
+	var createdAt int64
+
+	err := row.Scan(&r.ID, &r.UserID, &r.Request, &r.Refinement, &r.Title, &r.Text, &snapshot,
+		&r.Model, &r.PromptVersion, &r.IsFavorite, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	r.CreatedAt = time.UnixMilli(createdAt)
+
+	if fieldcrypt.IsEncrypted(snapshot) {
+		if db.keys == nil {
+			log.Printf("Ошибка расшифровки снимка предпочтений рецепта %d: %v", r.ID, errNoKeyring)
+			return r, nil
+		}
+		if snapshot, err = db.keys.Decrypt(snapshot, snapshotAAD(r.UserID)); err != nil {
+			log.Printf("Ошибка расшифровки снимка предпочтений рецепта %d: %v", r.ID, err)
+			return r, nil
+		}
+	}
+	if snapshot != "" {
+		var prefs models.UserPreferences
+		if err := json.Unmarshal([]byte(snapshot), &prefs); err != nil {
+			log.Printf("Ошибка разбора снимка предпочтений рецепта %d: %v", r.ID, err)
+			return r, nil
+		}
+		r.Preferences = &prefs
+	}
+	return r, nil
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"time"
+
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// AddShoppingItems добавляет продукты в список покупок. Продукт с тем же названием
+// и единицей складывается с уже добавленным; если тот уже куплен, количество
+// начинается заново, а отметка снимается.
+func (db *DB) AddShoppingItems(userID int64, items []models.ShoppingItem) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, item := range items {
+		_, err := tx.Exec(db.rebind(`
+			INSERT INTO shopping_items (user_id, name, name_key, amount, unit, section, checked, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, FALSE, ?)
+			ON CONFLICT(user_id, name_key, unit) DO UPDATE SET
+				amount = CASE WHEN shopping_items.checked THEN excluded.amount
+					ELSE shopping_items.amount + excluded.amount END,
+				checked = FALSE
+		`), userID, item.Name, models.ShoppingKey(item.Name), item.Amount, item.Unit, item.Section, item.CreatedAt.UnixMilli())
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListShoppingItems возвращает список покупок пользователя в порядке добавления
+func (db *DB) ListShoppingItems(userID int64) ([]models.ShoppingItem, error) {
+	rows, err := db.query(`
+		SELECT id, user_id, name, amount, unit, section, checked, created_at
+		FROM shopping_items WHERE user_id = ? ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.ShoppingItem
+	for rows.Next() {
+		var item models.ShoppingItem
+		var createdAt int64
+		if err := rows.Scan(&item.ID, &item.UserID, &item.Name, &item.Amount, &item.Unit,
+			&item.Section, &item.Checked, &createdAt); err != nil {
+			return nil, err
+		}
+		item.CreatedAt = time.UnixMilli(createdAt)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// SetShoppingItemChecked отмечает продукт купленным или снимает отметку.
+// Возвращает false, если продукт не найден.
+func (db *DB) SetShoppingItemChecked(userID, id int64, checked bool) (bool, error) {
+	res, err := db.exec(`UPDATE shopping_items SET checked = ? WHERE id = ? AND user_id = ?`, checked, id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ClearShoppingList удаляет из списка купленные продукты (checkedOnly) или все
+func (db *DB) ClearShoppingList(userID int64, checkedOnly bool) error {
+	query := `DELETE FROM shopping_items WHERE user_id = ?`
+	if checkedOnly {
+		query += ` AND checked = TRUE`
+	}
+	_, err := db.exec(query, userID)
+	return err
+}
@@ -15,7 +15,7 @@ func (db *DB) SaveUserState(state *models.UserState) error {
 		historyJSON = []byte("[]")
 	}
 
-	_, err = db.conn.Exec(`
+	_, err = db.exec(`
 		INSERT INTO user_states (user_id, current_state, last_message_id, input_data, state_history, updated_at)
 		VALUES (?, ?, ?, ?, ?, ?)
 		ON CONFLICT(user_id) DO UPDATE SET
@@ -34,7 +34,7 @@ func (db *DB) GetUserState(userID int64) (*models.UserState, error) {
 	state := &models.UserState{UserID: userID}
 	var historyJSON string
 
-	err := db.conn.QueryRow(`
+	err := db.queryRow(`
 		SELECT current_state, last_message_id, input_data, state_history
 		FROM user_states WHERE user_id = ?
 	`, userID).Scan(&state.CurrentState, &state.LastMessageID, &state.InputData, &historyJSON)
@@ -62,57 +62,49 @@ func (db *DB) GetUserState(userID int64) (*models.UserState, error) {
 	return state, nil
 }
 
-// CheckRateLimit проверяет, не превышен ли лимит запросов
-func (db *DB) CheckRateLimit(userID int64) (bool, error) {
-	var lastRequest sql.NullTime
-	var requestCount int
-
-	err := db.conn.QueryRow(`
-		SELECT last_request_at, request_count FROM rate_limits WHERE user_id = ?
-	`, userID).Scan(&lastRequest, &requestCount)
-
-	if err == sql.ErrNoRows {
-		// Первый запрос пользователя
-		return true, db.updateRateLimit(userID)
-	}
-
+// CheckRateLimit атомарно проверяет лимит и расходует один запрос (GCRA).
+// Всё делается одним INSERT ... ON CONFLICT ... RETURNING, поэтому параллельные
+// запросы одного пользователя не могут проскочить лимит.
+func (db *DB) CheckRateLimit(userID int64, limit models.RateLimit) (models.RateLimitResult, error) {
+	now := time.Now().UnixMilli()
+	interval := limit.Interval().Milliseconds()
+	tolerance := limit.Tolerance().Milliseconds()
+
+	var tat int64
+	var allowed int
+	err := db.queryRow(`
+		INSERT INTO rate_limits (user_id, tat, allowed, updated_at)
+		VALUES (?, ?, 1, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			allowed = CASE WHEN rate_limits.tat - ? > excluded.updated_at THEN 0 ELSE 1 END,
+			tat = CASE
+				WHEN rate_limits.tat - ? > excluded.updated_at THEN rate_limits.tat
+				WHEN rate_limits.tat < excluded.updated_at THEN excluded.tat
+				ELSE rate_limits.tat + ?
+			END,
+			updated_at = excluded.updated_at
+		RETURNING tat, allowed
+	`, userID, now+interval, now, tolerance, tolerance, interval).Scan(&tat, &allowed)
 	if err != nil {
-		return false, err
+		return models.RateLimitResult{}, err
 	}
 
-	// Сбрасываем счетчик если прошла минута
-	if lastRequest.Valid && time.Since(lastRequest.Time) > time.Minute {
-		return true, db.updateRateLimit(userID)
+	if allowed == 1 {
+		return models.RateLimitResult{Allowed: true}, nil
 	}
-
-	// Проверяем лимит (например, 5 запросов в минуту)
-	if requestCount >= 5 {
-		return false, nil
-	}
-
-	// Увеличиваем счетчик
-	_, err = db.conn.Exec(`
-		UPDATE rate_limits SET request_count = request_count + 1 WHERE user_id = ?
-	`, userID)
-
-	return true, err
-}
-
-// updateRateLimit обновляет или создает запись о лимитах
-func (db *DB) updateRateLimit(userID int64) error {
-	_, err := db.conn.Exec(`
-		INSERT INTO rate_limits (user_id, last_request_at, request_count)
-		VALUES (?, ?, 1)
-		ON CONFLICT(user_id) DO UPDATE SET
-			last_request_at = excluded.last_request_at,
-			request_count = 1
-	`, userID, time.Now())
-	return err
+	return models.RateLimitResult{
+		RetryAfter: time.Duration(tat-tolerance-now) * time.Millisecond,
+	}, nil
 }
 
-// SaveUserPreferences сохраняет предпочтения пользователя
+// SaveUserPreferences сохраняет предпочтения пользователя; чувствительные поля шифруются
 func (db *DB) SaveUserPreferences(prefs *models.UserPreferences) error {
-	_, err := db.conn.Exec(`
+	prefs, err := db.encryptPreferences(prefs)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.exec(`
 		INSERT INTO user_preferences (user_id, dietary_type, goal, allergies, likes, dislikes, updated_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(user_id) DO UPDATE SET
@@ -127,26 +119,89 @@ func (db *DB) SaveUserPreferences(prefs *models.UserPreferences) error {
 	return err
 }
 
-// GetUserPreferences получает предпочтения пользователя
+// GetUserPreferences получает предпочтения пользователя.
+// Если поле не удалось расшифровать, возвращает ошибку без предпочтений.
 func (db *DB) GetUserPreferences(userID int64) (*models.UserPreferences, error) {
 	prefs := &models.UserPreferences{UserID: userID}
 
-	err := db.conn.QueryRow(`
+	err := db.queryRow(`
 		SELECT dietary_type, goal, allergies, likes, dislikes
 		FROM user_preferences WHERE user_id = ?
 	`, userID).Scan(&prefs.DietaryType, &prefs.Goal, &prefs.Allergies, &prefs.Likes, &prefs.Dislikes)
 
-	if err == sql.ErrNoRows {
-		// Возвращаем пустые предпочтения для нового пользователя
-		return prefs, nil
+	// Для нового пользователя предпочтения пустые, но аллергены могли быть отмечены раньше
+	if err != nil && err != sql.ErrNoRows {
+		return prefs, err
 	}
 
-	return prefs, err
+	if prefs.Allergens, err = db.userCodes(`SELECT allergen_code FROM user_allergens WHERE user_id = ? ORDER BY allergen_code`, userID); err != nil {
+		return prefs, err
+	}
+	if prefs.DietRestrictions, err = db.userCodes(`SELECT restriction_code FROM user_diet_restrictions WHERE user_id = ? ORDER BY restriction_code`, userID); err != nil {
+		return prefs, err
+	}
+	if prefs.Body, err = db.GetBodyProfile(userID); err != nil {
+		return prefs, err
+	}
+
+	// Шифротекст не должен попасть ни в промпт, ни обратно в базу при сохранении
+	if err := db.decryptPreferences(prefs); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// userCodes возвращает коды из справочника, отмеченные пользователем (аллергены, ограничения)
+func (db *DB) userCodes(query string, userID int64) ([]string, error) {
+	rows, err := db.query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
+
+// SetAllergen отмечает аллерген или снимает отметку. Операция идемпотентна,
+// поэтому повторное нажатие той же кнопки ничего не ломает.
+func (db *DB) SetAllergen(userID int64, code string, selected bool) error {
+	if !selected {
+		_, err := db.exec(`DELETE FROM user_allergens WHERE user_id = ? AND allergen_code = ?`, userID, code)
+		return err
+	}
+
+	_, err := db.exec(`
+		INSERT INTO user_allergens (user_id, allergen_code) VALUES (?, ?)
+		ON CONFLICT(user_id, allergen_code) DO NOTHING
+	`, userID, code)
+	return err
+}
+
+// SetDietRestriction выбирает ограничение питания или снимает выбор (идемпотентно)
+func (db *DB) SetDietRestriction(userID int64, code string, selected bool) error {
+	if !selected {
+		_, err := db.exec(`DELETE FROM user_diet_restrictions WHERE user_id = ? AND restriction_code = ?`, userID, code)
+		return err
+	}
+
+	_, err := db.exec(`
+		INSERT INTO user_diet_restrictions (user_id, restriction_code) VALUES (?, ?)
+		ON CONFLICT(user_id, restriction_code) DO NOTHING
+	`, userID, code)
+	return err
 }
 
 // ClearUserPreferences очищает все предпочтения пользователя
 func (db *DB) ClearUserPreferences(userID int64) error {
-	_, err := db.conn.Exec(`
+	_, err := db.exec(`
 		UPDATE user_preferences SET
 			dietary_type = '',
 			goal = '',
@@ -161,10 +216,21 @@ func (db *DB) ClearUserPreferences(userID int64) error {
 		return err
 	}
 
+	if _, err := db.exec(`DELETE FROM user_allergens WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	if _, err := db.exec(`DELETE FROM user_diet_restrictions WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	if _, err := db.exec(`DELETE FROM body_profiles WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+
 	// Если записи не было, создаем пустую
-	_, err = db.conn.Exec(`
-		INSERT OR IGNORE INTO user_preferences (user_id, dietary_type, goal, allergies, likes, dislikes, updated_at)
+	_, err = db.exec(`
+		INSERT INTO user_preferences (user_id, dietary_type, goal, allergies, likes, dislikes, updated_at)
 		VALUES (?, '', '', '', '', '', ?)
+		ON CONFLICT(user_id) DO NOTHING
 	`, userID, time.Now())
 
 	return err
@@ -0,0 +1,253 @@
+package database_test
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pinghoyk/neurobot/internal/database"
+	"github.com/pinghoyk/neurobot/internal/database/storetest"
+	"github.com/pinghoyk/neurobot/internal/fieldcrypt"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+func TestSQLiteStorage(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) database.Storage {
+		db, err := database.New(filepath.Join(t.TempDir(), "neurobot.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	})
+}
+
+// TestSQLiteEncryptedStorage — то же с шифрованием чувствительных полей
+func TestSQLiteEncryptedStorage(t *testing.T) {
+	keys, err := fieldcrypt.New([]fieldcrypt.Key{{ID: "test", Secret: make([]byte, fieldcrypt.KeySize)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storetest.Run(t, func(t *testing.T) database.Storage {
+		db, err := database.New(filepath.Join(t.TempDir(), "neurobot.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.UseKeyring(keys)
+		return db
+	})
+}
+
+func TestMemoryStorage(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) database.Storage {
+		return database.NewMemory()
+	})
+}
+
+// TestPostgresStorage запускается, если задан TEST_DATABASE_URL (см. «Тесты» в README).
+// Каждая проверка получает свою схему: задания и рассылки, оставшиеся от прошлых
+// запусков, не попадут в выборки ClaimNotifyJob и ClaimBroadcast.
+func TestPostgresStorage(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL не задан")
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	storetest.Run(t, func(t *testing.T) database.Storage {
+		schema := fmt.Sprintf("storetest_%d", time.Now().UnixNano())
+		if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+			t.Fatalf("CREATE SCHEMA: %v", err)
+		}
+		t.Cleanup(func() {
+			if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+				t.Errorf("DROP SCHEMA: %v", err)
+			}
+		})
+
+		db, err := database.NewPostgres(withSearchPath(t, dsn, schema))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	})
+}
+
+// withSearchPath добавляет к DSN (URL или key=value) параметр search_path
+func withSearchPath(t *testing.T, dsn, schema string) string {
+	if !strings.Contains(dsn, "://") {
+		return dsn + " search_path=" + schema
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("неверный TEST_DATABASE_URL: %v", err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// TestEncryptPreferencesSnapshots: снимки предпочтений в рецептах, сохранённые
+// без шифрования и старым ключом, перешифровываются текущим ключом
+func TestEncryptPreferencesSnapshots(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "neurobot.db")
+	db, err := database.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	oldKey := fieldcrypt.Key{ID: "old", Secret: make([]byte, fieldcrypt.KeySize)}
+	newKey := fieldcrypt.Key{ID: "new", Secret: []byte(strings.Repeat("k", fieldcrypt.KeySize))}
+	lostKey := fieldcrypt.Key{ID: "lost", Secret: []byte(strings.Repeat("l", fieldcrypt.KeySize))}
+
+	save := func(keys []fieldcrypt.Key) int64 {
+		t.Helper()
+		if keys == nil {
+			db.UseKeyring(nil)
+		} else {
+			keyring, err := fieldcrypt.New(keys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			db.UseKeyring(keyring)
+		}
+		id, err := db.SaveRecipe(&models.Recipe{
+			UserID:      42,
+			Title:       "Омлет",
+			Preferences: &models.UserPreferences{UserID: 42, Allergies: "орехи"},
+			CreatedAt:   time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("SaveRecipe: %v", err)
+		}
+		return id
+	}
+	plainID := save(nil)
+	oldID := save([]fieldcrypt.Key{oldKey})
+	lostID := save([]fieldcrypt.Key{lostKey})
+
+	keyring, err := fieldcrypt.New([]fieldcrypt.Key{newKey, oldKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.UseKeyring(keyring)
+	rewritten, failed, err := db.EncryptPreferences()
+	if err != nil {
+		t.Fatalf("EncryptPreferences: %v", err)
+	}
+	if rewritten != 2 || failed != 1 {
+		t.Errorf("перешифровано %d, не удалось %d; ожидалось 2 и 1", rewritten, failed)
+	}
+
+	raw, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	for _, id := range []int64{plainID, oldID} {
+		var snapshot string
+		if err := raw.QueryRow(`SELECT prefs_snapshot FROM recipes WHERE id = ?`, id).Scan(&snapshot); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(snapshot, "enc:new:") {
+			t.Errorf("рецепт %d: снимок %q не зашифрован текущим ключом", id, snapshot)
+		}
+
+		r, err := db.GetRecipe(42, id)
+		if err != nil || r == nil || r.Preferences == nil || r.Preferences.Allergies != "орехи" {
+			t.Errorf("рецепт %d: %+v, %v; ожидался расшифрованный снимок", id, r, err)
+		}
+	}
+
+	// Снимок неизвестного ключа пропускается, а рецепт остаётся доступен
+	r, err := db.GetRecipe(42, lostID)
+	if err != nil || r == nil || r.Preferences != nil {
+		t.Errorf("рецепт %d: %+v, %v; ожидался рецепт без снимка", lostID, r, err)
+	}
+
+	// Повторный запуск ничего не меняет
+	if rewritten, failed, err := db.EncryptPreferences(); err != nil || rewritten != 0 || failed != 1 {
+		t.Errorf("повторно: перешифровано %d, не удалось %d, %v; ожидалось 0 и 1", rewritten, failed, err)
+	}
+}
+
+// TestRekeyProducts: ключи, сохранённые прежней версией ShoppingKey, пересчитываются,
+// а совпавшие продукты складываются
+func TestRekeyProducts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "neurobot.db")
+	db, err := database.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	raw, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+
+	now := time.Now().UnixMilli()
+	_, err = raw.Exec(`
+		INSERT INTO shopping_items (user_id, name, name_key, amount, unit, section, checked, created_at) VALUES
+			(1, 'Курица', 'курица', 300, 'г', 'meat', FALSE, ?),
+			(1, 'курицы', 'курицы', 200, 'г', 'meat', FALSE, ?),
+			(1, 'Филе куриное', 'филе куриное', 1, 'шт', 'meat', TRUE, ?),
+			(1, 'куриное филе', 'куриное филе', 2, 'шт', 'meat', FALSE, ?),
+			(2, 'курицы', 'курицы', 100, 'г', 'meat', FALSE, ?)
+	`, now, now, now, now, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = raw.Exec(`
+		INSERT INTO pantry_items (user_id, name, name_key, amount, unit, expires, created_at) VALUES
+			(1, 'Молоко', 'молоко', 1000, 'мл', '2026-01-10', ?),
+			(1, 'молока', 'молока', 500, 'мл', '2026-01-05', ?)
+	`, now, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := db.RekeyProducts()
+	if err != nil {
+		t.Fatalf("RekeyProducts: %v", err)
+	}
+	if changed != 7 {
+		t.Errorf("изменено строк: %d; ожидалось 7", changed)
+	}
+
+	items, err := db.ListShoppingItems(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Купленный продукт не добавляет количества к некупленному
+	if len(items) != 2 || items[0].Amount != 500 || items[1].Amount != 2 || items[1].Checked {
+		t.Errorf("список покупок: %+v", items)
+	}
+	if other, _ := db.ListShoppingItems(2); len(other) != 1 || other[0].Amount != 100 {
+		t.Errorf("список другого пользователя: %+v", other)
+	}
+
+	pantry, err := db.ListPantryItems(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pantry) != 1 || pantry[0].Amount != 1500 || pantry[0].Expires != "2026-01-05" {
+		t.Errorf("запасы: %+v", pantry)
+	}
+
+	if changed, err := db.RekeyProducts(); err != nil || changed != 0 {
+		t.Errorf("повторно: изменено %d, %v; ожидалось 0", changed, err)
+	}
+}
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"time"
+
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// StateStore хранит состояние диалога (FSM) пользователя
+type StateStore interface {
+	GetUserState(userID int64) (*models.UserState, error)
+	SaveUserState(state *models.UserState) error
+}
+
+// PreferencesStore хранит кулинарные предпочтения пользователя
+type PreferencesStore interface {
+	GetUserPreferences(userID int64) (*models.UserPreferences, error)
+	SaveUserPreferences(prefs *models.UserPreferences) error
+	// ClearUserPreferences сбрасывает предпочтения вместе с аллергенами, ограничениями и параметрами тела
+	ClearUserPreferences(userID int64) error
+	// SetAllergen отмечает аллерген из справочника или снимает отметку (идемпотентно)
+	SetAllergen(userID int64, code string, selected bool) error
+	// SetDietRestriction выбирает ограничение питания или снимает выбор (идемпотентно)
+	SetDietRestriction(userID int64, code string, selected bool) error
+	// SaveBodyProfile сохраняет параметры тела; GetBodyProfile возвращает nil, если их нет
+	SaveBodyProfile(profile *models.BodyProfile) error
+	GetBodyProfile(userID int64) (*models.BodyProfile, error)
+}
+
+// RateLimitStore атомарно проверяет и расходует лимит запросов пользователя
+type RateLimitStore interface {
+	CheckRateLimit(userID int64, limit models.RateLimit) (models.RateLimitResult, error)
+}
+
+// QuotaStore ведёт журнал генераций и считает по нему дневные и месячные квоты
+type QuotaStore interface {
+	// ReserveGeneration записывает начатую генерацию, если квота позволяет.
+	// Возвращает ID записи (0 — квота исчерпана) и использование с учётом этой записи.
+	ReserveGeneration(userID int64, quota models.Quota, now time.Time) (int64, models.QuotaUsage, error)
+	// FinishGeneration отмечает результат; неудачные генерации не расходуют квоту
+	FinishGeneration(id int64, genErr error) error
+	GetQuotaUsage(userID int64, quota models.Quota, now time.Time) (models.QuotaUsage, error)
+	GetUserQuota(userID int64) (*models.QuotaOverride, error)
+	SetUserQuota(override *models.QuotaOverride) error
+}
+
+// UserStore хранит профили пользователей и отмечает тех, кому бот не может писать
+type UserStore interface {
+	// UpsertUser обновляет профиль и время последнего визита, снимая отметку блокировки
+	UpsertUser(user *models.User, now time.Time) error
+	GetUser(userID int64) (*models.User, error)
+	MarkUserInactive(userID int64, reason string) error
+}
+
+// AdminStore — операции администратора: баны и статистика
+type AdminStore interface {
+	BanUser(userID, adminID int64, reason string) error
+	UnbanUser(userID int64) error
+	IsBanned(userID int64) (bool, error)
+	GetStats(dayStart time.Time) (*models.Stats, error)
+}
+
+// BroadcastStore — очередь рассылок и выбор получателей
+type BroadcastStore interface {
+	CreateBroadcast(b *models.Broadcast) (int64, error)
+	GetBroadcast(id int64) (*models.Broadcast, error)
+	// ClaimBroadcast берёт в работу незавершённую рассылку под аренду до now+lease (nil — брать нечего)
+	ClaimBroadcast(now time.Time, lease time.Duration) (*models.Broadcast, error)
+	// SaveBroadcastProgress возвращает false, если рассылка отменена или уже завершена
+	SaveBroadcastProgress(b *models.Broadcast, lockedUntil time.Time) (bool, error)
+	FinishBroadcast(id int64, now time.Time) error
+	CancelBroadcast(id int64, now time.Time) (bool, error)
+	ListBroadcastRecipients(filter models.RecipientFilter, afterUserID int64, limit int) ([]int64, error)
+}
+
+// RecipeStore хранит историю сгенерированных рецептов и избранное.
+// Методы с userID работают только с рецептами этого пользователя.
+type RecipeStore interface {
+	SaveRecipe(recipe *models.Recipe) (int64, error)
+	// GetRecipe возвращает nil, если рецепт не найден
+	GetRecipe(userID, id int64) (*models.Recipe, error)
+	// ListRecipes возвращает страницу рецептов (новые первыми) и их общее число
+	ListRecipes(userID int64, favoritesOnly bool, offset, limit int) ([]models.Recipe, int, error)
+	SetRecipeFavorite(userID, id int64, favorite bool) (bool, error)
+	DeleteRecipe(userID, id int64) (bool, error)
+}
+
+// FeedbackStore хранит оценки рецептов и собирает по ним отчёт
+type FeedbackStore interface {
+	SaveRecipeFeedback(f *models.RecipeFeedback) error
+	// GetRecipeFeedback возвращает nil, если пользователь не оценивал рецепт
+	GetRecipeFeedback(userID, recipeID int64) (*models.RecipeFeedback, error)
+	FeedbackReport(since time.Time) ([]models.FeedbackStat, []models.FeedbackReasonStat, error)
+}
+
+// ShoppingStore хранит список покупок
+type ShoppingStore interface {
+	// AddShoppingItems складывает продукты с одинаковым названием и единицей
+	AddShoppingItems(userID int64, items []models.ShoppingItem) error
+	ListShoppingItems(userID int64) ([]models.ShoppingItem, error)
+	SetShoppingItemChecked(userID, id int64, checked bool) (bool, error)
+	ClearShoppingList(userID int64, checkedOnly bool) error
+}
+
+// PantryStore хранит запасы продуктов пользователя
+type PantryStore interface {
+	// AddPantryItems складывает продукты с одинаковым названием и единицей
+	AddPantryItems(userID int64, items []models.PantryItem) error
+	ListPantryItems(userID int64) ([]models.PantryItem, error)
+	DeletePantryItem(userID, id int64) (bool, error)
+	// DeductPantryItems списывает израсходованное; закончившиеся продукты удаляются
+	DeductPantryItems(userID int64, uses []models.PantryUse) error
+}
+
+// PlanStore хранит план питания как календарь: одно блюдо на день и приём пищи
+type PlanStore interface {
+	// SavePlanMeals заменяет блюда на те же дни и приёмы пищи
+	SavePlanMeals(userID int64, meals []models.PlanMeal) error
+	// GetPlanMeals возвращает блюда с дня from по день to включительно (models.DayLayout)
+	GetPlanMeals(userID int64, from, to string) ([]models.PlanMeal, error)
+}
+
+// DiaryStore хранит дневник питания
+type DiaryStore interface {
+	AddDiaryEntry(e *models.DiaryEntry) (int64, error)
+	// ListDiaryEntries возвращает записи с дня from по день to включительно (models.DayLayout)
+	ListDiaryEntries(userID int64, from, to string) ([]models.DiaryEntry, error)
+	DeleteDiaryEntry(userID, id int64) (bool, error)
+}
+
+// NotifyStore хранит настройки уведомлений и расписание их отправки
+type NotifyStore interface {
+	// GetNotifySettings возвращает nil, если пользователь не менял настройки
+	GetNotifySettings(userID int64) (*models.NotifySettings, error)
+	// SaveNotifySettings сохраняет настройки и заменяет задания пользователя на jobs
+	SaveNotifySettings(s *models.NotifySettings, jobs []models.NotifyJob) error
+	// ClaimNotifyJob берёт наступившее задание под аренду до now+lease (nil — брать нечего)
+	ClaimNotifyJob(now time.Time, lease time.Duration) (*models.NotifyJob, error)
+	// RescheduleNotifyJob переносит задание на runAt и снимает аренду
+	RescheduleNotifyJob(id int64, runAt time.Time) error
+	ListNotifyJobs(userID int64) ([]models.NotifyJob, error)
+}
+
+// UserDataStore выгружает и безвозвратно удаляет все данные пользователя
+type UserDataStore interface {
+	ExportUserData(userID int64) (*models.UserData, error)
+	// DeleteUserData удаляет данные из всех таблиц и записывает удаление в журнал аудита
+	DeleteUserData(userID int64, now time.Time) error
+}
+
+// Storage — всё хранилище бота целиком
+type Storage interface {
+	StateStore
+	PreferencesStore
+	RateLimitStore
+	QuotaStore
+	UserStore
+	AdminStore
+	BroadcastStore
+	RecipeStore
+	FeedbackStore
+	ShoppingStore
+	PantryStore
+	PlanStore
+	DiaryStore
+	NotifyStore
+	UserDataStore
+	Close() error
+}
+
+var (
+	_ Storage = (*DB)(nil)
+	_ Storage = (*Memory)(nil)
+)
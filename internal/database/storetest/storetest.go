@@ -0,0 +1,1373 @@
+// Package storetest — общий контрактный набор проверок для реализаций database.Storage.
+// SQLite, PostgreSQL и хранилище в памяти должны проходить его одинаково.
+//
+// Подключение — в internal/database/storage_test.go; PostgreSQL проверяется,
+// если задан TEST_DATABASE_URL (см. «Тесты» в README).
+package storetest
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pinghoyk/neurobot/internal/database"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// Factory создаёт хранилище для одной проверки. Закрывать его будет Run.
+type Factory func(t *testing.T) database.Storage
+
+// Run прогоняет все контрактные проверки
+func Run(t *testing.T, newStorage Factory) {
+	checks := []struct {
+		name string
+		fn   func(t *testing.T, s database.Storage)
+	}{
+		{"StateDefault", testStateDefault},
+		{"StateRoundTrip", testStateRoundTrip},
+		{"PreferencesDefault", testPreferencesDefault},
+		{"PreferencesRoundTrip", testPreferencesRoundTrip},
+		{"PreferencesPrefix", testPreferencesPrefix},
+		{"PreferencesClear", testPreferencesClear},
+		{"Allergens", testAllergens},
+		{"DietRestrictions", testDietRestrictions},
+		{"BodyProfile", testBodyProfile},
+		{"RateLimit", testRateLimit},
+		{"RateLimitConcurrent", testRateLimitConcurrent},
+		{"Quota", testQuota},
+		{"QuotaConcurrent", testQuotaConcurrent},
+		{"QuotaOverride", testQuotaOverride},
+		{"Users", testUsers},
+		{"Bans", testBans},
+		{"BroadcastRecipients", testBroadcastRecipients},
+		{"Broadcasts", testBroadcasts},
+		{"Recipes", testRecipes},
+		{"Feedback", testFeedback},
+		{"Shopping", testShopping},
+		{"Pantry", testPantry},
+		{"Plan", testPlan},
+		{"Diary", testDiary},
+		{"Notify", testNotify},
+		{"UserData", testUserData},
+		{"UserDataKeepsBan", testUserDataKeepsBan},
+	}
+
+	for _, c := range checks {
+		t.Run(c.name, func(t *testing.T) {
+			s := newStorage(t)
+			t.Cleanup(func() { s.Close() })
+			c.fn(t, s)
+		})
+	}
+}
+
+var lastUserID atomic.Int64
+
+// NewUserID возвращает ID, не пересекающийся с данными прошлых запусков в общей базе
+func NewUserID() int64 {
+	for {
+		last := lastUserID.Load()
+		next := time.Now().UnixNano()
+		if next <= last {
+			next = last + 1
+		}
+		if lastUserID.CompareAndSwap(last, next) {
+			return next
+		}
+	}
+}
+
+func testStateDefault(t *testing.T, s database.Storage) {
+	userID := NewUserID()
+
+	state, err := s.GetUserState(userID)
+	if err != nil {
+		t.Fatalf("GetUserState: %v", err)
+	}
+	if state.UserID != userID || state.CurrentState != models.StateMain {
+		t.Fatalf("ожидалось начальное состояние %q, получено %+v", models.StateMain, state)
+	}
+}
+
+func testStateRoundTrip(t *testing.T, s database.Storage) {
+	userID := NewUserID()
+	want := &models.UserState{
+		UserID:        userID,
+		CurrentState:  models.StateSettingsGoal,
+		LastMessageID: 42,
+		InputData:     "гречка, курица",
+		StateHistory:  []string{models.StateMain, models.StateSettings},
+	}
+
+	if err := s.SaveUserState(want); err != nil {
+		t.Fatalf("SaveUserState: %v", err)
+	}
+	got, err := s.GetUserState(userID)
+	if err != nil {
+		t.Fatalf("GetUserState: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("состояние не совпадает:\nполучено  %+v\nожидалось %+v", got, want)
+	}
+
+	// Повторное сохранение перезаписывает запись
+	want.CurrentState = models.StateSettings
+	want.StateHistory = []string{models.StateMain}
+	if err := s.SaveUserState(want); err != nil {
+		t.Fatalf("SaveUserState: %v", err)
+	}
+	got, err = s.GetUserState(userID)
+	if err != nil {
+		t.Fatalf("GetUserState: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("состояние не обновилось:\nполучено  %+v\nожидалось %+v", got, want)
+	}
+}
+
+func testPreferencesDefault(t *testing.T, s database.Storage) {
+	userID := NewUserID()
+
+	prefs, err := s.GetUserPreferences(userID)
+	if err != nil {
+		t.Fatalf("GetUserPreferences: %v", err)
+	}
+	if want := (&models.UserPreferences{UserID: userID}); !reflect.DeepEqual(prefs, want) {
+		t.Fatalf("ожидались пустые предпочтения, получено %+v", prefs)
+	}
+}
+
+func testPreferencesRoundTrip(t *testing.T, s database.Storage) {
+	userID := NewUserID()
+	want := &models.UserPreferences{
+		UserID:      userID,
+		DietaryType: "Похудение",
+		Goal:        "минус 3 кг",
+		Allergies:   "орехи",
+		Likes:       "гречка",
+		Dislikes:    "лук",
+	}
+
+	if err := s.SaveUserPreferences(want); err != nil {
+		t.Fatalf("SaveUserPreferences: %v", err)
+	}
+	got, err := s.GetUserPreferences(userID)
+	if err != nil {
+		t.Fatalf("GetUserPreferences: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("предпочтения не совпадают:\nполучено  %+v\nожидалось %+v", got, want)
+	}
+}
+
+func testPreferencesPrefix(t *testing.T, s database.Storage) {
+	userID := NewUserID()
+	// Текст пользователя, похожий на шифротекст, должен читаться так, как был введён
+	want := &models.UserPreferences{
+		UserID:    userID,
+		Goal:      "enc:похудеть",
+		Allergies: "raw:enc:орехи",
+		Likes:     "raw:гречка",
+	}
+
+	if err := s.SaveUserPreferences(want); err != nil {
+		t.Fatalf("SaveUserPreferences: %v", err)
+	}
+	got, err := s.GetUserPreferences(userID)
+	if err != nil {
+		t.Fatalf("GetUserPreferences: %v", err)
+	}
+	if got.Goal != want.Goal || got.Allergies != want.Allergies || got.Likes != want.Likes {
+		t.Fatalf("предпочтения не совпадают:\nполучено  %+v\nожидалось %+v", got, want)
+	}
+}
+
+func testPreferencesClear(t *testing.T, s database.Storage) {
+	userID := NewUserID()
+	empty := &models.UserPreferences{UserID: userID}
+
+	// Сброс без сохранённых предпочтений не должен падать
+	if err := s.ClearUserPreferences(userID); err != nil {
+		t.Fatalf("ClearUserPreferences без записи: %v", err)
+	}
+
+	prefs := &models.UserPreferences{UserID: userID, DietaryType: "Обычное", Allergies: "молоко"}
+	if err := s.SaveUserPreferences(prefs); err != nil {
+		t.Fatalf("SaveUserPreferences: %v", err)
+	}
+	if err := s.ClearUserPreferences(userID); err != nil {
+		t.Fatalf("ClearUserPreferences: %v", err)
+	}
+
+	got, err := s.GetUserPreferences(userID)
+	if err != nil {
+		t.Fatalf("GetUserPreferences: %v", err)
+	}
+	if !reflect.DeepEqual(got, empty) {
+		t.Fatalf("после сброса ожидались пустые предпочтения, получено %+v", got)
+	}
+}
+
+func testAllergens(t *testing.T, s database.Storage) {
+	userID := NewUserID()
+
+	// Все коды справочника должны проходить ограничение внешнего ключа
+	var all []string
+	for _, a := range models.Allergens {
+		if err := s.SetAllergen(userID, a.Code, true); err != nil {
+			t.Fatalf("SetAllergen(%s): %v", a.Code, err)
+		}
+		all = append(all, a.Code)
+	}
+	sort.Strings(all)
+
+	// Повторная отметка и снятие отсутствующей отметки не должны падать
+	if err := s.SetAllergen(userID, models.Allergens[0].Code, true); err != nil {
+		t.Fatalf("повторный SetAllergen: %v", err)
+	}
+	if err := s.SetAllergen(NewUserID(), models.Allergens[0].Code, false); err != nil {
+		t.Fatalf("снятие отсутствующей отметки: %v", err)
+	}
+
+	// Сохранение предпочтений не трогает аллергены
+	if err := s.SaveUserPreferences(&models.UserPreferences{UserID: userID, Allergies: "киви"}); err != nil {
+		t.Fatalf("SaveUserPreferences: %v", err)
+	}
+
+	got, err := s.GetUserPreferences(userID)
+	if err != nil {
+		t.Fatalf("GetUserPreferences: %v", err)
+	}
+	if !reflect.DeepEqual(got.Allergens, all) || got.Allergies != "киви" {
+		t.Fatalf("аллергены = %v (%q), ожидалось %v (\"киви\")", got.Allergens, got.Allergies, all)
+	}
+
+	if err := s.SetAllergen(userID, all[0], false); err != nil {
+		t.Fatalf("SetAllergen(false): %v", err)
+	}
+	if got, _ := s.GetUserPreferences(userID); !reflect.DeepEqual(got.Allergens, all[1:]) {
+		t.Fatalf("после снятия отметки аллергены = %v, ожидалось %v", got.Allergens, all[1:])
+	}
+
+	if err := s.ClearUserPreferences(userID); err != nil {
+		t.Fatalf("ClearUserPreferences: %v", err)
+	}
+	if got, _ := s.GetUserPreferences(userID); len(got.Allergens) != 0 {
+		t.Fatalf("после сброса остались аллергены: %v", got.Allergens)
+	}
+}
+
+func testDietRestrictions(t *testing.T, s database.Storage) {
+	userID := NewUserID()
+
+	// Ограничения сочетаются, и все коды справочника проходят ограничение внешнего ключа
+	var all []string
+	for _, r := range models.DietRestrictions {
+		if err := s.SetDietRestriction(userID, r.Code, true); err != nil {
+			t.Fatalf("SetDietRestriction(%s): %v", r.Code, err)
+		}
+		all = append(all, r.Code)
+	}
+	sort.Strings(all)
+
+	if err := s.SetDietRestriction(userID, all[0], true); err != nil {
+		t.Fatalf("повторный SetDietRestriction: %v", err)
+	}
+	// Цель по весу хранится отдельно и не сбрасывает ограничения
+	if err := s.SaveUserPreferences(&models.UserPreferences{UserID: userID, DietaryType: "Похудение"}); err != nil {
+		t.Fatalf("SaveUserPreferences: %v", err)
+	}
+
+	got, err := s.GetUserPreferences(userID)
+	if err != nil {
+		t.Fatalf("GetUserPreferences: %v", err)
+	}
+	if !reflect.DeepEqual(got.DietRestrictions, all) || got.DietaryType != "Похудение" || len(got.Allergens) != 0 {
+		t.Fatalf("ограничения = %v, тип = %q, аллергены = %v; ожидалось %v", got.DietRestrictions, got.DietaryType, got.Allergens, all)
+	}
+
+	if err := s.SetDietRestriction(userID, all[0], false); err != nil {
+		t.Fatalf("SetDietRestriction(false): %v", err)
+	}
+	if got, _ := s.GetUserPreferences(userID); !reflect.DeepEqual(got.DietRestrictions, all[1:]) {
+		t.Fatalf("после снятия выбора ограничения = %v, ожидалось %v", got.DietRestrictions, all[1:])
+	}
+
+	if err := s.ClearUserPreferences(userID); err != nil {
+		t.Fatalf("ClearUserPreferences: %v", err)
+	}
+	if got, _ := s.GetUserPreferences(userID); len(got.DietRestrictions) != 0 {
+		t.Fatalf("после сброса остались ограничения: %v", got.DietRestrictions)
+	}
+}
+
+func testBodyProfile(t *testing.T, s database.Storage) {
+	userID := NewUserID()
+
+	if p, err := s.GetBodyProfile(userID); err != nil || p != nil {
+		t.Fatalf("GetBodyProfile без записи = %+v, %v; ожидалось nil", p, err)
+	}
+
+	// Мастер сохраняет профиль по шагам
+	partial := &models.BodyProfile{UserID: userID, Sex: models.SexFemale, Age: 30}
+	if err := s.SaveBodyProfile(partial); err != nil {
+		t.Fatalf("SaveBodyProfile: %v", err)
+	}
+	got, err := s.GetBodyProfile(userID)
+	if err != nil || got == nil || got.Complete() {
+		t.Fatalf("неполный профиль = %+v, %v", got, err)
+	}
+
+	want := &models.BodyProfile{UserID: userID, Sex: models.SexFemale, Age: 30, HeightCm: 165.5, WeightKg: 62.3, Activity: "moderate"}
+	if err := s.SaveBodyProfile(want); err != nil {
+		t.Fatalf("SaveBodyProfile: %v", err)
+	}
+
+	prefs, err := s.GetUserPreferences(userID)
+	if err != nil {
+		t.Fatalf("GetUserPreferences: %v", err)
+	}
+	if prefs.Body == nil || prefs.Body.UpdatedAt.IsZero() {
+		t.Fatalf("параметры тела не загружены в предпочтения: %+v", prefs.Body)
+	}
+	got = prefs.Body
+	got.UpdatedAt = time.Time{}
+	if !reflect.DeepEqual(got, want) || !got.Complete() {
+		t.Fatalf("параметры тела не совпадают:\nполучено  %+v\nожидалось %+v", got, want)
+	}
+
+	if err := s.ClearUserPreferences(userID); err != nil {
+		t.Fatalf("ClearUserPreferences: %v", err)
+	}
+	if p, _ := s.GetBodyProfile(userID); p != nil {
+		t.Fatalf("после сброса остались параметры тела: %+v", p)
+	}
+}
+
+func testRateLimit(t *testing.T, s database.Storage) {
+	userID := NewUserID()
+	limit := models.RateLimit{PerMinute: 2, Burst: 3}
+
+	for i := 0; i < limit.Burst; i++ {
+		res, err := s.CheckRateLimit(userID, limit)
+		if err != nil {
+			t.Fatalf("CheckRateLimit #%d: %v", i+1, err)
+		}
+		if !res.Allowed {
+			t.Fatalf("запрос #%d должен быть разрешён", i+1)
+		}
+	}
+
+	res, err := s.CheckRateLimit(userID, limit)
+	if err != nil {
+		t.Fatalf("CheckRateLimit: %v", err)
+	}
+	if res.Allowed {
+		t.Fatalf("запрос сверх лимита должен быть отклонён")
+	}
+	if res.RetryAfter <= 0 || res.RetryAfter > limit.Interval() {
+		t.Fatalf("RetryAfter = %s, ожидалось в (0, %s]", res.RetryAfter, limit.Interval())
+	}
+
+	// Лимиты разных пользователей независимы
+	res, err = s.CheckRateLimit(NewUserID(), limit)
+	if err != nil {
+		t.Fatalf("CheckRateLimit: %v", err)
+	}
+	if !res.Allowed {
+		t.Fatalf("лимит другого пользователя не должен влиять")
+	}
+}
+
+func testRateLimitConcurrent(t *testing.T, s database.Storage) {
+	userID := NewUserID()
+	limit := models.RateLimit{PerMinute: 1, Burst: 5}
+
+	const workers = 20
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := s.CheckRateLimit(userID, limit)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if res.Allowed {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("CheckRateLimit: %v", err)
+	}
+	if got := int(allowed.Load()); got != limit.Burst {
+		t.Fatalf("параллельно разрешено %d запросов, ожидалось ровно %d", got, limit.Burst)
+	}
+}
+
+func testQuota(t *testing.T, s database.Storage) {
+	userID := NewUserID()
+	quota := models.Quota{Daily: 2, Monthly: 10}
+	now := time.Now()
+
+	first, usage, err := s.ReserveGeneration(userID, quota, now)
+	if err != nil || first == 0 {
+		t.Fatalf("первая генерация должна пройти: id=%d, err=%v", first, err)
+	}
+	if usage.UsedToday != 1 || usage.RemainingToday() != 1 {
+		t.Fatalf("после первой генерации: %+v", usage)
+	}
+
+	// Неудачная генерация не расходует квоту
+	if err := s.FinishGeneration(first, errors.New("timeout")); err != nil {
+		t.Fatalf("FinishGeneration: %v", err)
+	}
+
+	for i := 0; i < quota.Daily; i++ {
+		id, _, err := s.ReserveGeneration(userID, quota, now)
+		if err != nil || id == 0 {
+			t.Fatalf("генерация #%d должна пройти: id=%d, err=%v", i+1, id, err)
+		}
+		if err := s.FinishGeneration(id, nil); err != nil {
+			t.Fatalf("FinishGeneration: %v", err)
+		}
+	}
+
+	id, usage, err := s.ReserveGeneration(userID, quota, now)
+	if err != nil {
+		t.Fatalf("ReserveGeneration: %v", err)
+	}
+	if id != 0 || usage.RemainingToday() != 0 {
+		t.Fatalf("дневная квота должна быть исчерпана: id=%d, usage=%+v", id, usage)
+	}
+
+	// На следующие сутки дневная квота восстанавливается, месячная продолжает считаться
+	dayStart, _ := models.QuotaPeriods(now)
+	tomorrow := dayStart.AddDate(0, 0, 1)
+	if tomorrow.Month() == now.Month() {
+		usage, err := s.GetQuotaUsage(userID, quota, tomorrow)
+		if err != nil {
+			t.Fatalf("GetQuotaUsage: %v", err)
+		}
+		if usage.UsedToday != 0 || usage.UsedMonth != quota.Daily {
+			t.Fatalf("на следующий день ожидалось 0/%d, получено %+v", quota.Daily, usage)
+		}
+	}
+
+	// QuotaUnlimited — без ограничения, нулевая квота запрещает генерации
+	id, _, err = s.ReserveGeneration(userID, models.UnlimitedQuota, now)
+	if err != nil || id == 0 {
+		t.Fatalf("без квоты генерация должна пройти: id=%d, err=%v", id, err)
+	}
+	id, _, err = s.ReserveGeneration(NewUserID(), models.Quota{Daily: 0, Monthly: models.QuotaUnlimited}, now)
+	if err != nil || id != 0 {
+		t.Fatalf("при нулевой квоте генерация должна быть запрещена: id=%d, err=%v", id, err)
+	}
+}
+
+func testQuotaConcurrent(t *testing.T, s database.Storage) {
+	userID := NewUserID()
+	quota := models.Quota{Daily: 3, Monthly: models.QuotaUnlimited}
+	now := time.Now()
+
+	var reserved atomic.Int64
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id, _, err := s.ReserveGeneration(userID, quota, now)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if id != 0 {
+				reserved.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("ReserveGeneration: %v", err)
+	}
+	if got := int(reserved.Load()); got != quota.Daily {
+		t.Fatalf("параллельно зарезервировано %d генераций, ожидалось ровно %d", got, quota.Daily)
+	}
+}
+
+func testQuotaOverride(t *testing.T, s database.Storage) {
+	userID := NewUserID()
+
+	override, err := s.GetUserQuota(userID)
+	if err != nil || override != nil {
+		t.Fatalf("ожидалось отсутствие квоты: %+v, %v", override, err)
+	}
+
+	daily := 100
+	if err := s.SetUserQuota(&models.QuotaOverride{UserID: userID, Daily: &daily}); err != nil {
+		t.Fatalf("SetUserQuota: %v", err)
+	}
+	override, err = s.GetUserQuota(userID)
+	if err != nil {
+		t.Fatalf("GetUserQuota: %v", err)
+	}
+	got := override.Apply(models.Quota{Daily: 5, Monthly: 50})
+	if got != (models.Quota{Daily: 100, Monthly: 50}) {
+		t.Fatalf("квота применена неверно: %+v", got)
+	}
+
+	if err := s.SetUserQuota(&models.QuotaOverride{UserID: userID}); err != nil {
+		t.Fatalf("SetUserQuota: %v", err)
+	}
+	if override, err := s.GetUserQuota(userID); err != nil || override != nil {
+		t.Fatalf("квота должна быть снята: %+v, %v", override, err)
+	}
+}
+
+func testUsers(t *testing.T, s database.Storage) {
+	userID := NewUserID()
+
+	if user, err := s.GetUser(userID); err != nil || user != nil {
+		t.Fatalf("неизвестный пользователь: %+v, %v", user, err)
+	}
+
+	first := time.UnixMilli(time.Now().UnixMilli())
+	profile := &models.User{ID: userID, Username: "cook", FirstName: "Анна", LanguageCode: "ru"}
+	if err := s.UpsertUser(profile, first); err != nil {
+		t.Fatalf("UpsertUser: %v", err)
+	}
+
+	// Блокировка бота идемпотентна
+	for i := 0; i < 2; i++ {
+		if err := s.MarkUserInactive(userID, "Forbidden: bot was blocked by the user"); err != nil {
+			t.Fatalf("MarkUserInactive: %v", err)
+		}
+	}
+	if user, err := s.GetUser(userID); err != nil || user == nil || !user.IsBlocked {
+		t.Fatalf("пользователь должен быть отмечен заблокировавшим бота: %+v, %v", user, err)
+	}
+
+	// Новое обновление от пользователя обновляет профиль и снимает блокировку,
+	// а время первого визита сохраняется
+	last := first.Add(time.Hour)
+	profile.Username = "chef"
+	if err := s.UpsertUser(profile, last); err != nil {
+		t.Fatalf("UpsertUser: %v", err)
+	}
+
+	user, err := s.GetUser(userID)
+	if err != nil || user == nil {
+		t.Fatalf("GetUser: %+v, %v", user, err)
+	}
+	if user.IsBlocked || user.Username != "chef" || user.FirstName != "Анна" || user.LanguageCode != "ru" {
+		t.Fatalf("профиль обновлён неверно: %+v", user)
+	}
+	if !user.FirstSeen.Equal(first) || !user.LastSeen.Equal(last) {
+		t.Fatalf("время визитов: first=%v last=%v, ожидалось %v и %v", user.FirstSeen, user.LastSeen, first, last)
+	}
+
+	// Бан по ID пользователя, который ещё не писал боту
+	strangerID := NewUserID()
+	if err := s.BanUser(strangerID, userID, "спам"); err != nil {
+		t.Fatalf("BanUser: %v", err)
+	}
+	stranger, err := s.GetUser(strangerID)
+	if err != nil || stranger == nil || !stranger.IsBanned || !stranger.FirstSeen.IsZero() {
+		t.Fatalf("забаненный заранее пользователь: %+v, %v", stranger, err)
+	}
+}
+
+func testBans(t *testing.T, s database.Storage) {
+	userID, adminID := NewUserID(), NewUserID()
+	if err := s.UpsertUser(&models.User{ID: userID}, time.Now()); err != nil {
+		t.Fatalf("UpsertUser: %v", err)
+	}
+
+	if banned, err := s.IsBanned(userID); err != nil || banned {
+		t.Fatalf("новый пользователь не должен быть забанен: %v, %v", banned, err)
+	}
+	if !containsID(t, s, userID) {
+		t.Fatalf("пользователь должен быть в списке получателей")
+	}
+
+	if err := s.BanUser(userID, adminID, "спам"); err != nil {
+		t.Fatalf("BanUser: %v", err)
+	}
+	if banned, err := s.IsBanned(userID); err != nil || !banned {
+		t.Fatalf("пользователь должен быть забанен: %v, %v", banned, err)
+	}
+	if containsID(t, s, userID) {
+		t.Fatalf("забаненный пользователь не должен получать рассылки")
+	}
+
+	if err := s.UnbanUser(userID); err != nil {
+		t.Fatalf("UnbanUser: %v", err)
+	}
+	if banned, err := s.IsBanned(userID); err != nil || banned {
+		t.Fatalf("бан должен быть снят: %v, %v", banned, err)
+	}
+
+	if err := s.MarkUserInactive(userID, "blocked"); err != nil {
+		t.Fatalf("MarkUserInactive: %v", err)
+	}
+	if containsID(t, s, userID) {
+		t.Fatalf("заблокировавший бота пользователь не должен получать рассылки")
+	}
+}
+
+func containsID(t *testing.T, s database.Storage, userID int64) bool {
+	t.Helper()
+
+	ids, err := s.ListBroadcastRecipients(models.RecipientFilter{}, userID-1, 1)
+	if err != nil {
+		t.Fatalf("ListBroadcastRecipients: %v", err)
+	}
+	return len(ids) == 1 && ids[0] == userID
+}
+
+func testBroadcastRecipients(t *testing.T, s database.Storage) {
+	lose, gain, idle := NewUserID(), NewUserID(), NewUserID()
+	for _, userID := range []int64{lose, gain, idle} {
+		if err := s.UpsertUser(&models.User{ID: userID}, time.Now()); err != nil {
+			t.Fatalf("UpsertUser: %v", err)
+		}
+	}
+	if err := s.SaveUserPreferences(&models.UserPreferences{UserID: lose, DietaryType: "Похудение"}); err != nil {
+		t.Fatalf("SaveUserPreferences: %v", err)
+	}
+	if err := s.SaveUserPreferences(&models.UserPreferences{UserID: gain, DietaryType: "Набор массы"}); err != nil {
+		t.Fatalf("SaveUserPreferences: %v", err)
+	}
+	now := time.Now()
+	if _, _, err := s.ReserveGeneration(gain, models.UnlimitedQuota, now); err != nil {
+		t.Fatalf("ReserveGeneration: %v", err)
+	}
+
+	list := func(filter models.RecipientFilter, after int64, limit int) []int64 {
+		t.Helper()
+		ids, err := s.ListBroadcastRecipients(filter, after, limit)
+		if err != nil {
+			t.Fatalf("ListBroadcastRecipients: %v", err)
+		}
+		return ids
+	}
+
+	// Постраничный обход по возрастанию ID
+	if got := list(models.RecipientFilter{}, lose-1, 2); !reflect.DeepEqual(got, []int64{lose, gain}) {
+		t.Fatalf("первая страница: %v", got)
+	}
+	if got := list(models.RecipientFilter{}, gain, 1); !reflect.DeepEqual(got, []int64{idle}) {
+		t.Fatalf("вторая страница: %v", got)
+	}
+
+	if got := list(models.RecipientFilter{DietaryType: "Похудение"}, lose-1, 10); !reflect.DeepEqual(got, []int64{lose}) {
+		t.Fatalf("фильтр по типу питания: %v", got)
+	}
+	active := models.RecipientFilter{ActiveSince: now.Add(-time.Hour)}
+	if got := list(active, lose-1, 10); !reflect.DeepEqual(got, []int64{gain}) {
+		t.Fatalf("фильтр по активности: %v", got)
+	}
+}
+
+func testBroadcasts(t *testing.T, s database.Storage) {
+	now := time.Now()
+
+	// Незавершённые рассылки прошлых запусков в общей базе забираем заранее
+	for {
+		b, err := s.ClaimBroadcast(now, time.Minute)
+		if err != nil {
+			t.Fatalf("ClaimBroadcast: %v", err)
+		}
+		if b == nil {
+			break
+		}
+		if _, err := s.CancelBroadcast(b.ID, now); err != nil {
+			t.Fatalf("CancelBroadcast: %v", err)
+		}
+	}
+
+	id, err := s.CreateBroadcast(&models.Broadcast{
+		AdminID:     NewUserID(),
+		AdminChatID: 1,
+		Text:        "Новые рецепты!",
+		Buttons:     []models.BroadcastButton{{Text: "Сайт", URL: "https://example.com"}},
+		Segment:     "all",
+		CreatedAt:   now,
+	})
+	if err != nil {
+		t.Fatalf("CreateBroadcast: %v", err)
+	}
+
+	b, err := s.ClaimBroadcast(now, time.Minute)
+	if err != nil || b == nil || b.ID != id {
+		t.Fatalf("ClaimBroadcast: %+v, %v", b, err)
+	}
+	if b.Status != models.BroadcastRunning || len(b.Buttons) != 1 || b.Buttons[0].URL != "https://example.com" {
+		t.Fatalf("рассылка прочитана неверно: %+v", b)
+	}
+
+	// Пока аренда действует, вторая реплика рассылку не получит
+	if other, err := s.ClaimBroadcast(now.Add(time.Second), time.Minute); err != nil || other != nil {
+		t.Fatalf("рассылка выдана дважды: %+v, %v", other, err)
+	}
+
+	b.LastUserID, b.Delivered, b.Blocked = 42, 3, 1
+	if ok, err := s.SaveBroadcastProgress(b, now.Add(time.Minute)); err != nil || !ok {
+		t.Fatalf("SaveBroadcastProgress: %v, %v", ok, err)
+	}
+
+	// После истечения аренды рассылка продолжается с сохранённого места
+	resumed, err := s.ClaimBroadcast(now.Add(2*time.Minute), time.Minute)
+	if err != nil || resumed == nil || resumed.ID != id {
+		t.Fatalf("рассылка не подхвачена после истечения аренды: %+v, %v", resumed, err)
+	}
+	if resumed.LastUserID != 42 || resumed.Delivered != 3 || resumed.Blocked != 1 {
+		t.Fatalf("прогресс потерян: %+v", resumed)
+	}
+
+	if ok, err := s.CancelBroadcast(id, now); err != nil || !ok {
+		t.Fatalf("CancelBroadcast: %v, %v", ok, err)
+	}
+	if ok, err := s.SaveBroadcastProgress(resumed, now.Add(3*time.Minute)); err != nil || ok {
+		t.Fatalf("отменённая рассылка не должна продолжаться: %v, %v", ok, err)
+	}
+
+	got, err := s.GetBroadcast(id)
+	if err != nil || got == nil || got.Status != models.BroadcastCancelled {
+		t.Fatalf("GetBroadcast: %+v, %v", got, err)
+	}
+}
+
+func testRecipes(t *testing.T, s database.Storage) {
+	userID, otherID := NewUserID(), NewUserID()
+	now := time.Now().Truncate(time.Millisecond)
+
+	var ids []int64
+	for i := 1; i <= 5; i++ {
+		id, err := s.SaveRecipe(&models.Recipe{
+			UserID:        userID,
+			Request:       fmt.Sprintf("запрос %d", i),
+			Refinement:    "Сделай блюдо острее.",
+			Title:         fmt.Sprintf("Блюдо %d", i),
+			Text:          "*1. Блюдо*",
+			Preferences:   &models.UserPreferences{UserID: userID, Allergies: "орехи"},
+			Model:         "GigaChat",
+			PromptVersion: "1",
+			CreatedAt:     now,
+		})
+		if err != nil {
+			t.Fatalf("SaveRecipe: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	got, err := s.GetRecipe(userID, ids[0])
+	if err != nil || got == nil {
+		t.Fatalf("GetRecipe = %+v, %v", got, err)
+	}
+	if got.Request != "запрос 1" || got.Refinement != "Сделай блюдо острее." || got.Model != "GigaChat" || got.PromptVersion != "1" || !got.CreatedAt.Equal(now) {
+		t.Fatalf("рецепт сохранён неверно: %+v", got)
+	}
+	if got.Preferences == nil || got.Preferences.Allergies != "орехи" {
+		t.Fatalf("снимок предпочтений не сохранён: %+v", got.Preferences)
+	}
+
+	// Чужой рецепт недоступен
+	if r, _ := s.GetRecipe(otherID, ids[0]); r != nil {
+		t.Fatalf("получен чужой рецепт: %+v", r)
+	}
+	if ok, _ := s.SetRecipeFavorite(otherID, ids[0], true); ok {
+		t.Fatalf("чужой рецепт добавлен в избранное")
+	}
+	if ok, _ := s.DeleteRecipe(otherID, ids[0]); ok {
+		t.Fatalf("удалён чужой рецепт")
+	}
+
+	// Страницы идут от новых к старым
+	page, total, err := s.ListRecipes(userID, false, 2, 2)
+	if err != nil {
+		t.Fatalf("ListRecipes: %v", err)
+	}
+	if total != 5 || len(page) != 2 || page[0].ID != ids[2] || page[1].ID != ids[1] {
+		t.Fatalf("страница истории неверна: total=%d, %+v", total, page)
+	}
+
+	for _, id := range []int64{ids[1], ids[3]} {
+		if ok, err := s.SetRecipeFavorite(userID, id, true); err != nil || !ok {
+			t.Fatalf("SetRecipeFavorite = %v, %v", ok, err)
+		}
+	}
+	favs, total, err := s.ListRecipes(userID, true, 0, 10)
+	if err != nil || total != 2 || len(favs) != 2 || favs[0].ID != ids[3] || !favs[0].IsFavorite {
+		t.Fatalf("избранное неверно: total=%d, %+v, %v", total, favs, err)
+	}
+
+	if ok, err := s.DeleteRecipe(userID, ids[3]); err != nil || !ok {
+		t.Fatalf("DeleteRecipe = %v, %v", ok, err)
+	}
+	if ok, _ := s.DeleteRecipe(userID, ids[3]); ok {
+		t.Fatalf("повторное удаление должно вернуть false")
+	}
+	if _, total, _ := s.ListRecipes(userID, true, 0, 10); total != 1 {
+		t.Fatalf("после удаления в избранном %d рецептов, ожидался 1", total)
+	}
+	if page, total, _ := s.ListRecipes(userID, false, 10, 5); total != 4 || len(page) != 0 {
+		t.Fatalf("страница за пределами истории: total=%d, %+v", total, page)
+	}
+}
+
+func testFeedback(t *testing.T, s database.Storage) {
+	userID, otherID := NewUserID(), NewUserID()
+	now := time.Now().Truncate(time.Millisecond)
+	// Отчёт общий для всех пользователей, поэтому берём уникальный тип питания
+	diet := fmt.Sprintf("диета %d", userID)
+
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		id, err := s.SaveRecipe(&models.Recipe{UserID: userID, Request: "ужин", CreatedAt: now})
+		if err != nil {
+			t.Fatalf("SaveRecipe: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if f, err := s.GetRecipeFeedback(userID, ids[0]); err != nil || f != nil {
+		t.Fatalf("оценка по умолчанию = %+v, %v", f, err)
+	}
+
+	feedback := []models.RecipeFeedback{
+		{RecipeID: ids[0], Rating: models.RatingLike},
+		{RecipeID: ids[1], Rating: models.RatingDislike, Reason: "too_long"},
+		{RecipeID: ids[2], Rating: models.RatingDislike, Reason: models.FeedbackReasonOther, Comment: "мало соли"},
+	}
+	for _, f := range feedback {
+		f.UserID, f.DietType, f.PromptVersion, f.Model, f.CreatedAt = userID, diet, "4", "GigaChat", now
+		if err := s.SaveRecipeFeedback(&f); err != nil {
+			t.Fatalf("SaveRecipeFeedback: %v", err)
+		}
+	}
+
+	got, err := s.GetRecipeFeedback(userID, ids[2])
+	if err != nil || got == nil {
+		t.Fatalf("GetRecipeFeedback = %+v, %v", got, err)
+	}
+	if got.Rating != models.RatingDislike || got.Comment != "мало соли" || got.DietType != diet || !got.CreatedAt.Equal(now) {
+		t.Fatalf("оценка сохранена неверно: %+v", got)
+	}
+	if f, _ := s.GetRecipeFeedback(otherID, ids[2]); f != nil {
+		t.Fatalf("получена чужая оценка: %+v", f)
+	}
+
+	// Повторная оценка заменяет предыдущую
+	if err := s.SaveRecipeFeedback(&models.RecipeFeedback{
+		RecipeID: ids[1], UserID: userID, Rating: models.RatingDislike, Reason: "expensive",
+		DietType: diet, PromptVersion: "4", Model: "GigaChat", CreatedAt: now,
+	}); err != nil {
+		t.Fatalf("SaveRecipeFeedback: %v", err)
+	}
+
+	stats, reasons, err := s.FeedbackReport(now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("FeedbackReport: %v", err)
+	}
+	var found *models.FeedbackStat
+	for i := range stats {
+		if stats[i].DietType == diet {
+			found = &stats[i]
+		}
+	}
+	if found == nil || found.Likes != 1 || found.Dislikes != 2 || found.PromptVersion != "4" || found.Model != "GigaChat" {
+		t.Fatalf("статистика оценок неверна: %+v", stats)
+	}
+	counts := make(map[string]int)
+	for _, r := range reasons {
+		counts[r.Reason] = r.Count
+	}
+	if counts["expensive"] < 1 || counts[models.FeedbackReasonOther] < 1 {
+		t.Fatalf("причины не учтены: %+v", reasons)
+	}
+
+	// Оценки до начала периода не попадают в отчёт
+	stats, _, err = s.FeedbackReport(now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("FeedbackReport: %v", err)
+	}
+	for _, st := range stats {
+		if st.DietType == diet {
+			t.Fatalf("в отчёт попали старые оценки: %+v", st)
+		}
+	}
+}
+
+func testShopping(t *testing.T, s database.Storage) {
+	userID, otherID := NewUserID(), NewUserID()
+	now := time.Now().Truncate(time.Millisecond)
+
+	if items, err := s.ListShoppingItems(userID); err != nil || len(items) != 0 {
+		t.Fatalf("список по умолчанию = %+v, %v", items, err)
+	}
+
+	err := s.AddShoppingItems(userID, []models.ShoppingItem{
+		{Name: "Рис", Amount: 200, Unit: "г", Section: "grocery", CreatedAt: now},
+		{Name: "Яйца", Amount: 2, Unit: "шт", Section: "dairy", CreatedAt: now},
+		{Name: "Соль", Section: "pantry", CreatedAt: now},
+	})
+	if err != nil {
+		t.Fatalf("AddShoppingItems: %v", err)
+	}
+	// Тот же продукт в другом регистре и падеже складывается, в другой единице — нет
+	err = s.AddShoppingItems(userID, []models.ShoppingItem{
+		{Name: "риса", Amount: 150, Unit: "г", Section: "grocery", CreatedAt: now},
+		{Name: "Рис", Amount: 1, Unit: "пачка", Section: "grocery", CreatedAt: now},
+	})
+	if err != nil {
+		t.Fatalf("AddShoppingItems: %v", err)
+	}
+
+	items, err := s.ListShoppingItems(userID)
+	if err != nil {
+		t.Fatalf("ListShoppingItems: %v", err)
+	}
+	if len(items) != 4 || items[0].Name != "Рис" || items[0].Amount != 350 || items[0].Section != "grocery" ||
+		!items[0].CreatedAt.Equal(now) || items[3].Unit != "пачка" {
+		t.Fatalf("список покупок неверен: %+v", items)
+	}
+
+	if ok, _ := s.SetShoppingItemChecked(otherID, items[0].ID, true); ok {
+		t.Fatalf("отмечен чужой продукт")
+	}
+	for _, item := range items[:2] {
+		if ok, err := s.SetShoppingItemChecked(userID, item.ID, true); err != nil || !ok {
+			t.Fatalf("SetShoppingItemChecked = %v, %v", ok, err)
+		}
+	}
+
+	// Купленный продукт при повторном добавлении начинается заново
+	if err := s.AddShoppingItems(userID, []models.ShoppingItem{{Name: "Рис", Amount: 100, Unit: "г", CreatedAt: now}}); err != nil {
+		t.Fatalf("AddShoppingItems: %v", err)
+	}
+	items, _ = s.ListShoppingItems(userID)
+	if items[0].Amount != 100 || items[0].Checked || !items[1].Checked {
+		t.Fatalf("купленный продукт добавлен неверно: %+v", items)
+	}
+
+	if err := s.ClearShoppingList(userID, true); err != nil {
+		t.Fatalf("ClearShoppingList: %v", err)
+	}
+	items, _ = s.ListShoppingItems(userID)
+	if len(items) != 3 || items[1].Name != "Соль" {
+		t.Fatalf("после удаления купленного: %+v", items)
+	}
+
+	if err := s.AddShoppingItems(otherID, []models.ShoppingItem{{Name: "Рис", CreatedAt: now}}); err != nil {
+		t.Fatalf("AddShoppingItems: %v", err)
+	}
+	if err := s.ClearShoppingList(userID, false); err != nil {
+		t.Fatalf("ClearShoppingList: %v", err)
+	}
+	if items, _ := s.ListShoppingItems(userID); len(items) != 0 {
+		t.Fatalf("список не очищен: %+v", items)
+	}
+	if items, _ := s.ListShoppingItems(otherID); len(items) != 1 {
+		t.Fatalf("очищен чужой список: %+v", items)
+	}
+}
+
+func testPantry(t *testing.T, s database.Storage) {
+	userID, otherID := NewUserID(), NewUserID()
+	now := time.Now().Truncate(time.Millisecond)
+
+	if items, err := s.ListPantryItems(userID); err != nil || len(items) != 0 {
+		t.Fatalf("запасы по умолчанию = %+v, %v", items, err)
+	}
+
+	err := s.AddPantryItems(userID, []models.PantryItem{
+		{Name: "Соль", CreatedAt: now},
+		{Name: "Молоко", Amount: 1000, Unit: "мл", Expires: "2026-01-10", CreatedAt: now},
+		{Name: "Яйца", Amount: 10, Unit: "шт", Expires: "2026-01-20", CreatedAt: now},
+	})
+	if err != nil {
+		t.Fatalf("AddPantryItems: %v", err)
+	}
+	// Тот же продукт складывается, срок годности остаётся более ранний
+	err = s.AddPantryItems(userID, []models.PantryItem{
+		{Name: "молоко", Amount: 500, Unit: "мл", Expires: "2026-01-05", CreatedAt: now},
+		{Name: "яйцо", Amount: 5, Unit: "шт", CreatedAt: now},
+	})
+	if err != nil {
+		t.Fatalf("AddPantryItems: %v", err)
+	}
+
+	items, err := s.ListPantryItems(userID)
+	if err != nil {
+		t.Fatalf("ListPantryItems: %v", err)
+	}
+	if len(items) != 3 || items[0].Name != "Молоко" || items[0].Amount != 1500 || items[0].Expires != "2026-01-05" ||
+		items[1].Amount != 15 || items[1].Expires != "2026-01-20" || items[2].Name != "Соль" || !items[0].CreatedAt.Equal(now) {
+		t.Fatalf("запасы неверны: %+v", items)
+	}
+
+	// Списание чужому пользователю не действует; закончившийся продукт удаляется
+	if err := s.DeductPantryItems(otherID, []models.PantryUse{{ItemID: items[1].ID, Amount: 15}}); err != nil {
+		t.Fatalf("DeductPantryItems: %v", err)
+	}
+	err = s.DeductPantryItems(userID, []models.PantryUse{{ItemID: items[0].ID, Amount: 200}, {ItemID: items[1].ID, Amount: 15}})
+	if err != nil {
+		t.Fatalf("DeductPantryItems: %v", err)
+	}
+	items, _ = s.ListPantryItems(userID)
+	if len(items) != 2 || items[0].Amount != 1300 || items[1].Name != "Соль" {
+		t.Fatalf("после списания: %+v", items)
+	}
+
+	if ok, _ := s.DeletePantryItem(otherID, items[1].ID); ok {
+		t.Fatalf("удалён чужой продукт")
+	}
+	if ok, err := s.DeletePantryItem(userID, items[1].ID); err != nil || !ok {
+		t.Fatalf("DeletePantryItem = %v, %v", ok, err)
+	}
+	if items, _ := s.ListPantryItems(userID); len(items) != 1 {
+		t.Fatalf("продукт не удалён: %+v", items)
+	}
+}
+
+func testPlan(t *testing.T, s database.Storage) {
+	userID, otherID := NewUserID(), NewUserID()
+	now := time.Now().Truncate(time.Millisecond)
+
+	if meals, err := s.GetPlanMeals(userID, "2026-01-01", "2026-01-31"); err != nil || len(meals) != 0 {
+		t.Fatalf("план по умолчанию = %+v, %v", meals, err)
+	}
+
+	err := s.SavePlanMeals(userID, []models.PlanMeal{
+		{Day: "2026-01-01", Slot: "lunch", Title: "Суп", Protein: "курица", Calories: 500, CreatedAt: now},
+		{Day: "2026-01-01", Slot: "breakfast", Title: "Омлет", Protein: "яйца", Calories: 400, CreatedAt: now},
+		{Day: "2026-01-02", Slot: "breakfast", Title: "Каша", Protein: models.ProteinNone, Calories: 350, CreatedAt: now},
+		{Day: "2026-01-03", Slot: "dinner", Title: "Рыба", Protein: "рыба", Calories: 450, CreatedAt: now},
+	})
+	if err != nil {
+		t.Fatalf("SavePlanMeals: %v", err)
+	}
+	if err := s.SavePlanMeals(otherID, []models.PlanMeal{{Day: "2026-01-01", Slot: "lunch", Title: "Плов", CreatedAt: now}}); err != nil {
+		t.Fatalf("SavePlanMeals: %v", err)
+	}
+
+	// Замена блюда перезаписывает ячейку
+	if err := s.SavePlanMeals(userID, []models.PlanMeal{{Day: "2026-01-01", Slot: "lunch", Title: "Борщ", Protein: "говядина", Calories: 550, CreatedAt: now}}); err != nil {
+		t.Fatalf("SavePlanMeals: %v", err)
+	}
+
+	meals, err := s.GetPlanMeals(userID, "2026-01-01", "2026-01-02")
+	if err != nil {
+		t.Fatalf("GetPlanMeals: %v", err)
+	}
+	if len(meals) != 3 || meals[0].Day != "2026-01-01" || meals[2].Day != "2026-01-02" {
+		t.Fatalf("план за период неверен: %+v", meals)
+	}
+	for _, m := range meals {
+		if m.Slot == "lunch" && (m.Title != "Борщ" || m.Protein != "говядина" || m.Calories != 550 || m.UserID != userID || !m.CreatedAt.Equal(now)) {
+			t.Fatalf("блюдо не заменено: %+v", m)
+		}
+	}
+}
+
+func testDiary(t *testing.T, s database.Storage) {
+	userID, otherID := NewUserID(), NewUserID()
+	now := time.Now().Truncate(time.Millisecond)
+
+	if entries, err := s.ListDiaryEntries(userID, "2026-01-01", "2026-01-31"); err != nil || len(entries) != 0 {
+		t.Fatalf("дневник по умолчанию = %+v, %v", entries, err)
+	}
+
+	add := func(userID int64, day, title string, n models.Nutrition) int64 {
+		id, err := s.AddDiaryEntry(&models.DiaryEntry{UserID: userID, Day: day, RecipeID: 7, Title: title, Nutrition: n, CreatedAt: now})
+		if err != nil || id == 0 {
+			t.Fatalf("AddDiaryEntry: %d, %v", id, err)
+		}
+		return id
+	}
+	add(userID, "2026-01-02", "Суп", models.Nutrition{Calories: 350, Protein: 20, Fat: 10.5, Carbs: 40})
+	omelet := add(userID, "2026-01-01", "Омлет", models.Nutrition{Calories: 400, Protein: 25, Fat: 28, Carbs: 5})
+	add(userID, "2026-01-05", "Рыба", models.Nutrition{Calories: 450})
+	add(otherID, "2026-01-01", "Плов", models.Nutrition{Calories: 600})
+
+	entries, err := s.ListDiaryEntries(userID, "2026-01-01", "2026-01-02")
+	if err != nil {
+		t.Fatalf("ListDiaryEntries: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Title != "Омлет" || entries[0].Nutrition.Fat != 28 || entries[0].RecipeID != 7 ||
+		entries[1].Day != "2026-01-02" || entries[1].Nutrition.Fat != 10.5 || !entries[1].CreatedAt.Equal(now) {
+		t.Fatalf("записи дневника неверны: %+v", entries)
+	}
+
+	if ok, _ := s.DeleteDiaryEntry(otherID, omelet); ok {
+		t.Fatalf("удалена чужая запись")
+	}
+	if ok, err := s.DeleteDiaryEntry(userID, omelet); err != nil || !ok {
+		t.Fatalf("DeleteDiaryEntry = %v, %v", ok, err)
+	}
+	if entries, _ := s.ListDiaryEntries(userID, "2026-01-01", "2026-01-31"); len(entries) != 2 || entries[0].Title != "Суп" {
+		t.Fatalf("запись не удалена: %+v", entries)
+	}
+}
+
+func testNotify(t *testing.T, s database.Storage) {
+	userID, otherID := NewUserID(), NewUserID()
+	now := time.Now().Truncate(time.Millisecond)
+
+	if settings, err := s.GetNotifySettings(userID); err != nil || settings != nil {
+		t.Fatalf("настройки по умолчанию = %+v, %v", settings, err)
+	}
+
+	// Наступившие задания прошлых запусков в общей базе забираем заранее
+	for {
+		job, err := s.ClaimNotifyJob(now, time.Hour)
+		if err != nil {
+			t.Fatalf("ClaimNotifyJob: %v", err)
+		}
+		if job == nil {
+			break
+		}
+	}
+
+	settings := models.DefaultNotifySettings(userID)
+	settings.Timezone = "Asia/Novosibirsk"
+	settings.Dinner, settings.DinnerAt = true, 19*60+30
+	err := s.SaveNotifySettings(settings, []models.NotifyJob{
+		{Kind: models.NotifyDinner, RunAt: now.Add(-time.Minute)},
+		{Kind: models.NotifyExpiry, RunAt: now.Add(-2 * time.Minute)},
+	})
+	if err != nil {
+		t.Fatalf("SaveNotifySettings: %v", err)
+	}
+	other := models.DefaultNotifySettings(otherID)
+	if err := s.SaveNotifySettings(other, []models.NotifyJob{{Kind: models.NotifyExpiry, RunAt: now.Add(time.Hour)}}); err != nil {
+		t.Fatalf("SaveNotifySettings: %v", err)
+	}
+
+	saved, err := s.GetNotifySettings(userID)
+	if err != nil || saved == nil || saved.Timezone != "Asia/Novosibirsk" || !saved.Dinner || saved.DinnerAt != 19*60+30 ||
+		!saved.Expiry || saved.Plan || saved.QuietStart != 23*60 || saved.QuietEnd != 8*60 {
+		t.Fatalf("настройки прочитаны неверно: %+v, %v", saved, err)
+	}
+
+	// Задания выдаются по времени отправки, каждое — один раз, пока действует аренда
+	expiry, err := s.ClaimNotifyJob(now, time.Minute)
+	if err != nil || expiry == nil || expiry.Kind != models.NotifyExpiry || expiry.UserID != userID || !expiry.RunAt.Equal(now.Add(-2*time.Minute)) {
+		t.Fatalf("ClaimNotifyJob: %+v, %v", expiry, err)
+	}
+	dinner, err := s.ClaimNotifyJob(now, time.Minute)
+	if err != nil || dinner == nil || dinner.Kind != models.NotifyDinner {
+		t.Fatalf("ClaimNotifyJob: %+v, %v", dinner, err)
+	}
+	if job, err := s.ClaimNotifyJob(now.Add(time.Second), time.Minute); err != nil || job != nil {
+		t.Fatalf("задание выдано дважды или раньше срока: %+v, %v", job, err)
+	}
+
+	// Перенесённое задание снова ждёт своего времени
+	if err := s.RescheduleNotifyJob(expiry.ID, now.Add(24*time.Hour)); err != nil {
+		t.Fatalf("RescheduleNotifyJob: %v", err)
+	}
+	if job, err := s.ClaimNotifyJob(now.Add(time.Second), time.Minute); err != nil || job != nil {
+		t.Fatalf("перенесённое задание выдано раньше срока: %+v, %v", job, err)
+	}
+
+	// Невыполненное задание подхватывается после истечения аренды
+	resumed, err := s.ClaimNotifyJob(now.Add(2*time.Minute), time.Minute)
+	if err != nil || resumed == nil || resumed.ID != dinner.ID {
+		t.Fatalf("задание не подхвачено после истечения аренды: %+v, %v", resumed, err)
+	}
+
+	jobs, err := s.ListNotifyJobs(userID)
+	if err != nil || len(jobs) != 2 || jobs[0].Kind != models.NotifyDinner || !jobs[1].RunAt.Equal(now.Add(24*time.Hour)) {
+		t.Fatalf("ListNotifyJobs: %+v, %v", jobs, err)
+	}
+
+	// Новые настройки заменяют задания; перенос удалённого задания ничего не делает
+	settings.Expiry = false
+	if err := s.SaveNotifySettings(settings, []models.NotifyJob{{Kind: models.NotifyDinner, RunAt: now.Add(time.Hour)}}); err != nil {
+		t.Fatalf("SaveNotifySettings: %v", err)
+	}
+	if err := s.RescheduleNotifyJob(expiry.ID, now); err != nil {
+		t.Fatalf("RescheduleNotifyJob: %v", err)
+	}
+	jobs, _ = s.ListNotifyJobs(userID)
+	if len(jobs) != 1 || jobs[0].Kind != models.NotifyDinner || !jobs[0].RunAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("задания не заменены: %+v", jobs)
+	}
+	if jobs, _ := s.ListNotifyJobs(otherID); len(jobs) != 1 {
+		t.Fatalf("задания другого пользователя затронуты: %+v", jobs)
+	}
+}
+
+func testUserDataKeepsBan(t *testing.T, s database.Storage) {
+	userID, adminID := NewUserID(), NewUserID()
+	now := time.Now()
+
+	if err := s.UpsertUser(&models.User{ID: userID, Username: "spammer", FirstName: "Спамер"}, now); err != nil {
+		t.Fatalf("UpsertUser: %v", err)
+	}
+	if err := s.BanUser(userID, adminID, "спам"); err != nil {
+		t.Fatalf("BanUser: %v", err)
+	}
+	if err := s.DeleteUserData(userID, now); err != nil {
+		t.Fatalf("DeleteUserData: %v", err)
+	}
+
+	if banned, err := s.IsBanned(userID); err != nil || !banned {
+		t.Fatalf("бан должен сохраниться после удаления данных: %v, %v", banned, err)
+	}
+	user, err := s.GetUser(userID)
+	if err != nil || user == nil {
+		t.Fatalf("GetUser: %+v, %v", user, err)
+	}
+	if user.Username != "" || user.FirstName != "" || !user.FirstSeen.IsZero() || user.BannedBy != adminID || user.BanReason != "спам" {
+		t.Fatalf("должен остаться только бан: %+v", user)
+	}
+}
+
+func testUserData(t *testing.T, s database.Storage) {
+	userID, otherID := NewUserID(), NewUserID()
+	now := time.Now()
+
+	for _, id := range []int64{userID, otherID} {
+		if err := s.UpsertUser(&models.User{ID: id, Username: "cook"}, now); err != nil {
+			t.Fatalf("UpsertUser: %v", err)
+		}
+		if err := s.SaveUserState(&models.UserState{UserID: id, CurrentState: models.StateSettings}); err != nil {
+			t.Fatalf("SaveUserState: %v", err)
+		}
+		if err := s.SaveUserPreferences(&models.UserPreferences{UserID: id, Allergies: "арахис"}); err != nil {
+			t.Fatalf("SaveUserPreferences: %v", err)
+		}
+		if err := s.SetAllergen(id, models.Allergens[0].Code, true); err != nil {
+			t.Fatalf("SetAllergen: %v", err)
+		}
+		if err := s.SetDietRestriction(id, models.DietRestrictions[0].Code, true); err != nil {
+			t.Fatalf("SetDietRestriction: %v", err)
+		}
+		if err := s.SaveBodyProfile(&models.BodyProfile{UserID: id, Age: 30}); err != nil {
+			t.Fatalf("SaveBodyProfile: %v", err)
+		}
+		daily := 3
+		if err := s.SetUserQuota(&models.QuotaOverride{UserID: id, Daily: &daily}); err != nil {
+			t.Fatalf("SetUserQuota: %v", err)
+		}
+		if _, err := s.CheckRateLimit(id, models.RateLimit{PerMinute: 5, Burst: 5}); err != nil {
+			t.Fatalf("CheckRateLimit: %v", err)
+		}
+		genID, _, err := s.ReserveGeneration(id, models.UnlimitedQuota, now)
+		if err != nil {
+			t.Fatalf("ReserveGeneration: %v", err)
+		}
+		if err := s.FinishGeneration(genID, errors.New("таймаут")); err != nil {
+			t.Fatalf("FinishGeneration: %v", err)
+		}
+		recipeID, err := s.SaveRecipe(&models.Recipe{UserID: id, Request: "ужин", Text: "*1. Плов*", CreatedAt: now})
+		if err != nil {
+			t.Fatalf("SaveRecipe: %v", err)
+		}
+		if err := s.SaveRecipeFeedback(&models.RecipeFeedback{RecipeID: recipeID, UserID: id, Rating: models.RatingLike, CreatedAt: now}); err != nil {
+			t.Fatalf("SaveRecipeFeedback: %v", err)
+		}
+		if err := s.AddShoppingItems(id, []models.ShoppingItem{{Name: "Рис", Amount: 200, Unit: "г", CreatedAt: now}}); err != nil {
+			t.Fatalf("AddShoppingItems: %v", err)
+		}
+		if err := s.AddPantryItems(id, []models.PantryItem{{Name: "Молоко", Expires: "2026-01-10", CreatedAt: now}}); err != nil {
+			t.Fatalf("AddPantryItems: %v", err)
+		}
+		if err := s.SavePlanMeals(id, []models.PlanMeal{{Day: "2026-01-01", Slot: "lunch", Title: "Плов", CreatedAt: now}}); err != nil {
+			t.Fatalf("SavePlanMeals: %v", err)
+		}
+		if _, err := s.AddDiaryEntry(&models.DiaryEntry{UserID: id, Day: "2026-01-01", Title: "Плов", Nutrition: models.Nutrition{Calories: 600}, CreatedAt: now}); err != nil {
+			t.Fatalf("AddDiaryEntry: %v", err)
+		}
+		notify := models.DefaultNotifySettings(id)
+		if err := s.SaveNotifySettings(notify, notify.Jobs(now)); err != nil {
+			t.Fatalf("SaveNotifySettings: %v", err)
+		}
+	}
+
+	data, err := s.ExportUserData(userID)
+	if err != nil {
+		t.Fatalf("ExportUserData: %v", err)
+	}
+	if data.Profile == nil || data.Profile.Username != "cook" {
+		t.Fatalf("профиль не выгружен: %+v", data.Profile)
+	}
+	if data.State.CurrentState != models.StateSettings || data.Preferences.Allergies != "арахис" || len(data.Preferences.Allergens) != 1 {
+		t.Fatalf("состояние или предпочтения не выгружены: %+v, %+v", data.State, data.Preferences)
+	}
+	if data.Quota == nil || data.RateLimitTAT == nil {
+		t.Fatalf("квота или лимит не выгружены: %+v, %v", data.Quota, data.RateLimitTAT)
+	}
+	if len(data.Generations) != 1 || data.Generations[0].Status != models.GenerationError || data.Generations[0].Error != "таймаут" {
+		t.Fatalf("журнал генераций выгружен неверно: %+v", data.Generations)
+	}
+	if len(data.Recipes) != 1 || data.Recipes[0].Request != "ужин" {
+		t.Fatalf("рецепты не выгружены: %+v", data.Recipes)
+	}
+	if len(data.Feedback) != 1 || data.Feedback[0].RecipeID != data.Recipes[0].ID {
+		t.Fatalf("оценки не выгружены: %+v", data.Feedback)
+	}
+	if len(data.Shopping) != 1 || data.Shopping[0].Name != "Рис" {
+		t.Fatalf("список покупок не выгружен: %+v", data.Shopping)
+	}
+	if len(data.Pantry) != 1 || data.Pantry[0].Expires != "2026-01-10" {
+		t.Fatalf("запасы не выгружены: %+v", data.Pantry)
+	}
+	if len(data.Plan) != 1 || data.Plan[0].Title != "Плов" {
+		t.Fatalf("план питания не выгружен: %+v", data.Plan)
+	}
+	if len(data.Diary) != 1 || data.Diary[0].Nutrition.Calories != 600 {
+		t.Fatalf("дневник питания не выгружен: %+v", data.Diary)
+	}
+	if data.Notify == nil || data.Notify.Timezone != models.DefaultTimezone || len(data.NotifyJobs) != 1 {
+		t.Fatalf("уведомления не выгружены: %+v, %+v", data.Notify, data.NotifyJobs)
+	}
+
+	if err := s.DeleteUserData(userID, now); err != nil {
+		t.Fatalf("DeleteUserData: %v", err)
+	}
+
+	data, err = s.ExportUserData(userID)
+	if err != nil {
+		t.Fatalf("ExportUserData: %v", err)
+	}
+	if data.Profile != nil || data.Quota != nil || data.RateLimitTAT != nil || len(data.Generations) != 0 || len(data.Recipes) != 0 ||
+		len(data.Feedback) != 0 || len(data.Shopping) != 0 || len(data.Pantry) != 0 || len(data.Plan) != 0 ||
+		len(data.Diary) != 0 || data.Notify != nil || len(data.NotifyJobs) != 0 {
+		t.Fatalf("данные не удалены: %+v", data)
+	}
+	if data.Preferences.Allergies != "" || len(data.Preferences.Allergens) != 0 || len(data.Preferences.DietRestrictions) != 0 ||
+		data.Preferences.Body != nil || data.State.CurrentState != models.StateMain {
+		t.Fatalf("предпочтения или состояние не удалены: %+v, %+v", data.Preferences, data.State)
+	}
+
+	// Данные других пользователей не затронуты
+	other, err := s.ExportUserData(otherID)
+	if err != nil {
+		t.Fatalf("ExportUserData: %v", err)
+	}
+	if other.Profile == nil || other.Preferences.Allergies != "арахис" || len(other.Preferences.Allergens) != 1 ||
+		other.Preferences.Body == nil || len(other.Generations) != 1 || len(other.Recipes) != 1 || len(other.Feedback) != 1 ||
+		len(other.Shopping) != 1 || len(other.Pantry) != 1 || len(other.Plan) != 1 || len(other.Diary) != 1 || other.Notify == nil || len(other.NotifyJobs) != 1 {
+		t.Fatalf("удалены чужие данные: %+v", other)
+	}
+}
@@ -0,0 +1,195 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// userTables — таблицы с данными пользователя (колонка user_id), кроме users.
+// Новую таблицу с персональными данными нужно добавить сюда и в ExportUserData.
+var userTables = []string{
+	"user_states",
+	"user_preferences",
+	"user_allergens",
+	"user_diet_restrictions",
+	"body_profiles",
+	"recipes",
+	"recipe_feedback",
+	"shopping_items",
+	"plan_meals",
+	"diary_entries",
+	"pantry_items",
+	"notify_settings",
+	"notify_jobs",
+	"rate_limits",
+	"generations",
+	"user_quotas",
+}
+
+// ExportUserData собирает всё, что хранится о пользователе
+func (db *DB) ExportUserData(userID int64) (*models.UserData, error) {
+	data := &models.UserData{UserID: userID, ExportedAt: time.Now()}
+	var err error
+
+	if data.Profile, err = db.GetUser(userID); err != nil {
+		return nil, err
+	}
+	if data.State, err = db.GetUserState(userID); err != nil {
+		return nil, err
+	}
+	if data.Preferences, err = db.GetUserPreferences(userID); err != nil {
+		return nil, err
+	}
+	if data.Quota, err = db.GetUserQuota(userID); err != nil {
+		return nil, err
+	}
+
+	var tat int64
+	err = db.queryRow(`SELECT tat FROM rate_limits WHERE user_id = ?`, userID).Scan(&tat)
+	switch {
+	case err == nil:
+		t := time.UnixMilli(tat)
+		data.RateLimitTAT = &t
+	case err != sql.ErrNoRows:
+		return nil, err
+	}
+
+	rows, err := db.query(`
+		SELECT id, status, COALESCE(error, ''), created_at
+		FROM generations WHERE user_id = ? ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data.Generations = []models.GenerationRecord{}
+	for rows.Next() {
+		var g models.GenerationRecord
+		var createdAt int64
+		if err := rows.Scan(&g.ID, &g.Status, &g.Error, &createdAt); err != nil {
+			return nil, err
+		}
+		g.CreatedAt = time.UnixMilli(createdAt)
+		data.Generations = append(data.Generations, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	recipeRows, err := db.query(`SELECT `+recipeColumns+` FROM recipes WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer recipeRows.Close()
+
+	data.Recipes = []models.Recipe{}
+	for recipeRows.Next() {
+		r, err := db.scanRecipe(recipeRows)
+		if err != nil {
+			return nil, err
+		}
+		data.Recipes = append(data.Recipes, *r)
+	}
+	if err := recipeRows.Err(); err != nil {
+		return nil, err
+	}
+
+	feedbackRows, err := db.query(`SELECT `+feedbackColumns+` FROM recipe_feedback WHERE user_id = ? ORDER BY recipe_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer feedbackRows.Close()
+
+	data.Feedback = []models.RecipeFeedback{}
+	for feedbackRows.Next() {
+		f, err := scanFeedback(feedbackRows)
+		if err != nil {
+			return nil, err
+		}
+		data.Feedback = append(data.Feedback, *f)
+	}
+	if err := feedbackRows.Err(); err != nil {
+		return nil, err
+	}
+
+	if data.Shopping, err = db.ListShoppingItems(userID); err != nil {
+		return nil, err
+	}
+	if data.Shopping == nil {
+		data.Shopping = []models.ShoppingItem{}
+	}
+
+	if data.Pantry, err = db.ListPantryItems(userID); err != nil {
+		return nil, err
+	}
+	if data.Pantry == nil {
+		data.Pantry = []models.PantryItem{}
+	}
+
+	if data.Plan, err = db.GetPlanMeals(userID, "", "9999-12-31"); err != nil {
+		return nil, err
+	}
+	if data.Plan == nil {
+		data.Plan = []models.PlanMeal{}
+	}
+
+	if data.Diary, err = db.ListDiaryEntries(userID, "", "9999-12-31"); err != nil {
+		return nil, err
+	}
+	if data.Diary == nil {
+		data.Diary = []models.DiaryEntry{}
+	}
+
+	if data.Notify, err = db.GetNotifySettings(userID); err != nil {
+		return nil, err
+	}
+	if data.NotifyJobs, err = db.ListNotifyJobs(userID); err != nil {
+		return nil, err
+	}
+	if data.NotifyJobs == nil {
+		data.NotifyJobs = []models.NotifyJob{}
+	}
+
+	return data, nil
+}
+
+// DeleteUserData безвозвратно удаляет все данные пользователя одной транзакцией
+// и записывает факт удаления в журнал аудита. У забаненного пользователя остаётся
+// строка users без профиля: иначе удалением данных можно было бы снять бан.
+func (db *DB) DeleteUserData(userID int64, now time.Time) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(db.rebind(`DELETE FROM users WHERE user_id = ? AND NOT is_banned`), userID); err != nil {
+		return fmt.Errorf("не удалось удалить данные из users: %w", err)
+	}
+	_, err = tx.Exec(db.rebind(`
+		UPDATE users SET username = '', first_name = '', language_code = '',
+			first_seen = 0, last_seen = 0, is_blocked = ?, blocked_reason = ''
+		WHERE user_id = ?
+	`), false, userID)
+	if err != nil {
+		return fmt.Errorf("не удалось удалить профиль из users: %w", err)
+	}
+
+	for _, table := range userTables {
+		if _, err := tx.Exec(db.rebind(`DELETE FROM `+table+` WHERE user_id = ?`), userID); err != nil {
+			return fmt.Errorf("не удалось удалить данные из %s: %w", table, err)
+		}
+	}
+
+	_, err = tx.Exec(db.rebind(`INSERT INTO user_deletions (user_id, deleted_at) VALUES (?, ?)`),
+		userID, now.UnixMilli())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
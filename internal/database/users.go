@@ -0,0 +1,68 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// UpsertUser обновляет профиль пользователя при каждом обновлении от Telegram.
+// Раз пользователь пишет боту, значит бот снова может ему отвечать — отметка блокировки снимается.
+func (db *DB) UpsertUser(user *models.User, now time.Time) error {
+	_, err := db.exec(`
+		INSERT INTO users (user_id, username, first_name, language_code, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			username = excluded.username,
+			first_name = excluded.first_name,
+			language_code = excluded.language_code,
+			first_seen = CASE WHEN users.first_seen = 0 THEN excluded.first_seen ELSE users.first_seen END,
+			last_seen = excluded.last_seen,
+			is_blocked = FALSE,
+			blocked_reason = ''
+	`, user.ID, user.Username, user.FirstName, user.LanguageCode, now.UnixMilli(), now.UnixMilli())
+
+	return err
+}
+
+// GetUser возвращает профиль пользователя (nil — пользователь неизвестен)
+func (db *DB) GetUser(userID int64) (*models.User, error) {
+	user := models.User{ID: userID}
+	var firstSeen, lastSeen int64
+
+	err := db.queryRow(`
+		SELECT username, first_name, language_code, first_seen, last_seen,
+			is_blocked, is_banned, banned_by, ban_reason
+		FROM users WHERE user_id = ?
+	`, userID).Scan(&user.Username, &user.FirstName, &user.LanguageCode, &firstSeen, &lastSeen,
+		&user.IsBlocked, &user.IsBanned, &user.BannedBy, &user.BanReason)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if firstSeen > 0 {
+		user.FirstSeen = time.UnixMilli(firstSeen)
+	}
+	if lastSeen > 0 {
+		user.LastSeen = time.UnixMilli(lastSeen)
+	}
+	return &user, nil
+}
+
+// MarkUserInactive отмечает пользователя, которому бот больше не может писать (например, заблокировал бота)
+func (db *DB) MarkUserInactive(userID int64, reason string) error {
+	_, err := db.exec(`
+		INSERT INTO users (user_id, is_blocked, blocked_reason)
+		VALUES (?, TRUE, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			is_blocked = TRUE,
+			blocked_reason = excluded.blocked_reason
+	`, userID, reason)
+
+	return err
+}
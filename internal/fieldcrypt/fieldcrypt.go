@@ -0,0 +1,168 @@
+// Package fieldcrypt шифрует отдельные поля базы данных алгоритмом AES-256-GCM.
+//
+// Зашифрованное значение имеет вид "enc:<id ключа>:<base64(nonce|шифротекст)>".
+// ID ключа позволяет ротацию: новые значения шифруются текущим ключом,
+// а старые расшифровываются ключом, которым были зашифрованы.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const prefix = "enc:"
+
+// rawPrefix экранирует открытое значение, которое само начинается с prefix или rawPrefix
+const rawPrefix = "raw:"
+
+// KeySize — длина ключа AES-256 в байтах
+const KeySize = 32
+
+// Key — ключ шифрования с идентификатором
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Keyring хранит ключи: текущий для шифрования и все — для расшифровки
+type Keyring struct {
+	currentID string
+	aeads     map[string]cipher.AEAD
+}
+
+// New создаёт связку ключей; первый ключ становится текущим
+func New(keys []Key) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("не задано ни одного ключа шифрования")
+	}
+
+	k := &Keyring{currentID: keys[0].ID, aeads: make(map[string]cipher.AEAD)}
+	for _, key := range keys {
+		if key.ID == "" || strings.Contains(key.ID, ":") {
+			return nil, fmt.Errorf("некорректный ID ключа %q", key.ID)
+		}
+		if len(key.Secret) != KeySize {
+			return nil, fmt.Errorf("ключ %s: нужно %d байт, получено %d", key.ID, KeySize, len(key.Secret))
+		}
+		if _, ok := k.aeads[key.ID]; ok {
+			return nil, fmt.Errorf("ключ %s указан дважды", key.ID)
+		}
+
+		block, err := aes.NewCipher(key.Secret)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		k.aeads[key.ID] = aead
+	}
+
+	return k, nil
+}
+
+// ParseKeys разбирает список ключей вида "id:base64" через запятую или перевод строки.
+// Пустые строки и строки, начинающиеся с #, пропускаются.
+func ParseKeys(spec string) ([]Key, error) {
+	var keys []Key
+	for _, line := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("ожидается id:base64, получено %q", line)
+		}
+		secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("ключ %s: некорректный base64: %w", id, err)
+		}
+		keys = append(keys, Key{ID: strings.TrimSpace(id), Secret: secret})
+	}
+	return keys, nil
+}
+
+// IsEncrypted проверяет, зашифровано ли значение
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Escape готовит открытое значение к записи без шифрования: текст пользователя
+// вида "enc:..." получает префикс "raw:", чтобы при чтении его не приняли за шифротекст
+func Escape(plaintext string) string {
+	if strings.HasPrefix(plaintext, prefix) || strings.HasPrefix(plaintext, rawPrefix) {
+		return rawPrefix + plaintext
+	}
+	return plaintext
+}
+
+// Unescape возвращает открытое значение, записанное через Escape
+func Unescape(value string) string {
+	if rest, ok := strings.CutPrefix(value, rawPrefix); ok && (strings.HasPrefix(rest, prefix) || strings.HasPrefix(rest, rawPrefix)) {
+		return rest
+	}
+	return value
+}
+
+// CurrentID возвращает ID ключа, которым шифруются новые значения
+func (k *Keyring) CurrentID() string {
+	return k.currentID
+}
+
+// Encrypt шифрует значение текущим ключом. aad (например, "таблица.поле:user_id")
+// привязывает шифротекст к месту хранения: его нельзя переставить в другую строку или поле.
+func (k *Keyring) Encrypt(plaintext, aad string) (string, error) {
+	aead := k.aeads[k.currentID]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(aad))
+	return prefix + k.currentID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt расшифровывает значение; незашифрованное значение возвращается без экранирования
+func (k *Keyring) Decrypt(value, aad string) (string, error) {
+	if !IsEncrypted(value) {
+		return Unescape(value), nil
+	}
+
+	keyID, encoded, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !ok {
+		return "", errors.New("повреждённое зашифрованное значение")
+	}
+	aead, ok := k.aeads[keyID]
+	if !ok {
+		return "", fmt.Errorf("неизвестный ключ шифрования %q", keyID)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("повреждённое зашифрованное значение")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(aad))
+	if err != nil {
+		return "", fmt.Errorf("не удалось расшифровать значение ключом %s: %w", keyID, err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRewrite сообщает, что значение хранится открыто или зашифровано не текущим ключом
+func (k *Keyring) NeedsRewrite(value string) bool {
+	if value == "" {
+		return false
+	}
+	return !strings.HasPrefix(value, prefix+k.currentID+":")
+}
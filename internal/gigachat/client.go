@@ -25,6 +25,14 @@ const (
 	apiURL   = "https://gigachat.devices.sberbank.ru/api/v1/chat/completions"
 )
 
+// Model — модель, которой генерируются рецепты.
+// PromptVersion нужно увеличивать при каждом изменении buildSystemPrompt:
+// версия сохраняется вместе с рецептом, чтобы сравнивать качество промптов.
+const (
+	Model         = "GigaChat"
+	PromptVersion = "5"
+)
+
 // Client — клиент для GigaChat с OAuth-авторизацией.
 type Client struct {
 	clientID     string
@@ -145,15 +153,18 @@ func (c *Client) getAccessToken() (string, error) {
 
 // GenerateRecipe генерирует рецепт.
 func (c *Client) GenerateRecipe(userRequest string, prefs *models.UserPreferences) (string, error) {
+	return c.complete(buildSystemPrompt(prefs), userRequest)
+}
+
+// complete отправляет системный промпт и запрос пользователя, возвращает ответ модели.
+func (c *Client) complete(systemPrompt, userRequest string) (string, error) {
 	token, err := c.getAccessToken()
 	if err != nil {
 		return "", fmt.Errorf("не удалось получить токен: %w", err)
 	}
 
-	systemPrompt := buildSystemPrompt(prefs)
-
 	chatReq := ChatRequest{
-		Model: "GigaChat", // ✅ Или "GigaChat-Pro", если у вас есть доступ
+		Model: Model, // ✅ Или "GigaChat-Pro", если у вас есть доступ
 		Messages: []ChatMessage{
 			{Role: "system", Content: systemPrompt},
 			{Role: "user", Content: userRequest},
@@ -198,7 +209,7 @@ func (c *Client) GenerateRecipe(userRequest string, prefs *models.UserPreference
 		c.accessToken = ""
 		c.tokenExpires = time.Time{}
 		c.mu.Unlock()
-		return c.GenerateRecipe(userRequest, prefs) // один раз
+		return c.complete(systemPrompt, userRequest) // один раз
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -236,9 +247,25 @@ func min(a, b int) int {
 	return b
 }
 
-// buildSystemPrompt — как раньше (не менялся)
+// buildSystemPrompt собирает системный промпт рецепта из предпочтений пользователя
 func buildSystemPrompt(prefs *models.UserPreferences) string {
-	hasSettings := prefs != nil && (prefs.DietaryType != "" || prefs.Goal != "" || prefs.Allergies != "" || prefs.Likes != "" || prefs.Dislikes != "")
+	hasSettings := prefs != nil && (prefs.DietaryType != "" || prefs.Goal != "" || prefs.Allergies != "" || len(prefs.Allergens) > 0 || len(prefs.DietRestrictions) > 0 || prefs.Likes != "" || prefs.Dislikes != "")
+
+	// Аллергены из справочника и «другое» одной строкой
+	var allergyList string
+	if prefs != nil {
+		allergyList = strings.Join(prefs.AllergyList(), ", ")
+	}
+
+	// Ограничения питания (вегетарианство, кето и т.д.) — отдельно от цели по весу
+	var restrictions []models.DietRestriction
+	var restrictionNames []string
+	if prefs != nil {
+		restrictions = prefs.SelectedDietRestrictions()
+	}
+	for _, r := range restrictions {
+		restrictionNames = append(restrictionNames, r.Name)
+	}
 
 	var sb strings.Builder
 	sb.WriteString(`Ты — профессиональный шеф-повар и сертифицированный нутрициолог.  
@@ -261,10 +288,14 @@ func buildSystemPrompt(prefs *models.UserPreferences) string {
 		if goal == "" {
 			goal = "не указана"
 		}
-		allergies := prefs.Allergies
+		allergies := allergyList
 		if allergies == "" {
 			allergies = "нет"
 		}
+		restrictionsText := strings.Join(restrictionNames, ", ")
+		if restrictionsText == "" {
+			restrictionsText = "нет"
+		}
 		dislikes := prefs.Dislikes
 		if dislikes == "" {
 			dislikes = "ничего"
@@ -274,12 +305,20 @@ func buildSystemPrompt(prefs *models.UserPreferences) string {
 			likes = "не указано"
 		}
 
-		sb.WriteString(fmt.Sprintf(`- **Тип питания**: %s  
+		sb.WriteString(fmt.Sprintf(`- **Цель по весу**: %s  
+- **Ограничения питания**: %s  
 - **Цель**: %s  
 - **Аллергии / непереносимости**: %s  
 - **Избегать**: %s  
 - **Любит / хочет**: %s  
-`, dietType, goal, allergies, dislikes, likes))
+`, dietType, restrictionsText, goal, allergies, dislikes, likes))
+
+		if len(restrictions) > 0 {
+			sb.WriteString("\n🥗 **Правила ограничений** (соблюдай все одновременно):\n")
+			for _, r := range restrictions {
+				sb.WriteString(fmt.Sprintf("- %s: %s.\n", r.Name, r.Rule))
+			}
+		}
 	} else {
 		sb.WriteString(`→ Настройки не заданы. Используй подход **«здоровое повседневное питание для студента»**:  
    - бюджетно, быстро, без экзотики  
@@ -317,13 +356,22 @@ _Краткое пояснение: почему оно подходит под
 - *Белки*: X г  
 - *Жиры*: X г  
 - *Углеводы*: X г  
-→ Оценка приблизительная, но реалистичная. Если тип питания — «Похудение», ккал ≤ 450; «Набор массы» — ≥ 600.
+→ Оценка приблизительная, но реалистичная.
 `)
 
-	if hasSettings && (prefs.Allergies != "" || prefs.Dislikes != "") {
+	// Норма на приём пищи из параметров тела; без них — общее правило по цели
+	if prefs != nil && prefs.Body.Complete() {
+		meal := prefs.Body.MealTargets(prefs.DietaryType, prefs.DietRestrictions)
+		sb.WriteString(fmt.Sprintf("→ Ориентир на 1 приём пищи (рассчитан по параметрам пользователя): ~%d ккал (±10%%), белки ~%d г, жиры ~%d г, углеводы ~%d г. Подбери порцию так, чтобы попасть в эти значения.\n",
+			meal.Calories, meal.Protein, meal.Fat, meal.Carbs))
+	} else {
+		sb.WriteString("→ Если цель по весу — «Похудение», ккал ≤ 450; «Набор массы» — ≥ 600.\n")
+	}
+
+	if hasSettings && (allergyList != "" || prefs.Dislikes != "") {
 		sb.WriteString("\n❗️ *Запрещено*:\n")
-		if prefs.Allergies != "" {
-			sb.WriteString(fmt.Sprintf("- Использовать %s — даже в скобках/альтернативах.\n", prefs.Allergies))
+		if allergyList != "" {
+			sb.WriteString(fmt.Sprintf("- Использовать %s — даже в скобках/альтернативах.\n", allergyList))
 		}
 		if prefs.Dislikes != "" {
 			sb.WriteString(fmt.Sprintf("- Использовать %s — даже в скобках/альтернативах.\n", prefs.Dislikes))
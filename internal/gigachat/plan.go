@@ -0,0 +1,109 @@
+package gigachat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// GenerateMealPlan составляет план питания на days дней: по строке
+// «День N | Приём | Блюдо | Белок | ккал» на каждый приём пищи (см. models.ParseMealPlan).
+func (c *Client) GenerateMealPlan(prefs *models.UserPreferences, days int) (string, error) {
+	request := fmt.Sprintf("Составь план питания на %d дней.", days)
+	return c.complete(buildPlanPrompt(prefs, days), request)
+}
+
+// GenerateMeal предлагает одно блюдо для плана строкой «Блюдо | Белок | ккал»
+// (см. models.ParsePlanMeal). excludeProteins — источники белка соседних дней,
+// excludeTitles — блюда, которые уже есть в плане.
+func (c *Client) GenerateMeal(prefs *models.UserPreferences, slot models.PlanSlot, excludeProteins, excludeTitles []string) (string, error) {
+	var sb strings.Builder
+	sb.WriteString(planIntro(prefs))
+
+	calories := slot.Calories(models.PlanDailyCalories(prefs))
+	sb.WriteString(fmt.Sprintf("\nПредложи одно блюдо на приём пищи «%s», ~%d ккал (±10%%).\n", slot.Name, calories))
+	if len(excludeProteins) > 0 {
+		sb.WriteString(fmt.Sprintf("- Основной белок НЕ может быть: %s.\n", strings.Join(excludeProteins, ", ")))
+	}
+	if len(excludeTitles) > 0 {
+		sb.WriteString(fmt.Sprintf("- Не предлагай эти блюда и их вариации: %s.\n", strings.Join(excludeTitles, "; ")))
+	}
+	sb.WriteString(fmt.Sprintf(`
+Основной белок — одно слово из списка: %s.
+
+Формат ответа — ровно одна строка без пояснений:
+Название блюда | основной белок | ккал
+`, models.PlanProteinNames()))
+
+	request := fmt.Sprintf("Предложи блюдо на %s.", strings.ToLower(slot.Name))
+	return c.complete(sb.String(), request)
+}
+
+// buildPlanPrompt — промпт плана питания: нормы по приёмам пищи и правила разнообразия
+func buildPlanPrompt(prefs *models.UserPreferences, days int) string {
+	var sb strings.Builder
+	sb.WriteString(planIntro(prefs))
+
+	daily := models.PlanDailyCalories(prefs)
+	sb.WriteString(fmt.Sprintf("\nСоставь план на %d дней: ", days))
+	var slots []string
+	for _, s := range models.PlanSlots {
+		slots = append(slots, fmt.Sprintf("%s ~%d ккал", strings.ToLower(s.Name), s.Calories(daily)))
+	}
+	sb.WriteString(strings.Join(slots, ", ") + fmt.Sprintf(" — всего ~%d ккал в день.\n", daily))
+
+	sb.WriteString(fmt.Sprintf(`
+📌 Правила разнообразия:
+- Основной источник белка не повторяется два дня подряд: если в какой-то день была курица, на следующий день курицы нет ни в одном приёме пищи.
+- Блюда не повторяются в течение всего плана.
+- Основной белок — одно слово из списка: %s.
+
+Формат ответа — только строки плана, по одной на приём пищи, без заголовков и пояснений:
+День 1 | Завтрак | Название блюда | основной белок | ккал
+День 1 | Обед | Название блюда | основной белок | ккал
+День 1 | Ужин | Название блюда | основной белок | ккал
+…
+День %d | Ужин | Название блюда | основной белок | ккал
+`, models.PlanProteinNames(), days))
+
+	return sb.String()
+}
+
+// planIntro — роль модели и параметры пользователя, общие для плана и замены блюда
+func planIntro(prefs *models.UserPreferences) string {
+	if prefs == nil {
+		prefs = &models.UserPreferences{}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`Ты — нутрициолог, который составляет недельные планы питания.
+Блюда простые, из продуктов обычного магазина, готовятся не дольше 40 минут.
+
+### 🔍 Параметры пользователя:
+`)
+
+	dietType := prefs.DietaryType
+	if dietType == "" {
+		dietType = "не указана"
+	}
+	sb.WriteString(fmt.Sprintf("- Цель по весу: %s\n", dietType))
+	if prefs.Goal != "" {
+		sb.WriteString(fmt.Sprintf("- Цель: %s\n", prefs.Goal))
+	}
+	for _, r := range prefs.SelectedDietRestrictions() {
+		sb.WriteString(fmt.Sprintf("- %s: %s.\n", r.Name, r.Rule))
+	}
+	if prefs.Likes != "" {
+		sb.WriteString(fmt.Sprintf("- Любит: %s\n", prefs.Likes))
+	}
+
+	if allergies := prefs.AllergyList(); len(allergies) > 0 {
+		sb.WriteString(fmt.Sprintf("\n❗️ Запрещено использовать %s — даже в соусах и гарнирах.\n", strings.Join(allergies, ", ")))
+	}
+	if prefs.Dislikes != "" {
+		sb.WriteString(fmt.Sprintf("❗️ Не использовать: %s.\n", prefs.Dislikes))
+	}
+
+	return sb.String()
+}
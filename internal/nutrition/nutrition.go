@@ -0,0 +1,208 @@
+// Package nutrition считает пищевую ценность рецепта по встроенной базе продуктов
+// без обращения к модели: ингредиенты из блока «Ингредиенты» переводятся в граммы
+// и складываются по значениям на 100 г.
+package nutrition
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pinghoyk/neurobot/internal/servings"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+//go:embed products.json
+var productsJSON []byte
+
+// Product — продукт базы: пищевая ценность на 100 г
+type Product struct {
+	Name     string   `json:"name"`
+	Synonyms []string `json:"synonyms"`
+	Calories float64  `json:"calories"`
+	Protein  float64  `json:"protein"`
+	Fat      float64  `json:"fat"`
+	Carbs    float64  `json:"carbs"`
+	Piece    float64  `json:"piece"` // вес одной штуки, г; 0 — продукт не считают штуками
+}
+
+// per100 — пищевая ценность grams граммов продукта
+func (p *Product) per100(grams float64) models.Nutrition {
+	k := grams / 100
+	return models.Nutrition{
+		Calories: p.Calories * k,
+		Protein:  p.Protein * k,
+		Fat:      p.Fat * k,
+		Carbs:    p.Carbs * k,
+	}
+}
+
+// grams переводит количество ингредиента в граммы. false — единицу нельзя взвесить.
+// Для диапазона «1–2» берётся середина: это оценка, а не закупка.
+// Плотность жидкостей — из таблицы пересчёта мер (servings.Density).
+func (p *Product) grams(ing servings.Ingredient) (float64, bool) {
+	amount := ing.Typical()
+	switch ing.Unit {
+	case servings.UnitGrams:
+		return amount, true
+	case servings.UnitMl:
+		if density, ok := servings.Density(ing.Name); ok {
+			return amount * density, true
+		}
+		return amount, true
+	}
+	for _, u := range measures {
+		if strings.HasPrefix(ing.Unit, u.stem) {
+			return amount * u.grams, true
+		}
+	}
+	// «2 шт», «1 луковица», «3 зубчика» — штуки продукта
+	if p.Piece > 0 {
+		return amount * p.Piece, true
+	}
+	return 0, false
+}
+
+// measures — вес бытовых мер, которые не зависят от продукта
+var measures = []struct {
+	stem  string
+	grams float64
+}{
+	{"щепот", 0.5},
+	{"пуч", 30},
+	{"ломт", 25},
+	{"горст", 30},
+}
+
+// entry — продукт с основами слов его названия и синонимов
+type entry struct {
+	product *Product
+	names   [][]string
+}
+
+var products = loadProducts()
+
+func loadProducts() []entry {
+	var list []Product
+	if err := json.Unmarshal(productsJSON, &list); err != nil {
+		log.Fatalf("Не удалось распарсить products.json: %v", err)
+	}
+
+	entries := make([]entry, len(list))
+	for i := range list {
+		entries[i].product = &list[i]
+		for _, name := range append([]string{list[i].Name}, list[i].Synonyms...) {
+			var stems []string
+			for _, w := range words(name) {
+				stems = append(stems, stem(w))
+			}
+			entries[i].names = append(entries[i].names, stems)
+		}
+	}
+	return entries
+}
+
+// Lookup ищет продукт по названию ингредиента: «200 г куриного филе», «лук красный».
+// Из подходящих названий выбирается самое подробное, поэтому «сливочное масло»
+// не путается с растительным, а «томатная паста» — с макаронами.
+func Lookup(name string) (*Product, bool) {
+	ws := words(name)
+	var best *Product
+	bestScore := 0
+	for _, e := range products {
+		for _, stems := range e.names {
+			if score := matchScore(stems, ws); score > bestScore {
+				best, bestScore = e.product, score
+			}
+		}
+	}
+	return best, best != nil
+}
+
+// matchScore — суммарная длина основ, если каждая нашлась в начале какого-нибудь слова; иначе 0
+func matchScore(stems, ws []string) int {
+	score := 0
+	for _, s := range stems {
+		found := false
+		for _, w := range ws {
+			if strings.HasPrefix(w, s) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0
+		}
+		score += utf8.RuneCountInString(s)
+	}
+	return score
+}
+
+// words — слова названия в нижнем регистре, «ё» заменена на «е»
+func words(name string) []string {
+	name = strings.ReplaceAll(strings.ToLower(name), "ё", "е")
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return !(r >= 'а' && r <= 'я' || r >= 'a' && r <= 'z')
+	})
+}
+
+// stem отбрасывает окончание слова, но оставляет хотя бы три буквы:
+// «курица» → «куриц», «сливочное» → «сливочн», «лук» → «лук»
+func stem(w string) string {
+	r := []rune(w)
+	for i := 0; i < 2 && len(r) > 3 && strings.ContainsRune("аеиоуыэюяйь", r[len(r)-1]); i++ {
+		r = r[:len(r)-1]
+	}
+	return string(r)
+}
+
+// Estimate — пищевая ценность рецепта, рассчитанная по базе продуктов
+type Estimate struct {
+	Total      models.Nutrition
+	PerServing models.Nutrition
+	Servings   float64  // число порций из рецепта; 1, если не указано
+	Matched    int      // сколько ингредиентов учтено
+	Unmatched  []string // ингредиенты с количеством, которых нет в базе или которые не взвесить
+}
+
+// Confident сообщает, что учтены все ингредиенты с количеством и расчёту можно доверять
+func (e Estimate) Confident() bool {
+	return e.Matched > 0 && len(e.Unmatched) == 0
+}
+
+// Calculate считает пищевую ценность рецепта на все порции и на одну.
+// Ингредиенты без количества («соль по вкусу») не влияют на расчёт.
+func Calculate(text string) Estimate {
+	e := Estimate{Servings: 1}
+	if n, ok := servings.ParseServings(text); ok {
+		e.Servings = n
+	}
+
+	for _, ing := range servings.ParseIngredients(text) {
+		if ing.Amount <= 0 {
+			continue
+		}
+		product, ok := Lookup(ing.Name)
+		if !ok {
+			e.Unmatched = append(e.Unmatched, ing.Name)
+			continue
+		}
+		grams, ok := product.grams(ing)
+		if !ok {
+			e.Unmatched = append(e.Unmatched, ing.Name)
+			continue
+		}
+		e.Total = e.Total.Add(product.per100(grams))
+		e.Matched++
+	}
+
+	e.PerServing = models.Nutrition{
+		Calories: e.Total.Calories / e.Servings,
+		Protein:  e.Total.Protein / e.Servings,
+		Fat:      e.Total.Fat / e.Servings,
+		Carbs:    e.Total.Carbs / e.Servings,
+	}
+	return e
+}
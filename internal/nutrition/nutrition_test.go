@@ -0,0 +1,124 @@
+package nutrition
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name string
+		want string // "" — продукта нет в базе
+	}{
+		{"Куриное филе", "куриное филе"},
+		{"грудка куриная", "куриное филе"},
+		{"Курица", "курица"},
+		{"Сливочное масло", "сливочное масло"},
+		{"Масло оливковое", "растительное масло"},
+		{"Томатная паста", "томатная паста"},
+		{"Паста", "макароны"},
+		{"Лук красный", "лук репчатый"},
+		{"Перец черный молотый", "черный перец"},
+		{"Сыр пармезан", "сыр"},
+		{"Яйца", "яйцо"},
+		{"Ананас", ""},
+	}
+
+	for _, tt := range tests {
+		p, ok := Lookup(tt.name)
+		got := ""
+		if ok {
+			got = p.Name
+		}
+		if got != tt.want {
+			t.Errorf("Lookup(%q) = %q; ожидалось %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStem(t *testing.T) {
+	tests := map[string]string{
+		"курица":    "куриц",
+		"сливочное": "сливочн",
+		"лук":       "лук",
+		"яйцо":      "яйц",
+	}
+	for w, want := range tests {
+		if got := stem(w); got != want {
+			t.Errorf("stem(%q) = %q; ожидалось %q", w, got, want)
+		}
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	recipe := `*Рис с курицей*
+Порций: 2
+
+*Ингредиенты*
+1. Куриное филе — 200 г
+2. Рис — 100 г
+3. Масло растительное — 1 ст. л.
+4. Яйца — 1–3 шт
+5. Соль — по вкусу
+
+*Пошаговый рецепт*
+1. Варите рис 15 минут`
+
+	e := Calculate(recipe)
+	if e.Matched != 4 || len(e.Unmatched) != 0 || !e.Confident() {
+		t.Fatalf("учтено %d, не найдено %v; ожидалось 4 и ничего", e.Matched, e.Unmatched)
+	}
+	if e.Servings != 2 {
+		t.Errorf("порций %v; ожидалось 2", e.Servings)
+	}
+
+	// Масло: 15 мл × 0,92 г/мл; яйца: середина диапазона — 2 шт по 55 г
+	want := models.Nutrition{
+		Calories: (226 + 344 + 13.8*8.99 + 110*1.57) / 2,
+		Protein:  (47.2 + 6.7 + 110*0.127) / 2,
+		Fat:      (3.8 + 0.7 + 13.8*0.999 + 110*0.115) / 2,
+		Carbs:    (0.8 + 78.9 + 110*0.007) / 2,
+	}
+	got := e.PerServing
+	for _, f := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"ккал", got.Calories, want.Calories},
+		{"белки", got.Protein, want.Protein},
+		{"жиры", got.Fat, want.Fat},
+		{"углеводы", got.Carbs, want.Carbs},
+	} {
+		if math.Abs(f.got-f.want) > 0.01 {
+			t.Errorf("%s на порцию: %.2f; ожидалось %.2f", f.name, f.got, f.want)
+		}
+	}
+}
+
+func TestCalculateUnmatched(t *testing.T) {
+	tests := []struct {
+		ingredients string
+		matched     int
+		unmatched   []string
+		confident   bool
+	}{
+		{"1. Ананас — 100 г\n2. Рис — 100 г", 1, []string{"Ананас"}, false},
+		// Пучок взвешивается без базы мер продукта
+		{"1. Зелень — 1 пучок", 1, nil, true},
+		// Штуки риса взвесить нельзя
+		{"1. Рис — 2 шт", 0, []string{"Рис"}, false},
+		// Без количества ингредиент не влияет на расчёт
+		{"1. Соль — по вкусу", 0, nil, false},
+	}
+
+	for _, tt := range tests {
+		e := Calculate("*Ингредиенты*\n" + tt.ingredients + "\n*Пошаговый рецепт*")
+		if e.Matched != tt.matched || !reflect.DeepEqual(e.Unmatched, tt.unmatched) || e.Confident() != tt.confident {
+			t.Errorf("Calculate(%q): учтено %d, не найдено %v, уверенно %v; ожидалось %d, %v, %v",
+				tt.ingredients, e.Matched, e.Unmatched, e.Confident(), tt.matched, tt.unmatched, tt.confident)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Package limitertest — общий контрактный набор проверок для реализаций ratelimit.Limiter.
+// Лимитер поверх хранилища и лимитер в памяти должны вести себя одинаково.
+package limitertest
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pinghoyk/neurobot/internal/ratelimit"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// Factory создаёт лимитер с заданным ограничением для одной проверки
+type Factory func(t *testing.T, limit models.RateLimit) ratelimit.Limiter
+
+var lastUserID atomic.Int64
+
+// Run прогоняет все контрактные проверки
+func Run(t *testing.T, newLimiter Factory) {
+	checks := []struct {
+		name string
+		fn   func(t *testing.T, newLimiter Factory)
+	}{
+		{"Burst", testBurst},
+		{"Independent", testIndependent},
+		{"Concurrent", testConcurrent},
+	}
+
+	for _, c := range checks {
+		t.Run(c.name, func(t *testing.T) {
+			c.fn(t, newLimiter)
+		})
+	}
+}
+
+// userID возвращает уникальный ID в пределах запуска; для общей базы
+// фабрика должна изолировать данные сама (например, отдельным файлом SQLite)
+func userID() int64 {
+	return lastUserID.Add(1)
+}
+
+func testBurst(t *testing.T, newLimiter Factory) {
+	limit := models.RateLimit{PerMinute: 2, Burst: 3}
+	l := newLimiter(t, limit)
+	id := userID()
+
+	for i := 0; i < limit.Burst; i++ {
+		res, err := l.Allow(id)
+		if err != nil {
+			t.Fatalf("Allow #%d: %v", i+1, err)
+		}
+		if !res.Allowed {
+			t.Fatalf("запрос #%d должен быть разрешён", i+1)
+		}
+	}
+
+	res, err := l.Allow(id)
+	if err != nil {
+		t.Fatalf("Allow: %v", err)
+	}
+	if res.Allowed {
+		t.Fatalf("запрос сверх лимита должен быть отклонён")
+	}
+	if res.RetryAfter <= 0 || res.RetryAfter > limit.Interval() {
+		t.Fatalf("RetryAfter = %s, ожидалось в (0, %s]", res.RetryAfter, limit.Interval())
+	}
+}
+
+func testIndependent(t *testing.T, newLimiter Factory) {
+	l := newLimiter(t, models.RateLimit{PerMinute: 1, Burst: 1})
+	first, second := userID(), userID()
+
+	if res, err := l.Allow(first); err != nil || !res.Allowed {
+		t.Fatalf("первый запрос должен пройти: %+v, %v", res, err)
+	}
+	if res, err := l.Allow(second); err != nil || !res.Allowed {
+		t.Fatalf("лимит другого пользователя не должен влиять: %+v, %v", res, err)
+	}
+}
+
+func testConcurrent(t *testing.T, newLimiter Factory) {
+	limit := models.RateLimit{PerMinute: 1, Burst: 5}
+	l := newLimiter(t, limit)
+	id := userID()
+
+	const workers = 20
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := l.Allow(id)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if res.Allowed {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("Allow: %v", err)
+	}
+	if got := int(allowed.Load()); got != limit.Burst {
+		t.Fatalf("параллельно разрешено %d запросов, ожидалось ровно %d", got, limit.Burst)
+	}
+}
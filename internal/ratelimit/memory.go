@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+const (
+	shardCount    = 32
+	sweepInterval = time.Minute
+)
+
+// Memory — лимитер в памяти процесса. Карта разбита на шарды, чтобы
+// запросы разных пользователей не ждали одну блокировку; фоновая горутина
+// удаляет записи, которые уже ничем не отличаются от отсутствующих.
+type Memory struct {
+	limit  models.RateLimit
+	shards [shardCount]shard
+	stop   chan struct{}
+	once   sync.Once
+}
+
+type shard struct {
+	mu  sync.Mutex
+	tat map[int64]time.Time // theoretical arrival time для GCRA
+}
+
+// NewMemory создаёт лимитер в памяти и запускает очистку устаревших записей
+func NewMemory(limit models.RateLimit) *Memory {
+	m := &Memory{
+		limit: limit,
+		stop:  make(chan struct{}),
+	}
+	for i := range m.shards {
+		m.shards[i].tat = make(map[int64]time.Time)
+	}
+
+	go m.sweepLoop()
+	return m
+}
+
+// Allow проверяет и расходует лимит пользователя
+func (m *Memory) Allow(userID int64) (models.RateLimitResult, error) {
+	s := m.shard(userID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tat, result := m.limit.Take(s.tat[userID], time.Now())
+	s.tat[userID] = tat
+	return result, nil
+}
+
+// Close останавливает фоновую очистку
+func (m *Memory) Close() error {
+	m.once.Do(func() { close(m.stop) })
+	return nil
+}
+
+func (m *Memory) shard(userID int64) *shard {
+	return &m.shards[uint64(userID)%shardCount]
+}
+
+func (m *Memory) sweepLoop() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case now := <-ticker.C:
+			m.sweep(now)
+		}
+	}
+}
+
+// sweep удаляет записи с tat в прошлом: для них лимит полностью восстановлен
+func (m *Memory) sweep(now time.Time) {
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.Lock()
+		for userID, tat := range s.tat {
+			if tat.Before(now) {
+				delete(s.tat, userID)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,35 @@
+// Package ratelimit ограничивает частоту генераций на пользователя.
+// Есть две реализации с одинаковым поведением: поверх хранилища (общая для
+// нескольких реплик) и в памяти процесса (для одного экземпляра бота).
+package ratelimit
+
+import (
+	"github.com/pinghoyk/neurobot/internal/database"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// Limiter проверяет и расходует лимит запросов пользователя
+type Limiter interface {
+	Allow(userID int64) (models.RateLimitResult, error)
+}
+
+// Store — лимитер, хранящий состояние в базе данных
+type Store struct {
+	store database.RateLimitStore
+	limit models.RateLimit
+}
+
+// NewStore создаёт лимитер поверх хранилища
+func NewStore(store database.RateLimitStore, limit models.RateLimit) *Store {
+	return &Store{store: store, limit: limit}
+}
+
+// Allow проверяет лимит одним атомарным запросом к базе
+func (s *Store) Allow(userID int64) (models.RateLimitResult, error) {
+	return s.store.CheckRateLimit(userID, s.limit)
+}
+
+var (
+	_ Limiter = (*Store)(nil)
+	_ Limiter = (*Memory)(nil)
+)
@@ -0,0 +1,30 @@
+package ratelimit_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/pinghoyk/neurobot/internal/database"
+	"github.com/pinghoyk/neurobot/internal/ratelimit"
+	"github.com/pinghoyk/neurobot/internal/ratelimit/limitertest"
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	limitertest.Run(t, func(t *testing.T, l models.RateLimit) ratelimit.Limiter {
+		m := ratelimit.NewMemory(l)
+		t.Cleanup(func() { m.Close() })
+		return m
+	})
+}
+
+func TestStoreLimiter(t *testing.T) {
+	limitertest.Run(t, func(t *testing.T, l models.RateLimit) ratelimit.Limiter {
+		db, err := database.New(filepath.Join(t.TempDir(), "neurobot.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return ratelimit.NewStore(db, l)
+	})
+}
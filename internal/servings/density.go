@@ -0,0 +1,72 @@
+package servings
+
+import "strings"
+
+// Объёмы кухонных мер, мл. Стакан — гранёный, до ободка.
+const (
+	mlPerCup  = 200
+	mlPerTbsp = 15
+	mlPerTsp  = 5
+)
+
+// density — плотность продукта, г/мл. stems — начала слов в названии продукта;
+// liquid — продукт отмеряют по объёму, поэтому в метрических мерах он остаётся в мл.
+type density struct {
+	stems  []string
+	gPerMl float64
+	liquid bool
+}
+
+// densities — плотности распространённых продуктов. Порядок важен: берётся первое
+// совпадение, поэтому уточнённые продукты («сахарная пудра», «рисовое молоко»)
+// стоят раньше общих («сахар», «рис»).
+var densities = []density{
+	{[]string{"вод", "бульон"}, 1.0, true},
+	{[]string{"молок"}, 1.03, true},
+	{[]string{"кефир", "ряженк", "простокваш"}, 1.03, true},
+	{[]string{"сливк"}, 1.0, true},
+	{[]string{"йогурт"}, 1.05, true},
+	{[]string{"соус"}, 1.15, true},
+	{[]string{"уксус"}, 1.0, true},
+	{[]string{"сок"}, 1.05, true},
+	{[]string{"сливочн"}, 0.95, false},
+	{[]string{"масл"}, 0.92, true},
+	{[]string{"мед", "мёд"}, 1.4, false},
+	{[]string{"сметан"}, 1.05, false},
+	{[]string{"пудр"}, 0.55, false},
+	{[]string{"крахмал"}, 0.7, false},
+	{[]string{"мук"}, 0.65, false},
+	{[]string{"сахар"}, 0.85, false},
+	{[]string{"соль", "соли"}, 1.2, false},
+	{[]string{"манк", "манн"}, 0.8, false},
+	{[]string{"рис"}, 0.9, false},
+	{[]string{"гречк", "гречнев"}, 0.85, false},
+	{[]string{"пшен", "булгур", "киноа", "кускус"}, 0.85, false},
+	{[]string{"чечевиц"}, 0.85, false},
+	{[]string{"овсян", "хлопь"}, 0.45, false},
+	{[]string{"какао"}, 0.5, false},
+	{[]string{"сухар", "панировк"}, 0.6, false},
+}
+
+// Density — плотность продукта из таблицы, г/мл
+func Density(name string) (float64, bool) {
+	d, ok := lookupDensity(name)
+	return d.gPerMl, ok
+}
+
+// lookupDensity ищет плотность продукта по названию
+func lookupDensity(name string) (density, bool) {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !(r >= 'а' && r <= 'я' || r == 'ё')
+	})
+	for _, d := range densities {
+		for _, stem := range d.stems {
+			for _, w := range words {
+				if strings.HasPrefix(w, stem) {
+					return d, true
+				}
+			}
+		}
+	}
+	return density{}, false
+}
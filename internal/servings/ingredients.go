@@ -0,0 +1,178 @@
+package servings
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pinghoyk/neurobot/pkg/models"
+)
+
+// Базовые единицы, к которым приводятся ингредиенты для сложения
+const (
+	UnitGrams  = "г"
+	UnitMl     = "мл"
+	UnitPieces = "шт"
+)
+
+// Ingredient — продукт из рецепта с количеством в базовых единицах
+type Ingredient struct {
+	Name      string
+	Amount    float64 // 0 — количество не указано («по вкусу»)
+	AmountMin float64 // нижняя граница диапазона «1–2»; 0 — диапазона нет
+	Unit      string  // UnitGrams, UnitMl, UnitPieces или единица из рецепта («зубчика»)
+}
+
+// Typical — количество для оценок вроде пищевой ценности: середина диапазона, без него — Amount
+func (i Ingredient) Typical() float64 {
+	if i.AmountMin > 0 {
+		return (i.AmountMin + i.Amount) / 2
+	}
+	return i.Amount
+}
+
+// parenthesesRe — уточнения в скобках: «(для подачи)», «(100 г)»
+var parenthesesRe = regexp.MustCompile(`\s*\([^)]*\)`)
+
+// ParseIngredients разбирает блок «Ингредиенты» рецепта. Количество приводится к граммам,
+// миллилитрам или штукам; ложки и стаканы сыпучих продуктов с известной плотностью — к граммам.
+// Для диапазона «1–2» Amount — верхняя граница, чтобы продуктов хватило; середина — в Typical.
+func ParseIngredients(text string) []Ingredient {
+	var result []Ingredient
+	inside := false
+	for _, line := range strings.Split(text, "\n") {
+		lower := strings.ToLower(line)
+		switch {
+		case strings.Contains(lower, "ингредиенты"):
+			inside = true
+		case strings.Contains(lower, "пошаговый рецепт") || strings.Contains(lower, "приготовление"):
+			inside = false
+		case inside:
+			if ing, ok := parseIngredient(line); ok {
+				result = append(result, ing)
+			}
+		}
+	}
+	return result
+}
+
+// ParseServings достаёт число порций из строки «Порций: 1–2». Для диапазона берётся
+// середина: пищевая ценность на порцию — оценка, а не запас.
+func ParseServings(text string) (float64, bool) {
+	for _, line := range strings.Split(text, "\n") {
+		lower := strings.ToLower(line)
+		if !strings.Contains(lower, "порци") || strings.Contains(lower, "на 1 порци") {
+			continue
+		}
+		m := quantityRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		value, ok := parseNumber(m[1])
+		if m[2] != "" {
+			if upper, ok2 := parseNumber(m[2]); ok2 {
+				value = (value + upper) / 2
+			}
+		}
+		if ok && value > 0 {
+			return value, true
+		}
+	}
+	return 0, false
+}
+
+// ParseItem разбирает продукт, записанный одной строкой: «молоко 1 л», «200 г курицы», «соль»
+func ParseItem(text string) (Ingredient, bool) {
+	return parseIngredient("- " + strings.TrimSpace(text))
+}
+
+// SameProduct сравнивает продукты по всем значимым словам без окончаний и без учёта порядка:
+// «курица» и «курицы», «филе куриное» и «куриное филе». «Масло сливочное» и «масло
+// растительное», как и «сыр» и «сыр моцарелла», — разные продукты.
+func SameProduct(a, b string) bool {
+	sa, sb := models.ProductStems(a), models.ProductStems(b)
+	if len(sa) == 0 || len(sa) != len(sb) {
+		return false
+	}
+	for i := range sa {
+		if sa[i] != sb[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func parseIngredient(line string) (Ingredient, bool) {
+	line = strings.NewReplacer("*", "", "_", "", "`", "").Replace(line)
+	marker := listMarkerRe.FindString(line)
+	rest := strings.TrimSpace(line[len(marker):])
+
+	name, amount := rest, rest
+	if loc := separatorRe.FindStringIndex(rest); loc != nil {
+		name, amount = rest[:loc[0]], rest[loc[1]:]
+	} else if marker == "" {
+		return Ingredient{}, false
+	}
+
+	// Предпочитаем метрическое количество: в «½ стакана (100 г)» берём 100 г
+	matches := quantityRe.FindAllStringSubmatch(amount, -1)
+	var quantity []string
+	for _, m := range matches {
+		if quantity == nil || parseUnit(m[3]).metric() && !parseUnit(quantity[3]).metric() {
+			quantity = m
+		}
+	}
+
+	ing := Ingredient{Name: name}
+	if quantity != nil {
+		ing.Amount, ing.Unit = baseAmount(quantity, name)
+		// Перевод в базовые единицы линейный, поэтому нижняя граница — в той же пропорции
+		if low, ok := parseNumber(quantity[1]); ok && quantity[2] != "" {
+			if high, _ := parseNumber(quantity[2]); high > 0 {
+				ing.AmountMin = ing.Amount * low / high
+			}
+		}
+		if name == amount {
+			// Без разделителя количество записано прямо в названии: «- 200 г курицы», «- 2 яйца».
+			// Незнакомое слово после числа — это сам продукт, а не единица.
+			unitWord := ""
+			if ing.Unit != UnitPieces && parseUnit(quantity[3]) == unitOther {
+				unitWord, ing.Unit = quantity[3], UnitPieces
+			}
+			ing.Name = strings.Replace(name, quantity[0], unitWord, 1)
+		}
+	}
+
+	ing.Name = strings.Trim(parenthesesRe.ReplaceAllString(ing.Name, ""), " .,;:")
+	if ing.Name == "" {
+		return Ingredient{}, false
+	}
+	return ing, true
+}
+
+// baseAmount переводит количество в базовую единицу
+func baseAmount(sub []string, name string) (float64, string) {
+	value, _ := parseNumber(sub[1])
+	if sub[2] != "" {
+		value, _ = parseNumber(sub[2])
+	}
+
+	u := parseUnit(sub[3])
+	switch {
+	case u == unitGram:
+		return value, UnitGrams
+	case u == unitKilogram:
+		return value * 1000, UnitGrams
+	case u.metric() || u.kitchen():
+		ml := value * u.ml()
+		if d, ok := lookupDensity(name); ok && !d.liquid {
+			return ml * d.gPerMl, UnitGrams
+		}
+		return ml, UnitMl
+	}
+
+	unitText := strings.ToLower(strings.TrimSuffix(sub[3], "."))
+	if unitText == "" || unitText == "шт" {
+		return value, UnitPieces
+	}
+	return value, unitText
+}
@@ -0,0 +1,133 @@
+package servings
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseIngredients(t *testing.T) {
+	text := `*Ингредиенты*
+1. Куриное филе — 300 г
+2. Мука — 2 ст. л.
+3. Молоко — 1 стакан
+4. Яйца — 2–3 шт
+5. Соль — по вкусу
+6. Чеснок — 2 зубчика
+7. Картофель — 1 кг
+8. Сметана (для подачи) — 50 г
+- 200 г сыра
+
+*Пошаговый рецепт*
+1. Духовка — 180 градусов`
+
+	want := []Ingredient{
+		{Name: "Куриное филе", Amount: 300, Unit: UnitGrams},
+		{Name: "Мука", Amount: 19.5, Unit: UnitGrams},
+		{Name: "Молоко", Amount: 200, Unit: UnitMl},
+		{Name: "Яйца", Amount: 3, AmountMin: 2, Unit: UnitPieces},
+		{Name: "Соль"},
+		{Name: "Чеснок", Amount: 2, Unit: "зубчика"},
+		{Name: "Картофель", Amount: 1000, Unit: UnitGrams},
+		{Name: "Сметана", Amount: 50, Unit: UnitGrams},
+		{Name: "сыра", Amount: 200, Unit: UnitGrams},
+	}
+
+	got := ParseIngredients(text)
+	if len(got) != len(want) {
+		t.Fatalf("ParseIngredients: %d ингредиентов, ожидалось %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		g := got[i]
+		g.Amount, g.AmountMin = round(g.Amount), round(g.AmountMin)
+		if !reflect.DeepEqual(g, want[i]) {
+			t.Errorf("ингредиент %d: %+v; ожидалось %+v", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestIngredientTypical(t *testing.T) {
+	tests := []struct {
+		ing  Ingredient
+		want float64
+	}{
+		{Ingredient{Amount: 3, AmountMin: 2}, 2.5},
+		{Ingredient{Amount: 200}, 200},
+		{Ingredient{}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.ing.Typical(); got != tt.want {
+			t.Errorf("Typical(%+v) = %v; ожидалось %v", tt.ing, got, tt.want)
+		}
+	}
+}
+
+func TestParseServings(t *testing.T) {
+	tests := []struct {
+		text string
+		want float64
+		ok   bool
+	}{
+		{"*Омлет*\nПорций: 2", 2, true},
+		{"Порций: 1–2", 1.5, true},
+		{"📊 Пищевая ценность (на 1 порцию, ~350 г)\nПорции: 4", 4, true},
+		{"📊 Пищевая ценность (на 1 порцию)", 0, false},
+		{"Рецепт без порций", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseServings(tt.text)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseServings(%q) = %v, %v; ожидалось %v, %v", tt.text, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseItem(t *testing.T) {
+	tests := []struct {
+		text string
+		want Ingredient
+		ok   bool
+	}{
+		{"молоко 1 л", Ingredient{Name: "молоко", Amount: 1000, Unit: UnitMl}, true},
+		{"200 г курицы", Ingredient{Name: "курицы", Amount: 200, Unit: UnitGrams}, true},
+		{"яйца 10 шт", Ingredient{Name: "яйца", Amount: 10, Unit: UnitPieces}, true},
+		{"соль", Ingredient{Name: "соль"}, true},
+		{"  ", Ingredient{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseItem(tt.text)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseItem(%q) = %+v, %v; ожидалось %+v, %v", tt.text, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSameProduct(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Курица", "курицы", true},
+		{"Яйца", "яйцо", true},
+		{"Филе куриное", "куриное филе", true},
+		{"Лук", "лук (репчатый)", true},
+		{"Свёкла", "свекла", true},
+		{"Масло сливочное", "масло растительное", false},
+		{"Сыр", "сыр твёрдый", false},
+		{"Мука", "Сахар", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := SameProduct(tt.a, tt.b); got != tt.want {
+			t.Errorf("SameProduct(%q, %q) = %v; ожидалось %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// round убирает погрешность плотности: 2 ст. л. муки — 19,5 г, а не 19,500000000000004
+func round(v float64) float64 {
+	return float64(int(v*1000+0.5)) / 1000
+}
@@ -0,0 +1,401 @@
+// Package servings пересчитывает количество ингредиентов в готовом рецепте
+// на другое число порций и переводит меры (граммы ↔ стаканы и ложки) без обращения к модели.
+//
+// Рецепт — текст в формате промпта: блок «Ингредиенты» со строками вида
+// «1. Продукт — 200 г» и строка «Порций: 1–2».
+package servings
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Units — в каких мерах выводить количество
+type Units string
+
+const (
+	UnitsOriginal Units = "o" // как в рецепте
+	UnitsMetric   Units = "m" // граммы и миллилитры
+	UnitsKitchen  Units = "k" // стаканы и ложки
+)
+
+// ParseUnits разбирает код мер из callback-данных
+func ParseUnits(s string) (Units, bool) {
+	switch u := Units(s); u {
+	case UnitsOriginal, UnitsMetric, UnitsKitchen:
+		return u, true
+	}
+	return "", false
+}
+
+// Допустимый множитель порций
+const (
+	MinFactor = 0.25
+	MaxFactor = 20
+)
+
+// unit — нормализованная единица измерения
+type unit int
+
+const (
+	unitOther unit = iota // штуки, зубчики и т.п.: только масштабируются
+	unitGram
+	unitKilogram
+	unitMl
+	unitLiter
+	unitTsp
+	unitTbsp
+	unitCup
+)
+
+// units — написания единиц без точек и пробелов
+var units = map[string]unit{
+	"г":        unitGram,
+	"гр":       unitGram,
+	"грамм":    unitGram,
+	"грамма":   unitGram,
+	"граммов":  unitGram,
+	"кг":       unitKilogram,
+	"мл":       unitMl,
+	"л":        unitLiter,
+	"литр":     unitLiter,
+	"литра":    unitLiter,
+	"литров":   unitLiter,
+	"чл":       unitTsp,
+	"стл":      unitTbsp,
+	"стакан":   unitCup,
+	"стакана":  unitCup,
+	"стаканов": unitCup,
+}
+
+func (u unit) metric() bool  { return u >= unitGram && u <= unitLiter }
+func (u unit) kitchen() bool { return u >= unitTsp }
+
+// ml — объём одной единицы в миллилитрах
+func (u unit) ml() float64 {
+	switch u {
+	case unitLiter:
+		return 1000
+	case unitTsp:
+		return mlPerTsp
+	case unitTbsp:
+		return mlPerTbsp
+	case unitCup:
+		return mlPerCup
+	}
+	return 1
+}
+
+const number = `\d+\s+\d+/\d+|\d+/\d+|\d+(?:[.,]\d+)?|[½¼¾⅓⅔]`
+
+var (
+	// quantityRe — количество: число или диапазон и, возможно, единица
+	quantityRe = regexp.MustCompile(`(` + number + `)(?:\s*[–—-]\s*(` + number + `))?(?:\s*((?:ст|ч)\.?\s?л\.?|[а-яёА-ЯЁ]+\.?))?`)
+	// listMarkerRe — номер или маркер пункта списка
+	listMarkerRe = regexp.MustCompile(`^\s*(?:\d+[.)]|[-•*–—])\s+`)
+	// separatorRe — разделитель названия продукта и количества
+	separatorRe = regexp.MustCompile(`\s+[—–-]\s+|:\s+`)
+)
+
+// Scale пересчитывает ингредиенты и число порций рецепта в factor раз и выводит
+// количество в мерах target. Остальной текст, включая пищевую ценность на порцию, не меняется.
+func Scale(text string, factor float64, target Units) string {
+	lines := strings.Split(text, "\n")
+	inside := false
+	for i, line := range lines {
+		lower := strings.ToLower(line)
+		servings := strings.Contains(lower, "порци") && !strings.Contains(lower, "на 1 порци")
+		switch {
+		case strings.Contains(lower, "ингредиенты"):
+			inside = true
+			if servings {
+				lines[i] = scaleServings(line, factor)
+			}
+		case servings:
+			lines[i] = scaleServings(line, factor)
+		case strings.Contains(lower, "пошаговый рецепт") || strings.Contains(lower, "приготовление"):
+			inside = false
+		case inside:
+			lines[i] = scaleIngredient(line, factor, target)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// scaleServings умножает числа в строке «Порций: 1–2»
+func scaleServings(line string, factor float64) string {
+	return quantityRe.ReplaceAllStringFunc(line, func(m string) string {
+		sub := quantityRe.FindStringSubmatch(m)
+		return scaleMatch(sub, factor, unitOther, sub[3])
+	})
+}
+
+// scaleIngredient пересчитывает строку ингредиента «1. Продукт — 200 г (1 стакан)»
+func scaleIngredient(line string, factor float64, target Units) string {
+	marker := listMarkerRe.FindString(line)
+	rest := line[len(marker):]
+
+	// Название продукта нужно для плотности; без разделителя вся строка — и название, и количество
+	name, amount := rest, rest
+	prefix := marker
+	if loc := separatorRe.FindStringIndex(rest); loc != nil {
+		name, amount = rest[:loc[0]], rest[loc[1]:]
+		prefix = marker + rest[:loc[1]]
+	} else if marker == "" {
+		return line
+	}
+
+	matches := quantityRe.FindAllStringSubmatchIndex(amount, -1)
+	if len(matches) == 0 {
+		return line
+	}
+
+	// Если в строке уже есть количество в нужных мерах (например, «½ стакана (100 г)»),
+	// остальные количества только масштабируются
+	convert := target != UnitsOriginal
+	for _, loc := range matches {
+		u := parseUnit(submatch(amount, loc, 3))
+		if target == UnitsMetric && u.metric() || target == UnitsKitchen && u.kitchen() {
+			convert = false
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	last := 0
+	for _, loc := range matches {
+		// «2,5%» — жирность, а не количество
+		if loc[1] < len(amount) && amount[loc[1]] == '%' {
+			continue
+		}
+		sb.WriteString(amount[last:loc[0]])
+		sub := make([]string, 4)
+		for i := range sub {
+			sub[i] = submatch(amount, loc, i)
+		}
+
+		u := parseUnit(sub[3])
+		if convert {
+			sb.WriteString(convertMatch(sub, factor, u, name, target))
+			convert = false
+		} else {
+			sb.WriteString(scaleMatch(sub, factor, u, sub[3]))
+		}
+		last = loc[1]
+	}
+	sb.WriteString(amount[last:])
+	return sb.String()
+}
+
+func submatch(s string, loc []int, i int) string {
+	if loc[2*i] < 0 {
+		return ""
+	}
+	return s[loc[2*i]:loc[2*i+1]]
+}
+
+// parseUnit нормализует написание единицы: «ст. л.» → «стл»
+func parseUnit(s string) unit {
+	key := strings.ToLower(strings.NewReplacer(".", "", " ", "").Replace(s))
+	if key == "" {
+		return unitOther
+	}
+	u, ok := units[key]
+	if !ok {
+		return unitOther
+	}
+	return u
+}
+
+// scaleMatch масштабирует количество (или диапазон), оставляя единицу как есть
+func scaleMatch(sub []string, factor float64, u unit, unitText string) string {
+	from, _ := parseNumber(sub[1])
+	text := formatAmount(from*factor, u)
+	if sub[2] != "" {
+		to, _ := parseNumber(sub[2])
+		text += "–" + formatAmount(to*factor, u)
+	}
+	// «стакан» согласуем с новым числом: 1 стакан → 2 стакана
+	if u == unitCup {
+		unitText = cupWord(roundTo(from*factor, 0.25))
+		if sub[2] != "" {
+			unitText = "стакана"
+		}
+	}
+	if unitText != "" {
+		text += " " + unitText
+	}
+	return text
+}
+
+// convertMatch масштабирует количество и переводит его в меры target.
+// Граммы и объём связываются через плотность продукта; если она неизвестна,
+// количество в граммах не переводится.
+func convertMatch(sub []string, factor float64, u unit, name string, target Units) string {
+	if u == unitOther {
+		return scaleMatch(sub, factor, u, sub[3])
+	}
+	d, known := lookupDensity(name)
+
+	// Переводим в миллилитры (объём) или граммы (масса)
+	value, _ := parseNumber(sub[1])
+	if sub[2] != "" {
+		to, _ := parseNumber(sub[2])
+		value = (value + to) / 2
+	}
+	value *= factor
+
+	var ml, grams float64
+	switch u {
+	case unitGram:
+		grams = value
+	case unitKilogram:
+		grams = value * 1000
+	default:
+		ml = value * u.ml()
+	}
+
+	switch target {
+	case UnitsMetric:
+		if ml > 0 && known && !d.liquid {
+			grams, ml = ml*d.gPerMl, 0
+		}
+		if grams > 0 {
+			return formatMetric(grams, "г", "кг")
+		}
+		return formatMetric(ml, "мл", "л")
+	case UnitsKitchen:
+		if grams > 0 {
+			if !known {
+				return scaleMatch(sub, factor, u, sub[3])
+			}
+			ml = grams / d.gPerMl
+		}
+		return formatKitchen(ml)
+	}
+	return scaleMatch(sub, factor, u, sub[3])
+}
+
+// formatMetric — «250 г», «1,2 кг»
+func formatMetric(v float64, small, large string) string {
+	if v >= 1000 {
+		return formatDecimal(math.Round(v/100)/10) + " " + large
+	}
+	return formatAmount(v, unitGram) + " " + small
+}
+
+// formatKitchen выражает объём в стаканах, столовых или чайных ложках
+func formatKitchen(ml float64) string {
+	switch {
+	case ml >= mlPerCup/2:
+		cups := roundTo(ml/mlPerCup, 0.25)
+		return formatFraction(cups) + " " + cupWord(cups)
+	case ml >= mlPerTbsp:
+		return formatFraction(roundTo(ml/mlPerTbsp, 0.5)) + " ст. л."
+	default:
+		return formatFraction(math.Max(roundTo(ml/mlPerTsp, 0.25), 0.25)) + " ч. л."
+	}
+}
+
+// cupWord согласует слово «стакан» с числом
+func cupWord(n float64) string {
+	if n != math.Trunc(n) {
+		return "стакана"
+	}
+	i := int(n)
+	switch {
+	case i%10 == 1 && i%100 != 11:
+		return "стакан"
+	case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+		return "стакана"
+	}
+	return "стаканов"
+}
+
+// formatAmount округляет количество под единицу: граммы — до 5 г при больших
+// значениях, штуки — до половинок
+func formatAmount(v float64, u unit) string {
+	switch {
+	case u == unitGram || u == unitMl:
+		if v >= 100 {
+			return formatDecimal(roundTo(v, 5))
+		}
+		if v >= 10 {
+			return formatDecimal(math.Round(v))
+		}
+		return formatDecimal(math.Round(v*10) / 10)
+	case u.kitchen():
+		return formatFraction(roundTo(v, 0.25))
+	case v >= 10:
+		return formatDecimal(math.Round(v))
+	}
+	return formatDecimal(math.Max(roundTo(v, 0.5), 0.5))
+}
+
+// formatFraction — «1½», «¾»
+func formatFraction(v float64) string {
+	whole := math.Floor(v)
+	fractions := map[float64]string{0.25: "¼", 0.5: "½", 0.75: "¾"}
+	frac, ok := fractions[v-whole]
+	if !ok {
+		return formatDecimal(v)
+	}
+	if whole == 0 {
+		return frac
+	}
+	return strconv.Itoa(int(whole)) + frac
+}
+
+// formatDecimal выводит число без лишних нулей и с запятой: 1,5
+func formatDecimal(v float64) string {
+	return strings.ReplaceAll(strconv.FormatFloat(v, 'f', -1, 64), ".", ",")
+}
+
+func roundTo(v, step float64) float64 {
+	return math.Round(v/step) * step
+}
+
+// parseNumber разбирает «2», «1,5», «1/2», «1 1/2», «½»
+func parseNumber(s string) (float64, bool) {
+	switch s {
+	case "½":
+		return 0.5, true
+	case "¼":
+		return 0.25, true
+	case "¾":
+		return 0.75, true
+	case "⅓":
+		return 1.0 / 3, true
+	case "⅔":
+		return 2.0 / 3, true
+	}
+
+	var whole float64
+	if fields := strings.Fields(s); len(fields) == 2 {
+		whole, _ = strconv.ParseFloat(fields[0], 64)
+		s = fields[1]
+	}
+	if num, den, ok := strings.Cut(s, "/"); ok {
+		n, err1 := strconv.ParseFloat(strings.TrimSpace(num), 64)
+		d, err2 := strconv.ParseFloat(strings.TrimSpace(den), 64)
+		if err1 != nil || err2 != nil || d == 0 {
+			return 0, false
+		}
+		return whole + n/d, true
+	}
+	v, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	return whole + v, err == nil
+}
+
+// ParseFactor разбирает множитель, введённый пользователем: «2», «1,5», «x3», «×0.5»
+func ParseFactor(s string) (float64, bool) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	s = strings.TrimLeft(s, "x×х ")
+	v, ok := parseNumber(s)
+	if !ok || v < MinFactor || v > MaxFactor {
+		return 0, false
+	}
+	return v, true
+}
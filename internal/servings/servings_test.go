@@ -0,0 +1,128 @@
+package servings
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScale(t *testing.T) {
+	tests := []struct {
+		line   string
+		factor float64
+		target Units
+		want   string
+	}{
+		{"1. Мука — 200 г", 2, UnitsOriginal, "1. Мука — 400 г"},
+		{"2. Яйца — 2 шт", 1.5, UnitsOriginal, "2. Яйца — 3 шт"},
+		{"3. Молоко — 1 стакан", 2, UnitsOriginal, "3. Молоко — 2 стакана"},
+		{"4. Соль — 1–2 ч. л.", 2, UnitsOriginal, "4. Соль — 2–4 ч. л."},
+		{"5. Молоко 2,5% — 200 мл", 2, UnitsOriginal, "5. Молоко 2,5% — 400 мл"},
+		{"6. Мука — 1 стакан", 1, UnitsMetric, "6. Мука — 130 г"},
+		{"7. Молоко — 1 стакан", 1, UnitsMetric, "7. Молоко — 200 мл"},
+		{"8. Сахар — 100 г", 1, UnitsKitchen, "8. Сахар — ½ стакана"},
+		{"9. Масло растительное — 30 мл", 1, UnitsKitchen, "9. Масло растительное — 2 ст. л."},
+		// Плотность неизвестна — граммы в ложки не переводятся
+		{"10. Курица — 500 г", 1, UnitsKitchen, "10. Курица — 500 г"},
+		// Метрическое количество уже есть — «½ стакана» только масштабируется
+		{"11. Рис — ½ стакана (90 г)", 2, UnitsMetric, "11. Рис — 1 стакан (180 г)"},
+		{"12. Соль — по вкусу", 2, UnitsOriginal, "12. Соль — по вкусу"},
+	}
+
+	for _, tt := range tests {
+		text := "*Ингредиенты*\n" + tt.line + "\n*Пошаговый рецепт*\n1. Варите 10 минут"
+		got := Scale(text, tt.factor, tt.target)
+		want := "*Ингредиенты*\n" + tt.want + "\n*Пошаговый рецепт*\n1. Варите 10 минут"
+		if got != want {
+			t.Errorf("Scale(%q, %v, %q):\nполучено  %q\nожидалось %q", tt.line, tt.factor, tt.target, got, want)
+		}
+	}
+}
+
+func TestScaleKeepsTextOutsideIngredients(t *testing.T) {
+	text := "*Омлет*\nПорций: 2\n\n*Ингредиенты*\n1. Яйца — 3 шт\n\n*Пошаговый рецепт*\n1. Взбейте 2 яйца\n\n📊 Пищевая ценность (на 1 порцию)\n- *Белки*: 12 г"
+	want := "*Омлет*\nПорций: 4\n\n*Ингредиенты*\n1. Яйца — 6 шт\n\n*Пошаговый рецепт*\n1. Взбейте 2 яйца\n\n📊 Пищевая ценность (на 1 порцию)\n- *Белки*: 12 г"
+
+	if got := Scale(text, 2, UnitsOriginal); got != want {
+		t.Errorf("Scale:\nполучено  %q\nожидалось %q", got, want)
+	}
+}
+
+func TestParseFactor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"2", 2, true},
+		{"1,5", 1.5, true},
+		{"x3", 3, true},
+		{"×0.5", 0.5, true},
+		{" 1/2 ", 0.5, true},
+		{"½", 0.5, true},
+		{"0.1", 0, false},
+		{"25", 0, false},
+		{"два", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseFactor(tt.in)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseFactor(%q) = %v, %v; ожидалось %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDensity(t *testing.T) {
+	tests := []struct {
+		name string
+		want float64
+		ok   bool
+	}{
+		{"Масло растительное", 0.92, true},
+		// Уточнённые продукты стоят в таблице раньше общих
+		{"Сливочное масло", 0.95, true},
+		{"Сахарная пудра", 0.55, true},
+		{"Рисовое молоко", 1.03, true},
+		{"Мёд", 1.4, true},
+		{"Курица", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Density(tt.name)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("Density(%q) = %v, %v; ожидалось %v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFormatKitchen(t *testing.T) {
+	tests := []struct {
+		ml   float64
+		want string
+	}{
+		{400, "2 стакана"},
+		{250, "1¼ стакана"},
+		{200, "1 стакан"},
+		{45, "3 ст. л."},
+		{5, "1 ч. л."},
+		{1, "¼ ч. л."},
+	}
+
+	for _, tt := range tests {
+		if got := formatKitchen(tt.ml); got != tt.want {
+			t.Errorf("formatKitchen(%v) = %q; ожидалось %q", tt.ml, got, tt.want)
+		}
+	}
+}
+
+func TestCupWord(t *testing.T) {
+	var got []string
+	for _, n := range []float64{1, 2, 5, 11, 21, 22, 1.5} {
+		got = append(got, cupWord(n))
+	}
+	want := "стакан стакана стаканов стаканов стакан стакана стакана"
+	if strings.Join(got, " ") != want {
+		t.Errorf("cupWord: %q; ожидалось %q", strings.Join(got, " "), want)
+	}
+}
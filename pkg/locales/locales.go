@@ -11,40 +11,65 @@ var localesJSON []byte
 
 // Locales содержит все текстовые строки из locales.json
 type Locales struct {
-	MainMenu      MainMenu      `json:"main_menu"`
-	SettingsMenu  SettingsMenu  `json:"settings_menu"`
-	DietMenu      DietMenu      `json:"diet_menu"`
-	GoalMenu      GoalMenu      `json:"goal_menu"`
-	AllergiesMenu AllergiesMenu `json:"allergies_menu"`
-	HabitsMenu    HabitsMenu    `json:"habits_menu"`
-	DislikesMenu  DislikesMenu  `json:"dislikes_menu"`
-	LikesMenu     LikesMenu     `json:"likes_menu"`
-	ClearConfirm  ClearConfirm  `json:"clear_confirm"`
-	ClearSuccess  ClearSuccess  `json:"clear_success"`
+	MainMenu         MainMenu         `json:"main_menu"`
+	SettingsMenu     SettingsMenu     `json:"settings_menu"`
+	DietMenu         DietMenu         `json:"diet_menu"`
+	RestrictionsMenu RestrictionsMenu `json:"restrictions_menu"`
+	BodyMenu         BodyMenu         `json:"body_menu"`
+	Recipes          Recipes          `json:"recipes"`
+	Feedback         Feedback         `json:"feedback"`
+	Servings         Servings         `json:"servings"`
+	Shopping         Shopping         `json:"shopping"`
+	Pantry           Pantry           `json:"pantry"`
+	Plan             Plan             `json:"plan"`
+	Diary            Diary            `json:"diary"`
+	Nutrition        Nutrition        `json:"nutrition"`
+	Notify           Notify           `json:"notify"`
+	GoalMenu         GoalMenu         `json:"goal_menu"`
+	AllergiesMenu    AllergiesMenu    `json:"allergies_menu"`
+	HabitsMenu       HabitsMenu       `json:"habits_menu"`
+	DislikesMenu     DislikesMenu     `json:"dislikes_menu"`
+	LikesMenu        LikesMenu        `json:"likes_menu"`
+	ClearConfirm     ClearConfirm     `json:"clear_confirm"`
+	ClearSuccess     ClearSuccess     `json:"clear_success"`
+	LimitsMenu       LimitsMenu       `json:"limits_menu"`
+	QuotaExceeded    QuotaExceeded    `json:"quota_exceeded"`
+	MyData           MyData           `json:"my_data"`
+	DeleteConfirm    DeleteConfirm    `json:"delete_confirm"`
+	DeleteSuccess    DeleteSuccess    `json:"delete_success"`
 }
 
 type MainMenu struct {
 	Text    string `json:"text"`
 	Buttons struct {
+		Pantry   string `json:"pantry"`
+		Diary    string `json:"diary"`
 		Settings string `json:"settings"`
 	} `json:"buttons"`
 }
 
 type SettingsMenu struct {
-	Text   string `json:"text"`
-	Fields struct {
-		Diet      string `json:"diet"`
-		Goal      string `json:"goal"`
-		Allergies string `json:"allergies"`
-		Habits    string `json:"habits"`
+	Text        string `json:"text"`
+	Unavailable string `json:"unavailable"`
+	Fields      struct {
+		Diet         string `json:"diet"`
+		Restrictions string `json:"restrictions"`
+		Goal         string `json:"goal"`
+		Allergies    string `json:"allergies"`
+		Habits       string `json:"habits"`
+		Body         string `json:"body"`
 	} `json:"fields"`
 	Buttons struct {
-		Diet      string `json:"diet"`
-		Goal      string `json:"goal"`
-		Allergies string `json:"allergies"`
-		Habits    string `json:"habits"`
-		Clear     string `json:"clear"`
-		Back      string `json:"back"`
+		Diet         string `json:"diet"`
+		Restrictions string `json:"restrictions"`
+		Goal         string `json:"goal"`
+		Allergies    string `json:"allergies"`
+		Habits       string `json:"habits"`
+		Body         string `json:"body"`
+		Limits       string `json:"limits"`
+		Notify       string `json:"notify"`
+		Clear        string `json:"clear"`
+		Back         string `json:"back"`
 	} `json:"buttons"`
 }
 
@@ -62,16 +87,218 @@ type DietMenu struct {
 	} `json:"buttons"`
 }
 
-type GoalMenu struct {
-	Text    string `json:"text"`
-	Success string `json:"success"`
+type RestrictionsMenu struct {
+	Text      string `json:"text"`
+	Violation string `json:"violation"` // %s — ограничения и найденные продукты
+	Buttons   struct {
+		Done string `json:"done"`
+	} `json:"buttons"`
+}
+
+type BodyMenu struct {
+	Text         string `json:"text"` // %s — параметры и нормы или Empty
+	Empty        string `json:"empty"`
+	Profile      string `json:"profile"` // пол, возраст, рост, вес, активность
+	Targets      string `json:"targets"` // суточная норма и норма на приём пищи
+	Summary      string `json:"summary"` // строка для экрана настроек
+	SexText      string `json:"sex_text"`
+	AgeText      string `json:"age_text"`
+	HeightText   string `json:"height_text"`
+	WeightText   string `json:"weight_text"`
+	ActivityText string `json:"activity_text"`
+	Invalid      string `json:"invalid"` // %d–%d — допустимый диапазон
+	Success      string `json:"success"`
+	Sex          struct {
+		Male   string `json:"male"`
+		Female string `json:"female"`
+	} `json:"sex"`
 	Buttons struct {
+		Fill           string `json:"fill"`
+		Refill         string `json:"refill"`
+		Cancel         string `json:"cancel"`
 		BackToSettings string `json:"back_to_settings"`
-		BackToMain     string `json:"back_to_main"`
 	} `json:"buttons"`
 }
 
-type AllergiesMenu struct {
+type Recipes struct {
+	HistoryTitle   string `json:"history_title"`
+	FavoritesTitle string `json:"favorites_title"`
+	Page           string `json:"page"` // %d из %d
+	HistoryEmpty   string `json:"history_empty"`
+	FavoritesEmpty string `json:"favorites_empty"`
+	NotFound       string `json:"not_found"`
+	Buttons        struct {
+		Save       string `json:"save"`
+		Saved      string `json:"saved"`
+		Delete     string `json:"delete"`
+		BackToList string `json:"back_to_list"`
+		Prev       string `json:"prev"`
+		Next       string `json:"next"`
+		BackToMain string `json:"back_to_main"`
+		Another    string `json:"another"`
+		Spicier    string `json:"spicier"`
+		Lighter    string `json:"lighter"`
+		Faster     string `json:"faster"`
+		Cheaper    string `json:"cheaper"`
+	} `json:"buttons"`
+}
+
+type Feedback struct {
+	ReasonText  string `json:"reason_text"`
+	CommentText string `json:"comment_text"`
+	Thanks      string `json:"thanks"`
+	Buttons     struct {
+		Like    string `json:"like"`
+		Dislike string `json:"dislike"`
+		Other   string `json:"other"`
+		Skip    string `json:"skip"`
+	} `json:"buttons"`
+}
+
+type Servings struct {
+	Header     string `json:"header"` // ×%s, %s — множитель и меры
+	CustomText string `json:"custom_text"`
+	Invalid    string `json:"invalid"`
+	Units      struct {
+		Original string `json:"original"`
+		Metric   string `json:"metric"`
+		Kitchen  string `json:"kitchen"`
+	} `json:"units"`
+	Buttons struct {
+		Scale    string `json:"scale"`
+		Custom   string `json:"custom"`
+		Original string `json:"original"`
+		Metric   string `json:"metric"`
+		Kitchen  string `json:"kitchen"`
+		Back     string `json:"back"`
+		Cancel   string `json:"cancel"`
+	} `json:"buttons"`
+}
+
+type Shopping struct {
+	Title        string `json:"title"`
+	Empty        string `json:"empty"`
+	Added        string `json:"added"` // %d — сколько продуктов добавлено
+	NothingAdded string `json:"nothing_added"`
+	ClearConfirm string `json:"clear_confirm"`
+	Buttons      struct {
+		Add          string `json:"add"`
+		ClearChecked string `json:"clear_checked"`
+		ClearAll     string `json:"clear_all"`
+		Yes          string `json:"yes"`
+		No           string `json:"no"`
+		BackToMain   string `json:"back_to_main"`
+	} `json:"buttons"`
+}
+
+type Pantry struct {
+	Title           string `json:"title"`
+	Empty           string `json:"empty"`
+	AddText         string `json:"add_text"`
+	Added           string `json:"added"` // %d — сколько продуктов добавлено
+	NothingAdded    string `json:"nothing_added"`
+	NothingToCook   string `json:"nothing_to_cook"`
+	NothingToDeduct string `json:"nothing_to_deduct"`
+	DeductConfirm   string `json:"deduct_confirm"` // %s — что будет списано
+	Deducted        string `json:"deducted"`
+	Expired         string `json:"expired"`
+	Buttons         struct {
+		Add        string `json:"add"`
+		Cook       string `json:"cook"`
+		Used       string `json:"used"`
+		Deduct     string `json:"deduct"`
+		No         string `json:"no"`
+		Back       string `json:"back"`
+		BackToMain string `json:"back_to_main"`
+	} `json:"buttons"`
+}
+
+type Diary struct {
+	Title          string `json:"title"`
+	Empty          string `json:"empty"`
+	Logged         string `json:"logged"` // %s — блюдо, %d — калории
+	NoNutrition    string `json:"no_nutrition"`
+	WeekTitle      string `json:"week_title"` // %s — первый и последний день
+	WeekEmpty      string `json:"week_empty"`
+	Average        string `json:"average"` // %d — дней с записями
+	DefaultTargets string `json:"default_targets"`
+	Fields         struct {
+		Calories string `json:"calories"`
+		Protein  string `json:"protein"`
+		Fat      string `json:"fat"`
+		Carbs    string `json:"carbs"`
+	} `json:"fields"`
+	Buttons struct {
+		Log        string `json:"log"`
+		Week       string `json:"week"`
+		Today      string `json:"today"`
+		BackToMain string `json:"back_to_main"`
+	} `json:"buttons"`
+}
+
+type Notify struct {
+	Text  string `json:"text"` // %s — текущие настройки
+	Kinds struct {
+		Expiry string `json:"expiry"`
+		Dinner string `json:"dinner"`
+		Plan   string `json:"plan"`
+	} `json:"kinds"`
+	Timezone          string `json:"timezone"`
+	Quiet             string `json:"quiet"`
+	QuietOff          string `json:"quiet_off"`
+	Off               string `json:"off"`
+	TimeText          string `json:"time_text"` // %s — вид уведомления
+	TimezoneText      string `json:"timezone_text"`
+	QuietText         string `json:"quiet_text"`
+	InvalidTime       string `json:"invalid_time"`
+	InvalidTimezone   string `json:"invalid_timezone"`
+	InvalidQuiet      string `json:"invalid_quiet"`
+	Saved             string `json:"saved"`
+	ExpiryMessage     string `json:"expiry_message"` // %s — продукты
+	DinnerMessage     string `json:"dinner_message"`
+	DinnerPlanMessage string `json:"dinner_plan_message"` // %s — блюдо, %d — калории
+	DinnerRequest     string `json:"dinner_request"`
+	PlanMessage       string `json:"plan_message"` // %s — блюда на сегодня
+	Buttons           struct {
+		Time           string `json:"time"` // %s — время уведомления
+		Timezone       string `json:"timezone"`
+		Quiet          string `json:"quiet"`
+		QuietOff       string `json:"quiet_off"`
+		Cancel         string `json:"cancel"`
+		BackToSettings string `json:"back_to_settings"`
+		Suggest        string `json:"suggest"`
+		PlanRecipe     string `json:"plan_recipe"`
+		OpenPlan       string `json:"open_plan"`
+		Cook           string `json:"cook"`
+	} `json:"buttons"`
+}
+
+type Plan struct {
+	Title        string `json:"title"`
+	Intro        string `json:"intro"` // %d — дневная норма калорий
+	Generating   string `json:"generating"`
+	Swapping     string `json:"swapping"`
+	Ready        string `json:"ready"`
+	Unresolved   string `json:"unresolved"` // %s — список блюд
+	Failed       string `json:"failed"`
+	SwapFailed   string `json:"swap_failed"`
+	NotPlanned   string `json:"not_planned"`
+	Total        string `json:"total"` // %d — калории за день, %d — норма
+	RenewConfirm string `json:"renew_confirm"`
+	Buttons      struct {
+		Create     string `json:"create"`
+		Renew      string `json:"renew"`
+		Recipe     string `json:"recipe"` // %s — приём пищи
+		Swap       string `json:"swap"`
+		Prev       string `json:"prev"`
+		Next       string `json:"next"`
+		Yes        string `json:"yes"`
+		No         string `json:"no"`
+		BackToMain string `json:"back_to_main"`
+	} `json:"buttons"`
+}
+
+type GoalMenu struct {
 	Text    string `json:"text"`
 	Success string `json:"success"`
 	Buttons struct {
@@ -80,6 +307,20 @@ type AllergiesMenu struct {
 	} `json:"buttons"`
 }
 
+type AllergiesMenu struct {
+	Text         string `json:"text"`
+	OtherCurrent string `json:"other_current"` // %s — аллергии свободным текстом
+	OtherText    string `json:"other_text"`
+	Success      string `json:"success"`
+	Buttons      struct {
+		Other           string `json:"other"`
+		Done            string `json:"done"`
+		BackToAllergies string `json:"back_to_allergies"`
+		BackToSettings  string `json:"back_to_settings"`
+		BackToMain      string `json:"back_to_main"`
+	} `json:"buttons"`
+}
+
 type HabitsMenu struct {
 	Text    string `json:"text"`
 	Buttons struct {
@@ -125,6 +366,45 @@ type ClearSuccess struct {
 	} `json:"buttons"`
 }
 
+type LimitsMenu struct {
+	Text      string `json:"text"`
+	Remaining string `json:"remaining"`
+	Unlimited string `json:"unlimited"`
+	Buttons   struct {
+		BackToSettings string `json:"back_to_settings"`
+	} `json:"buttons"`
+}
+
+type QuotaExceeded struct {
+	Daily   string `json:"daily"`
+	Monthly string `json:"monthly"`
+}
+
+type MyData struct {
+	Caption string `json:"caption"`
+	Error   string `json:"error"`
+}
+
+type DeleteConfirm struct {
+	Text    string `json:"text"`
+	Buttons struct {
+		Yes string `json:"yes"`
+		No  string `json:"no"`
+	} `json:"buttons"`
+}
+
+type Nutrition struct {
+	Verified   string `json:"verified"`
+	Computed   string `json:"computed"`   // ккал, белки, жиры, углеводы
+	Unverified string `json:"unverified"` // ненайденные ингредиенты, ккал по найденным
+	NotFound   string `json:"not_found"`
+}
+
+type DeleteSuccess struct {
+	Text  string `json:"text"`
+	Error string `json:"error"`
+}
+
 var L *Locales
 
 func init() {
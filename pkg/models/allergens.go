@@ -0,0 +1,60 @@
+package models
+
+// Allergen — аллерген из справочника
+type Allergen struct {
+	Code       string // ключ в таблице allergens и в callback-данных
+	Name       string // название на кнопке и в настройках
+	PromptName string // название для модели, с уточнением продуктов
+}
+
+// Allergens — справочник основных аллергенов в порядке показа.
+// Коды должны совпадать с таблицей allergens (миграция 0008_allergens).
+var Allergens = []Allergen{
+	{"gluten", "Глютен", "глютен (пшеница, рожь, ячмень, овёс)"},
+	{"lactose", "Лактоза", "лактоза (молоко и молочные продукты)"},
+	{"nuts", "Орехи", "орехи (грецкие, миндаль, фундук, кешью, фисташки)"},
+	{"peanuts", "Арахис", "арахис и арахисовое масло"},
+	{"eggs", "Яйца", "яйца"},
+	{"fish", "Рыба", "рыба"},
+	{"shellfish", "Морепродукты", "ракообразные и моллюски (креветки, крабы, мидии, кальмары)"},
+	{"soy", "Соя", "соя и соевый соус"},
+	{"sesame", "Кунжут", "кунжут и кунжутное масло"},
+	{"celery", "Сельдерей", "сельдерей"},
+	{"mustard", "Горчица", "горчица"},
+	{"sulfites", "Сульфиты", "сульфиты (вино, сухофрукты)"},
+}
+
+// AllergenByCode ищет аллерген в справочнике
+func AllergenByCode(code string) (Allergen, bool) {
+	for _, a := range Allergens {
+		if a.Code == code {
+			return a, true
+		}
+	}
+	return Allergen{}, false
+}
+
+// HasAllergen проверяет, отмечен ли аллерген у пользователя
+func (p *UserPreferences) HasAllergen(code string) bool {
+	for _, c := range p.Allergens {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// AllergyList возвращает отмеченные аллергены (названия для модели) в порядке справочника
+// и свободный текст «другое» последним элементом
+func (p *UserPreferences) AllergyList() []string {
+	var list []string
+	for _, a := range Allergens {
+		if p.HasAllergen(a.Code) {
+			list = append(list, a.PromptName)
+		}
+	}
+	if p.Allergies != "" {
+		list = append(list, p.Allergies)
+	}
+	return list
+}
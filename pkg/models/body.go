@@ -0,0 +1,148 @@
+package models
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// Пол для формулы Миффлина — Сан Жеора
+const (
+	SexMale   = "m"
+	SexFemale = "f"
+)
+
+// ActivityLevel — уровень физической активности и коэффициент для расчёта TDEE
+type ActivityLevel struct {
+	Code   string
+	Name   string
+	Factor float64
+}
+
+// ActivityLevels — уровни активности в порядке показа
+var ActivityLevels = []ActivityLevel{
+	{"sedentary", "Сидячий образ жизни", 1.2},
+	{"light", "Тренировки 1–3 раза в неделю", 1.375},
+	{"moderate", "Тренировки 3–5 раз в неделю", 1.55},
+	{"active", "Тренировки 6–7 раз в неделю", 1.725},
+	{"very_active", "Физическая работа и спорт", 1.9},
+}
+
+// ActivityLevelByCode ищет уровень активности по коду
+func ActivityLevelByCode(code string) (ActivityLevel, bool) {
+	for _, a := range ActivityLevels {
+		if a.Code == code {
+			return a, true
+		}
+	}
+	return ActivityLevel{}, false
+}
+
+// Допустимые значения параметров тела
+const (
+	MinAge      = 14
+	MaxAge      = 100
+	MinHeightCm = 100
+	MaxHeightCm = 250
+	MinWeightKg = 30
+	MaxWeightKg = 300
+)
+
+// MealsPerDay — на сколько основных приёмов пищи делится дневная норма
+const MealsPerDay = 3
+
+// BodyProfile — параметры тела для расчёта нормы калорий.
+// Мастер заполняет поля по очереди, поэтому профиль может быть неполным.
+type BodyProfile struct {
+	UserID    int64     `json:"user_id"`
+	Sex       string    `json:"sex"` // SexMale или SexFemale
+	Age       int       `json:"age"`
+	HeightCm  float64   `json:"height_cm"`
+	WeightKg  float64   `json:"weight_kg"`
+	Activity  string    `json:"activity"` // код из ActivityLevels
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Complete проверяет, что заполнены все параметры для расчёта
+func (p *BodyProfile) Complete() bool {
+	if p == nil {
+		return false
+	}
+	_, ok := ActivityLevelByCode(p.Activity)
+	return ok && (p.Sex == SexMale || p.Sex == SexFemale) && p.Age > 0 && p.HeightCm > 0 && p.WeightKg > 0
+}
+
+// BMR — базовый обмен веществ по формуле Миффлина — Сан Жеора, ккал в сутки
+func (p *BodyProfile) BMR() float64 {
+	bmr := 10*p.WeightKg + 6.25*p.HeightCm - 5*float64(p.Age)
+	if p.Sex == SexMale {
+		return bmr + 5
+	}
+	return bmr - 161
+}
+
+// TDEE — суточный расход энергии с учётом активности, ккал
+func (p *BodyProfile) TDEE() float64 {
+	level, _ := ActivityLevelByCode(p.Activity)
+	return p.BMR() * level.Factor
+}
+
+// NutritionTargets — нормы калорий и БЖУ
+type NutritionTargets struct {
+	Calories int // ккал
+	Protein  int // г
+	Fat      int // г
+	Carbs    int // г
+}
+
+// KetoDailyCarbs — потолок углеводов в сутки на кето, г (правило ограничения — 20–30 г на порцию)
+const KetoDailyCarbs = 50
+
+// DailyTargets рассчитывает суточную норму под цель по весу (DietaryType)
+// и ограничения питания: дефицит 20% для похудения, профицит 15% для набора массы.
+// Белок считается от веса, жиры — долей от калорий, углеводы — остаток.
+func (p *BodyProfile) DailyTargets(dietaryType string, restrictions []string) NutritionTargets {
+	calories := p.TDEE()
+	proteinPerKg, fatShare := 1.4, 0.3
+	switch dietaryType {
+	case "Похудение":
+		calories *= 0.8
+		proteinPerKg = 1.8
+		// Не опускаемся ниже базового обмена
+		calories = math.Max(calories, p.BMR())
+	case "Набор массы":
+		calories *= 1.15
+		proteinPerKg, fatShare = 1.8, 0.25
+	}
+
+	return splitMacros(calories, proteinPerKg*p.WeightKg, fatShare, restrictions)
+}
+
+// splitMacros делит калории на БЖУ: белок задан, жиры — доля fatShare, углеводы — остаток.
+// На кето углеводы не выше KetoDailyCarbs, освободившиеся калории переходят в жиры.
+func splitMacros(calories, protein, fatShare float64, restrictions []string) NutritionTargets {
+	fat := calories * fatShare / 9
+	carbs := math.Max(calories-protein*4-fat*9, 0) / 4
+	if slices.Contains(restrictions, "keto") && carbs > KetoDailyCarbs {
+		carbs = KetoDailyCarbs
+		fat = math.Max(calories-protein*4-carbs*4, 0) / 9
+	}
+
+	return NutritionTargets{
+		Calories: int(math.Round(calories)),
+		Protein:  int(math.Round(protein)),
+		Fat:      int(math.Round(fat)),
+		Carbs:    int(math.Round(carbs)),
+	}
+}
+
+// MealTargets делит суточную норму на MealsPerDay приёмов пищи
+func (p *BodyProfile) MealTargets(dietaryType string, restrictions []string) NutritionTargets {
+	day := p.DailyTargets(dietaryType, restrictions)
+	return NutritionTargets{
+		Calories: day.Calories / MealsPerDay,
+		Protein:  day.Protein / MealsPerDay,
+		Fat:      day.Fat / MealsPerDay,
+		Carbs:    day.Carbs / MealsPerDay,
+	}
+}
@@ -0,0 +1,67 @@
+package models
+
+import "testing"
+
+func TestDailyTargets(t *testing.T) {
+	// TDEE: (10×80 + 6,25×180 − 5×30 + 5) × 1,55 = 2759 ккал
+	profile := &BodyProfile{Sex: SexMale, Age: 30, HeightCm: 180, WeightKg: 80, Activity: "moderate"}
+
+	tests := []struct {
+		name         string
+		dietaryType  string
+		restrictions []string
+		want         NutritionTargets
+	}{
+		{"поддержание", "", nil, NutritionTargets{Calories: 2759, Protein: 112, Fat: 92, Carbs: 371}},
+		{"похудение", "Похудение", nil, NutritionTargets{Calories: 2207, Protein: 144, Fat: 74, Carbs: 242}},
+		{"набор массы", "Набор массы", nil, NutritionTargets{Calories: 3173, Protein: 144, Fat: 88, Carbs: 451}},
+		// На кето углеводы ограничены, остаток калорий уходит в жиры
+		{"похудение на кето", "Похудение", []string{"keto"}, NutritionTargets{Calories: 2207, Protein: 144, Fat: 159, Carbs: KetoDailyCarbs}},
+		{"другие ограничения", "Похудение", []string{"vegan"}, NutritionTargets{Calories: 2207, Protein: 144, Fat: 74, Carbs: 242}},
+	}
+
+	for _, tt := range tests {
+		if got := profile.DailyTargets(tt.dietaryType, tt.restrictions); got != tt.want {
+			t.Errorf("%s: DailyTargets = %+v; ожидалось %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDailyTargetsNotBelowBMR(t *testing.T) {
+	// Дефицит 20% при сидячем образе жизни опустил бы калории ниже базового обмена
+	profile := &BodyProfile{Sex: SexFemale, Age: 60, HeightCm: 150, WeightKg: 45, Activity: "sedentary"}
+	if got, bmr := profile.DailyTargets("Похудение", nil).Calories, int(profile.BMR()+0.5); got != bmr {
+		t.Errorf("калории %d; ожидался базовый обмен %d", got, bmr)
+	}
+}
+
+func TestSplitMacros(t *testing.T) {
+	tests := []struct {
+		name         string
+		calories     float64
+		protein      float64
+		restrictions []string
+		want         NutritionTargets
+	}{
+		{"обычное", 2000, 100, nil, NutritionTargets{Calories: 2000, Protein: 100, Fat: 67, Carbs: 250}},
+		{"кето", 2000, 100, []string{"keto"}, NutritionTargets{Calories: 2000, Protein: 100, Fat: 156, Carbs: 50}},
+		// Углеводов и так меньше потолка — кето ничего не меняет
+		{"кето с малым остатком", 1200, 200, []string{"keto"}, NutritionTargets{Calories: 1200, Protein: 200, Fat: 40, Carbs: 10}},
+		// Белок съел все калории — углеводы не уходят в минус
+		{"белок больше калорий", 500, 200, nil, NutritionTargets{Calories: 500, Protein: 200, Fat: 17, Carbs: 0}},
+	}
+
+	for _, tt := range tests {
+		if got := splitMacros(tt.calories, tt.protein, 0.3, tt.restrictions); got != tt.want {
+			t.Errorf("%s: splitMacros = %+v; ожидалось %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMealTargets(t *testing.T) {
+	profile := &BodyProfile{Sex: SexMale, Age: 30, HeightCm: 180, WeightKg: 80, Activity: "moderate"}
+	want := NutritionTargets{Calories: 735, Protein: 48, Fat: 53, Carbs: 16}
+	if got := profile.MealTargets("Похудение", []string{"keto"}); got != want {
+		t.Errorf("MealTargets = %+v; ожидалось %+v", got, want)
+	}
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Nutrition — пищевая ценность: калории и БЖУ
+type Nutrition struct {
+	Calories float64 `json:"calories"` // ккал
+	Protein  float64 `json:"protein"`  // г
+	Fat      float64 `json:"fat"`      // г
+	Carbs    float64 `json:"carbs"`    // г
+}
+
+// Add возвращает сумму пищевой ценности
+func (n Nutrition) Add(o Nutrition) Nutrition {
+	return Nutrition{
+		Calories: n.Calories + o.Calories,
+		Protein:  n.Protein + o.Protein,
+		Fat:      n.Fat + o.Fat,
+		Carbs:    n.Carbs + o.Carbs,
+	}
+}
+
+// DiaryEntry — запись дневника питания: съеденная порция блюда
+type DiaryEntry struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Day       string    `json:"day"`                 // DayLayout по часовому поясу пользователя
+	RecipeID  int64     `json:"recipe_id,omitempty"` // 0 — рецепт удалён или запись не из рецепта
+	Title     string    `json:"title"`
+	Nutrition Nutrition `json:"nutrition"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DailyNutritionTargets — суточная норма для дневника: по параметрам тела,
+// а без них — калории по цели по весу (см. PlanDailyCalories) и БЖУ
+// в пропорции 20/30/50% от калорий (на кето — с потолком углеводов)
+func DailyNutritionTargets(prefs *UserPreferences) NutritionTargets {
+	if prefs != nil && prefs.Body.Complete() {
+		return prefs.Body.DailyTargets(prefs.DietaryType, prefs.DietRestrictions)
+	}
+	var restrictions []string
+	if prefs != nil {
+		restrictions = prefs.DietRestrictions
+	}
+	calories := float64(PlanDailyCalories(prefs))
+	return splitMacros(calories, calories*0.2/4, 0.3, restrictions)
+}
+
+// nutritionRe — значение в строке пищевой ценности: «~420», «25 г», «20–25 г», «12,5 г»
+var nutritionRe = regexp.MustCompile(`(\d+(?:[.,]\d+)?)(?:\s*[-–—]\s*(\d+(?:[.,]\d+)?))?`)
+
+// nutritionFields — начала названий показателей в ответе модели
+var nutritionFields = []struct {
+	stems []string
+	set   func(n *Nutrition, v float64)
+}{
+	{[]string{"ккал", "калори", "энергетическ"}, func(n *Nutrition, v float64) { n.Calories = v }},
+	{[]string{"белк", "белок"}, func(n *Nutrition, v float64) { n.Protein = v }},
+	{[]string{"жир"}, func(n *Nutrition, v float64) { n.Fat = v }},
+	{[]string{"углевод"}, func(n *Nutrition, v float64) { n.Carbs = v }},
+}
+
+// ParseNutrition достаёт пищевую ценность на порцию из блока «Пищевая ценность»
+// рецепта: строки «- *Ккал*: ~420», «- *Белки*: 25 г». Для диапазона берётся середина.
+// false — в блоке нет хотя бы одного показателя или калорий.
+func ParseNutrition(text string) (Nutrition, bool) {
+	lines := strings.Split(text, "\n")
+	block, ok := nutritionBlock(lines)
+	if !ok {
+		return Nutrition{}, false
+	}
+
+	var n Nutrition
+	for field, i := range block {
+		_, value, loc, _ := nutritionLine(lines[i])
+		v := parseNutritionNumber(value[loc[2]:loc[3]])
+		if loc[4] >= 0 {
+			v = (v + parseNutritionNumber(value[loc[4]:loc[5]])) / 2
+		}
+		nutritionFields[field].set(&n, v)
+	}
+	return n, n.Calories > 0
+}
+
+// ReplaceNutrition подставляет значения n в строки блока «Пищевая ценность»,
+// сохраняя их оформление. false — в блоке нет хотя бы одного показателя; текст не меняется.
+func ReplaceNutrition(text string, n Nutrition) (string, bool) {
+	lines := strings.Split(text, "\n")
+	block, ok := nutritionBlock(lines)
+	if !ok {
+		return text, false
+	}
+
+	values := []float64{n.Calories, n.Protein, n.Fat, n.Carbs}
+	for field, i := range block {
+		_, value, loc, _ := nutritionLine(lines[i])
+		name := lines[i][:len(lines[i])-len(value)]
+		lines[i] = name + value[:loc[0]] + strconv.Itoa(int(math.Round(values[field]))) + value[loc[1]:]
+	}
+	return strings.Join(lines, "\n"), true
+}
+
+// nutritionBlock находит строки показателей после последнего заголовка «Пищевая ценность»:
+// индекс строки для каждого поля nutritionFields. Строки шагов вроде «Белки взбейте: 2–3 минуты»
+// стоят выше заголовка и не учитываются. Блок кончается на первой строке, не похожей
+// на показатель. false — заголовка нет или нашлись не все показатели.
+func nutritionBlock(lines []string) ([]int, bool) {
+	header := -1
+	for i, line := range lines {
+		if strings.Contains(strings.ToLower(line), "пищевая ценность") {
+			header = i
+		}
+	}
+	if header < 0 {
+		return nil, false
+	}
+
+	block := make([]int, len(nutritionFields))
+	found := 0
+	for i := header + 1; i < len(lines); i++ {
+		field, _, _, ok := nutritionLine(lines[i])
+		if !ok {
+			if found > 0 && strings.TrimSpace(lines[i]) != "" {
+				break
+			}
+			continue
+		}
+		if block[field] == 0 {
+			block[field] = i
+			found++
+		}
+	}
+	return block, found == len(nutritionFields)
+}
+
+// nutritionLine распознаёт строку пищевой ценности: номер показателя в nutritionFields,
+// часть строки после двоеточия и положение числа в ней (как у FindStringSubmatchIndex)
+func nutritionLine(line string) (field int, value string, loc []int, ok bool) {
+	name, value, found := strings.Cut(line, ":")
+	if !found {
+		return 0, "", nil, false
+	}
+	loc = nutritionRe.FindStringSubmatchIndex(value)
+	if loc == nil {
+		return 0, "", nil, false
+	}
+
+	// Перед названием бывают разметка и значки: «- *Ккал*», «🔥 Калории»
+	name = strings.ToLower(strings.NewReplacer("*", "", "_", "", "`", "").Replace(name))
+	name = strings.TrimLeftFunc(name, func(r rune) bool { return !unicode.IsLetter(r) })
+	for i, f := range nutritionFields {
+		if hasAnyPrefix(name, f.stems) {
+			return i, value, loc, true
+		}
+	}
+	return 0, "", nil, false
+}
+
+func parseNutritionNumber(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	return v
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
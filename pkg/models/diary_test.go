@@ -0,0 +1,87 @@
+package models
+
+import "testing"
+
+// nutritionRecipe — рецепт с шагом, похожим на строку пищевой ценности
+const nutritionRecipe = `*Меренги с ягодами*
+Порций: 2
+
+*Пошаговый рецепт*
+1. Белки взбейте до пиков: 2–3 минуты
+2. Жир с миски уберите: 1 раз протрите лимоном
+
+📊 Пищевая ценность (на 1 порцию, ~350–450 г)
+- *Ккал*: ~420
+- *Белки*: 12 г
+- *Жиры*: 20–25 г
+- *Углеводы*: 30,5 г
+→ Оценка приблизительная, но реалистичная.`
+
+func TestParseNutrition(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want Nutrition
+		ok   bool
+	}{
+		{"блок после шагов", nutritionRecipe, Nutrition{Calories: 420, Protein: 12, Fat: 22.5, Carbs: 30.5}, true},
+		{"без разметки", "Пищевая ценность:\nКалории: 300 ккал\nБелок: 10 г\nЖиры: 5 г\nУглеводы: 40 г",
+			Nutrition{Calories: 300, Protein: 10, Fat: 5, Carbs: 40}, true},
+		{"нет заголовка", "- *Ккал*: ~420\n- *Белки*: 12 г\n- *Жиры*: 20 г\n- *Углеводы*: 30 г", Nutrition{}, false},
+		{"не хватает показателя", "📊 Пищевая ценность\n- *Ккал*: ~420\n- *Белки*: 12 г\n- *Жиры*: 20 г", Nutrition{}, false},
+		{"показатель после блока не учитывается", "📊 Пищевая ценность\n- *Ккал*: ~420\n- *Белки*: 12 г\n- *Жиры*: 20 г\n\n*💡 Шеф-совет*\nУглеводы: 3 ложки сахара", Nutrition{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseNutrition(tt.text)
+		if ok != tt.ok || ok && got != tt.want {
+			t.Errorf("%s: ParseNutrition = %+v, %v; ожидалось %+v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestReplaceNutrition(t *testing.T) {
+	n := Nutrition{Calories: 380.4, Protein: 14.6, Fat: 18, Carbs: 29}
+	want := `*Меренги с ягодами*
+Порций: 2
+
+*Пошаговый рецепт*
+1. Белки взбейте до пиков: 2–3 минуты
+2. Жир с миски уберите: 1 раз протрите лимоном
+
+📊 Пищевая ценность (на 1 порцию, ~350–450 г)
+- *Ккал*: ~380
+- *Белки*: 15 г
+- *Жиры*: 18 г
+- *Углеводы*: 29 г
+→ Оценка приблизительная, но реалистичная.`
+
+	got, ok := ReplaceNutrition(nutritionRecipe, n)
+	if !ok || got != want {
+		t.Errorf("ReplaceNutrition = %v:\n%s\nожидалось:\n%s", ok, got, want)
+	}
+
+	// Без полного блока текст не меняется
+	partial := "📊 Пищевая ценность\n- *Ккал*: ~420"
+	if got, ok := ReplaceNutrition(partial, n); ok || got != partial {
+		t.Errorf("ReplaceNutrition без блока = %q, %v; ожидалось исходный текст и false", got, ok)
+	}
+}
+
+func TestDailyNutritionTargets(t *testing.T) {
+	tests := []struct {
+		name  string
+		prefs *UserPreferences
+		want  NutritionTargets
+	}{
+		{"без предпочтений", nil, NutritionTargets{Calories: 2000, Protein: 100, Fat: 67, Carbs: 250}},
+		{"похудение", &UserPreferences{DietaryType: "Похудение"}, NutritionTargets{Calories: 1500, Protein: 75, Fat: 50, Carbs: 188}},
+		{"кето", &UserPreferences{DietRestrictions: []string{"keto"}}, NutritionTargets{Calories: 2000, Protein: 100, Fat: 156, Carbs: 50}},
+	}
+
+	for _, tt := range tests {
+		if got := DailyNutritionTargets(tt.prefs); got != tt.want {
+			t.Errorf("%s: DailyNutritionTargets = %+v; ожидалось %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DietRestriction — ограничение питания (стиль питания), не связанное с целью по весу.
+// Ограничения можно сочетать.
+type DietRestriction struct {
+	Code string // ключ в таблице diet_restrictions и в callback-данных
+	Name string // название на кнопке и в настройках
+	Rule string // правило для модели
+
+	// Keywords — начала слов, которых не должно быть в ингредиентах (ё заменяется на е).
+	// Exceptions — фразы, которые вырезаются из строки перед проверкой
+	// (например, «кокосовое молоко» для веганов).
+	Keywords   []string
+	Exceptions []string
+}
+
+// DietRestrictions — справочник ограничений в порядке показа.
+// Коды должны совпадать с таблицей diet_restrictions (миграция 0009_diet_restrictions).
+var DietRestrictions = []DietRestriction{
+	{
+		Code: "vegetarian",
+		Name: "Вегетарианское",
+		Rule: "без мяса, птицы, рыбы и морепродуктов; яйца и молочные продукты можно; желатин замени агар-агаром",
+		Keywords: []string{"мяс", "говядин", "свинин", "баранин", "телятин", "куриц", "курин", "индейк", "утк", "фарш",
+			"бекон", "ветчин", "колбас", "сосиск", "рыб", "лосос", "семг", "тунец", "тунц", "креветк", "кальмар", "миди", "желатин"},
+		Exceptions: []string{"соевое мясо", "соевый фарш", "растительный фарш", "грибной фарш"},
+	},
+	{
+		Code: "vegan",
+		Name: "Веганское",
+		Rule: "только растительные продукты: без мяса, рыбы, морепродуктов, яиц, молочных продуктов, мёда и желатина",
+		Keywords: []string{"мяс", "говядин", "свинин", "баранин", "телятин", "куриц", "курин", "индейк", "утк", "фарш",
+			"бекон", "ветчин", "колбас", "сосиск", "рыб", "лосос", "семг", "тунец", "тунц", "креветк", "кальмар", "миди", "желатин",
+			"яйц", "яичн", "молок", "молоч", "сливк", "сливочн", "сметан", "творог", "сыр", "йогурт", "кефир", "мед", "майонез"},
+		Exceptions: []string{"соевое мясо", "соевый фарш", "растительный фарш", "грибной фарш",
+			"кокосовое молоко", "соевое молоко", "овсяное молоко", "миндальное молоко", "растительное молоко",
+			"кокосовые сливки", "растительные сливки", "соевый йогурт", "кокосовый йогурт", "веганский сыр", "веганский майонез",
+			"сырой", "сырое", "сырые", "сырых", "сырую"},
+	},
+	{
+		Code:       "keto",
+		Name:       "Кето",
+		Rule:       "не больше 20–30 г углеводов на порцию: без сахара, круп, хлеба, макарон, картофеля и сладких фруктов; основа — мясо, рыба, яйца, некрахмалистые овощи и жиры",
+		Keywords:   []string{"сахар", "мук", "хлеб", "батон", "лаваш", "макарон", "спагетти", "лапш", "рис", "греч", "овсян", "перлов", "пшен", "булгур", "кускус", "круп", "картоф", "кукуруз", "банан", "мед"},
+		Exceptions: []string{"сахарозаменитель", "миндальная мука", "кокосовая мука"},
+	},
+	{
+		Code:       "halal",
+		Name:       "Халяль",
+		Rule:       "без свинины и продуктов из неё, без алкоголя (в том числе вина и пива в соусах) и животного желатина; мясо — халяль",
+		Keywords:   []string{"свин", "бекон", "сало", "ветчин", "вин", "пив", "коньяк", "водк", "ликер", "желатин"},
+		Exceptions: []string{"винегрет"},
+	},
+	{
+		Code:     "kosher",
+		Name:     "Кошерное",
+		Rule:     "без свинины, кролика, морепродуктов и рыбы без чешуи; не сочетай мясо и молочные продукты в одном блюде",
+		Keywords: []string{"свин", "бекон", "сало", "ветчин", "кролик", "креветк", "кальмар", "миди", "краб", "осьминог", "устриц", "угор", "угр"},
+	},
+	{
+		Code:       "low_fodmap",
+		Name:       "Low-FODMAP",
+		Rule:       "без лука, чеснока, бобовых, пшеницы и ржи, молока с лактозой, мёда, яблок, груш и грибов; можно зелёную часть лука-порея, безлактозные продукты, рис, картофель",
+		Keywords:   []string{"лук", "чеснок", "фасол", "нут", "чечевиц", "горох", "пшенич", "ржан", "молок", "мед", "яблок", "груш", "гриб", "шампиньон"},
+		Exceptions: []string{"зеленый лук", "безлактозное молоко"},
+	},
+	{
+		Code:       "diabetic",
+		Name:       "Для диабетиков",
+		Rule:       "низкий гликемический индекс: без сахара, мёда, сиропов, варенья, белого хлеба, белого риса и сладкой выпечки; больше клетчатки и белка",
+		Keywords:   []string{"сахар", "мед", "сироп", "варень", "джем", "сгущ", "батон"},
+		Exceptions: []string{"сахарозаменитель", "без сахара"},
+	},
+}
+
+// DietRestrictionByCode ищет ограничение в справочнике
+func DietRestrictionByCode(code string) (DietRestriction, bool) {
+	for _, r := range DietRestrictions {
+		if r.Code == code {
+			return r, true
+		}
+	}
+	return DietRestriction{}, false
+}
+
+// HasDietRestriction проверяет, выбрано ли ограничение у пользователя
+func (p *UserPreferences) HasDietRestriction(code string) bool {
+	for _, c := range p.DietRestrictions {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectedDietRestrictions возвращает выбранные ограничения в порядке справочника
+func (p *UserPreferences) SelectedDietRestrictions() []DietRestriction {
+	var list []DietRestriction
+	for _, r := range DietRestrictions {
+		if p.HasDietRestriction(r.Code) {
+			list = append(list, r)
+		}
+	}
+	return list
+}
+
+// Violations возвращает слова из ингредиентов, нарушающие ограничение
+func (r DietRestriction) Violations(ingredients string) []string {
+	var found []string
+	for _, line := range strings.Split(normalizeFood(ingredients), "\n") {
+		for _, exception := range r.Exceptions {
+			line = strings.ReplaceAll(line, normalizeFood(exception), " ")
+		}
+
+		words := strings.FieldsFunc(line, func(c rune) bool { return !unicode.IsLetter(c) })
+		for _, word := range words {
+			for _, keyword := range r.Keywords {
+				if strings.HasPrefix(word, keyword) {
+					found = append(found, word)
+					break
+				}
+			}
+		}
+	}
+	return found
+}
+
+// normalizeFood приводит текст к нижнему регистру и заменяет ё на е
+func normalizeFood(s string) string {
+	return strings.ReplaceAll(strings.ToLower(s), "ё", "е")
+}
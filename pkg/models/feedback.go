@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// Оценки рецепта
+const (
+	RatingLike    = 1
+	RatingDislike = -1
+)
+
+// FeedbackReasonOther — причина «свой вариант»: текст пользователя хранится в Comment
+const FeedbackReasonOther = "other"
+
+// FeedbackReason — причина отрицательной оценки
+type FeedbackReason struct {
+	Code string
+	Name string
+}
+
+// FeedbackReasons — готовые причины в порядке показа
+var FeedbackReasons = []FeedbackReason{
+	{"too_complex", "Слишком сложно"},
+	{"allergen", "Есть аллерген"},
+	{"bad_taste", "Невкусно"},
+	{"too_long", "Долго готовить"},
+	{"expensive", "Дорогие продукты"},
+	{"wrong_diet", "Не подходит под питание"},
+}
+
+// FeedbackReasonName возвращает название причины по коду (сам код, если причина неизвестна)
+func FeedbackReasonName(code string) string {
+	for _, r := range FeedbackReasons {
+		if r.Code == code {
+			return r.Name
+		}
+	}
+	if code == FeedbackReasonOther {
+		return "Свой вариант"
+	}
+	return code
+}
+
+// RecipeFeedback — оценка рецепта пользователем. Тип питания, версия промпта и модель
+// копируются из рецепта, чтобы отчёт не зависел от удаления рецептов из истории.
+type RecipeFeedback struct {
+	RecipeID      int64     `json:"recipe_id"`
+	UserID        int64     `json:"user_id"`
+	Rating        int       `json:"rating"` // RatingLike или RatingDislike
+	Reason        string    `json:"reason,omitempty"`
+	Comment       string    `json:"comment,omitempty"`
+	DietType      string    `json:"diet_type"`
+	PromptVersion string    `json:"prompt_version"`
+	Model         string    `json:"model"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// FeedbackStat — число оценок для сочетания типа питания, версии промпта и модели
+type FeedbackStat struct {
+	DietType      string
+	PromptVersion string
+	Model         string
+	Likes         int
+	Dislikes      int
+}
+
+// FeedbackReasonStat — сколько раз указана причина отрицательной оценки
+type FeedbackReasonStat struct {
+	Reason string
+	Count  int
+}
@@ -1,35 +1,271 @@
 package models
 
+import "time"
+
 // UserState представляет текущее состояние пользователя в разговоре
 type UserState struct {
-	UserID        int64
-	CurrentState  string   // например: "main", "settings"
-	LastMessageID int      // ID последнего сообщения - для редактирования
-	InputData     string   // временные данные от пользователя (напр., введённые ингредиенты)
-	StateHistory  []string // стек состояний - чтобы можно было сделать "назад"
+	UserID        int64    `json:"user_id"`
+	CurrentState  string   `json:"current_state"`   // например: "main", "settings"
+	LastMessageID int      `json:"last_message_id"` // ID последнего сообщения - для редактирования
+	InputData     string   `json:"input_data"`      // временные данные от пользователя (напр., введённые ингредиенты)
+	StateHistory  []string `json:"state_history"`   // стек состояний - чтобы можно было сделать "назад"
 }
 
 // UserPreferences представляет кулинарные предпочтения пользователя
 type UserPreferences struct {
-	UserID      int64
-	DietaryType string // обычное, похудение, набор веса
-	Goal        string // цель: (напр., похудеть на 3 кг)
-	Allergies   string // данные, на что аллергия
-	Likes       string // данные, что нравится в еде
-	Dislikes    string // данные, что не нравится в еде
+	UserID           int64        `json:"user_id"`
+	DietaryType      string       `json:"dietary_type"`      // цель по весу: обычное, похудение, набор массы
+	Goal             string       `json:"goal"`              // цель: (напр., похудеть на 3 кг)
+	Allergies        string       `json:"allergies"`         // другие аллергии свободным текстом
+	Allergens        []string     `json:"allergens"`         // коды из справочника Allergens; меняются через SetAllergen
+	DietRestrictions []string     `json:"diet_restrictions"` // коды из справочника DietRestrictions; меняются через SetDietRestriction
+	Body             *BodyProfile `json:"body"`              // параметры тела; nil — не заполнены, меняются через SaveBodyProfile
+	Likes            string       `json:"likes"`             // данные, что нравится в еде
+	Dislikes         string       `json:"dislikes"`          // данные, что не нравится в еде
+}
+
+// RateLimit задаёт ограничение частоты запросов по алгоритму token bucket:
+// Burst запросов можно сделать подряд, затем — не чаще PerMinute в минуту
+type RateLimit struct {
+	PerMinute int
+	Burst     int
+}
+
+// Interval возвращает время восстановления одного запроса
+func (l RateLimit) Interval() time.Duration {
+	if l.PerMinute <= 0 {
+		return time.Minute
+	}
+	return time.Minute / time.Duration(l.PerMinute)
+}
+
+// Tolerance возвращает допустимое опережение графика (размер всплеска)
+func (l RateLimit) Tolerance() time.Duration {
+	if l.Burst <= 1 {
+		return 0
+	}
+	return time.Duration(l.Burst-1) * l.Interval()
+}
+
+// Take — один шаг GCRA: по теоретическому времени прихода tat решает, пропускать ли
+// запрос в момент now, и возвращает новое значение tat
+func (l RateLimit) Take(tat, now time.Time) (time.Time, RateLimitResult) {
+	if tat.Before(now) {
+		tat = now
+	}
+
+	if allowAt := tat.Add(-l.Tolerance()); allowAt.After(now) {
+		return tat, RateLimitResult{RetryAfter: allowAt.Sub(now)}
+	}
+
+	return tat.Add(l.Interval()), RateLimitResult{Allowed: true}
+}
+
+// RateLimitResult — результат проверки лимита
+type RateLimitResult struct {
+	Allowed    bool
+	RetryAfter time.Duration // через сколько можно повторить, если запрос отклонён
 }
 
+// QuotaUnlimited — лимит без ограничения. 0 — генерации запрещены.
+const QuotaUnlimited = -1
+
+// Quota — лимиты генераций на период
+type Quota struct {
+	Daily   int
+	Monthly int
+}
+
+// UnlimitedQuota — квота без ограничений
+var UnlimitedQuota = Quota{Daily: QuotaUnlimited, Monthly: QuotaUnlimited}
+
+// QuotaOverride — индивидуальная квота, назначенная администратором; nil — значение по умолчанию
+type QuotaOverride struct {
+	UserID  int64 `json:"user_id"`
+	Daily   *int  `json:"daily"`
+	Monthly *int  `json:"monthly"`
+}
+
+// Apply накладывает индивидуальную квоту на квоту по умолчанию
+func (o *QuotaOverride) Apply(q Quota) Quota {
+	if o == nil {
+		return q
+	}
+	if o.Daily != nil {
+		q.Daily = *o.Daily
+	}
+	if o.Monthly != nil {
+		q.Monthly = *o.Monthly
+	}
+	return q
+}
+
+// QuotaUsage — использование квот в текущих сутках и месяце
+type QuotaUsage struct {
+	Quota
+	UsedToday int
+	UsedMonth int
+}
+
+// RemainingToday возвращает остаток на сегодня (-1 — без ограничения)
+func (u QuotaUsage) RemainingToday() int {
+	return remaining(u.Daily, u.UsedToday)
+}
+
+// RemainingMonth возвращает остаток на месяц (-1 — без ограничения)
+func (u QuotaUsage) RemainingMonth() int {
+	return remaining(u.Monthly, u.UsedMonth)
+}
+
+func remaining(limit, used int) int {
+	if limit == QuotaUnlimited {
+		return -1
+	}
+	if used >= limit {
+		return 0
+	}
+	return limit - used
+}
+
+// QuotaPeriods возвращает начало текущих суток и месяца в часовом поясе now
+func QuotaPeriods(now time.Time) (dayStart, monthStart time.Time) {
+	y, m, d := now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, now.Location()), time.Date(y, m, 1, 0, 0, 0, 0, now.Location())
+}
+
+// User — профиль пользователя Telegram и его жизненный цикл в боте
+type User struct {
+	ID           int64     `json:"user_id"`
+	Username     string    `json:"username"`
+	FirstName    string    `json:"first_name"`
+	LanguageCode string    `json:"language_code"`
+	FirstSeen    time.Time `json:"first_seen"` // нулевое значение — пользователь ещё не писал боту
+	LastSeen     time.Time `json:"last_seen"`
+	IsBlocked    bool      `json:"is_blocked"` // пользователь заблокировал бота
+	IsBanned     bool      `json:"is_banned"`  // забанен администратором
+	BannedBy     int64     `json:"banned_by,omitempty"`
+	BanReason    string    `json:"ban_reason,omitempty"`
+}
+
+// UserData — всё, что бот хранит о пользователе (выгрузка по /mydata)
+type UserData struct {
+	UserID       int64              `json:"user_id"`
+	ExportedAt   time.Time          `json:"exported_at"`
+	Profile      *User              `json:"profile"`
+	State        *UserState         `json:"state"`
+	Preferences  *UserPreferences   `json:"preferences"`
+	Quota        *QuotaOverride     `json:"quota_override"`
+	RateLimitTAT *time.Time         `json:"rate_limit_tat"`
+	Generations  []GenerationRecord `json:"generations"`
+	Recipes      []Recipe           `json:"recipes"`
+	Feedback     []RecipeFeedback   `json:"feedback"`
+	Shopping     []ShoppingItem     `json:"shopping"`
+	Pantry       []PantryItem       `json:"pantry"`
+	Plan         []PlanMeal         `json:"meal_plan"`
+	Diary        []DiaryEntry       `json:"diary"`
+	Notify       *NotifySettings    `json:"notifications"`
+	NotifyJobs   []NotifyJob        `json:"notification_jobs"`
+}
+
+// GenerationRecord — запись журнала генераций
+type GenerationRecord struct {
+	ID        int64     `json:"id"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Stats — сводка для администратора за текущие сутки
+type Stats struct {
+	TotalUsers       int
+	ActiveToday      int // пользователи, запрашивавшие рецепты сегодня
+	GenerationsToday int
+	ErrorsToday      int
+	BannedUsers      int
+	InactiveUsers    int // заблокировали бота
+}
+
+// Broadcast — задание рассылки. LastUserID — последний обработанный получатель:
+// получатели перебираются по возрастанию ID, поэтому рассылку можно продолжить с него.
+type Broadcast struct {
+	ID          int64
+	AdminID     int64
+	AdminChatID int64
+	Text        string
+	Buttons     []BroadcastButton
+	Segment     string
+	Status      string
+	LastUserID  int64
+	Delivered   int
+	Failed      int
+	Blocked     int // заблокировали бота
+	CreatedAt   time.Time
+	FinishedAt  time.Time
+}
+
+// BroadcastButton — кнопка-ссылка под сообщением рассылки
+type BroadcastButton struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// RecipientFilter — условия выбора получателей рассылки (пустые поля не ограничивают)
+type RecipientFilter struct {
+	DietaryType string
+	ActiveSince time.Time // была генерация не раньше этого момента
+}
+
+// Статусы задания рассылки
+const (
+	BroadcastPending   = "pending"
+	BroadcastRunning   = "running"
+	BroadcastDone      = "done"
+	BroadcastCancelled = "cancelled"
+)
+
+// Статусы записи в журнале генераций
+const (
+	GenerationPending = "pending"
+	GenerationOK      = "ok"
+	GenerationError   = "error"
+)
+
 // Константы состояний для конечного автомата (FSM)
 const (
 	StateMain                   = "main"
 	StateHelp                   = "help"
 	StateSettings               = "settings"
 	StateSettingsDiet           = "settings_diet"
+	StateSettingsRestrictions   = "settings_restrictions"
 	StateSettingsAllerg         = "settings_allergies"
+	StateSettingsAllergOther    = "settings_allergies_other"
 	StateSettingsGoal           = "settings_goal"
 	StateSettingsHabits         = "settings_habits"
 	StateSettingsHabitsLikes    = "settings_habits_likes"
 	StateSettingsHabitsDislikes = "settings_habits_dislikes"
 	StateSettingsClearConfirm   = "settings_clear_confirm"
+	StateSettingsBody           = "settings_body"
+	StateSettingsBodySex        = "settings_body_sex"
+	StateSettingsBodyAge        = "settings_body_age"
+	StateSettingsBodyHeight     = "settings_body_height"
+	StateSettingsBodyWeight     = "settings_body_weight"
+	StateSettingsBodyActivity   = "settings_body_activity"
+	StateLimits                 = "limits"
+	StateDeleteConfirm          = "delete_confirm"
 	StateGenerating             = "generating"
+	StateHistory                = "history"
+	StateFavorites              = "favorites"
+	StateRecipe                 = "recipe"
+	StateFeedbackComment        = "feedback_comment"
+	StateScaleFactor            = "scale_factor"
+	StateShopping               = "shopping"
+	StatePlan                   = "plan"
+	StatePantry                 = "pantry"
+	StatePantryAdd              = "pantry_add"
+	StateDiary                  = "diary"
+	StateNotify                 = "notify"
+	StateNotifyTime             = "notify_time" // InputData — вид уведомления
+	StateNotifyTimezone         = "notify_timezone"
+	StateNotifyQuiet            = "notify_quiet"
+	StateBroadcastConfirm       = "broadcast_confirm" // InputData — ID сообщения с кнопками и аргументы /broadcast
 )
@@ -0,0 +1,259 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	// База часовых поясов встроена в бинарник: в минимальных образах её может не быть
+	_ "time/tzdata"
+)
+
+// Виды уведомлений
+const (
+	NotifyExpiry = "expiry" // продукты из запасов, срок годности которых истекает завтра
+	NotifyDinner = "dinner" // «что на ужин?» в выбранное время
+	NotifyPlan   = "plan"   // меню на сегодня из плана питания
+)
+
+// NotifyKinds — виды уведомлений в порядке показа в настройках
+var NotifyKinds = []string{NotifyExpiry, NotifyDinner, NotifyPlan}
+
+// DefaultTimezone — часовой пояс, пока пользователь не выбрал свой
+const DefaultTimezone = "Europe/Moscow"
+
+// NotifySettings — настройки уведомлений пользователя.
+// Время — минуты от полуночи в часовом поясе пользователя.
+type NotifySettings struct {
+	UserID     int64     `json:"user_id"`
+	Timezone   string    `json:"timezone"`    // IANA («Europe/Moscow») или смещение («UTC+3»)
+	QuietStart int       `json:"quiet_start"` // тихие часы: уведомления не приходят с QuietStart до QuietEnd;
+	QuietEnd   int       `json:"quiet_end"`   // равные значения — тихих часов нет
+	Expiry     bool      `json:"expiry"`
+	ExpiryAt   int       `json:"expiry_at"`
+	Dinner     bool      `json:"dinner"`
+	DinnerAt   int       `json:"dinner_at"`
+	Plan       bool      `json:"plan"`
+	PlanAt     int       `json:"plan_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// NotifyJob — запланированная отправка уведомления. У пользователя не больше
+// одного задания каждого вида; после отправки задание переносится на следующий раз.
+type NotifyJob struct {
+	ID     int64     `json:"id"`
+	UserID int64     `json:"user_id"`
+	Kind   string    `json:"kind"`
+	RunAt  time.Time `json:"run_at"`
+}
+
+// DefaultNotifySettings — настройки для пользователя, который их не менял:
+// включены только напоминания о сроках годности
+func DefaultNotifySettings(userID int64) *NotifySettings {
+	return &NotifySettings{
+		UserID:     userID,
+		Timezone:   DefaultTimezone,
+		QuietStart: 23 * 60,
+		QuietEnd:   8 * 60,
+		Expiry:     true,
+		ExpiryAt:   10 * 60,
+		DinnerAt:   18 * 60,
+		PlanAt:     9 * 60,
+	}
+}
+
+// Location — часовой пояс пользователя (DefaultTimezone, если сохранённый не распознан)
+func (s *NotifySettings) Location() *time.Location {
+	if loc, _, ok := LoadTimezone(s.Timezone); ok {
+		return loc
+	}
+	loc, _, _ := LoadTimezone(DefaultTimezone)
+	return loc
+}
+
+// Enabled сообщает, включено ли уведомление вида kind
+func (s *NotifySettings) Enabled(kind string) bool {
+	switch kind {
+	case NotifyExpiry:
+		return s.Expiry
+	case NotifyDinner:
+		return s.Dinner
+	case NotifyPlan:
+		return s.Plan
+	}
+	return false
+}
+
+// At — время уведомления вида kind в минутах от полуночи
+func (s *NotifySettings) At(kind string) int {
+	switch kind {
+	case NotifyExpiry:
+		return s.ExpiryAt
+	case NotifyDinner:
+		return s.DinnerAt
+	case NotifyPlan:
+		return s.PlanAt
+	}
+	return 0
+}
+
+// Set включает или выключает уведомление вида kind и задаёт его время
+func (s *NotifySettings) Set(kind string, enabled bool, at int) {
+	switch kind {
+	case NotifyExpiry:
+		s.Expiry, s.ExpiryAt = enabled, at
+	case NotifyDinner:
+		s.Dinner, s.DinnerAt = enabled, at
+	case NotifyPlan:
+		s.Plan, s.PlanAt = enabled, at
+	}
+}
+
+// IsQuiet сообщает, попадает ли момент t в тихие часы пользователя
+func (s *NotifySettings) IsQuiet(t time.Time) bool {
+	if s.QuietStart == s.QuietEnd {
+		return false
+	}
+	local := t.In(s.Location())
+	m := local.Hour()*60 + local.Minute()
+	if s.QuietStart < s.QuietEnd {
+		return m >= s.QuietStart && m < s.QuietEnd
+	}
+	return m >= s.QuietStart || m < s.QuietEnd
+}
+
+// NextRun — ближайший после now момент отправки уведомления вида kind.
+// Если он попадает в тихие часы, отправка переносится на их окончание.
+func (s *NotifySettings) NextRun(kind string, now time.Time) time.Time {
+	next := s.clock(now, 0, s.At(kind))
+	if !next.After(now) {
+		next = s.clock(now, 1, s.At(kind))
+	}
+	if !s.IsQuiet(next) {
+		return next
+	}
+
+	end := s.clock(next, 0, s.QuietEnd)
+	if !end.After(next) {
+		end = s.clock(next, 1, s.QuietEnd)
+	}
+	return end
+}
+
+// Jobs — задания для всех включённых уведомлений с отправкой после now
+func (s *NotifySettings) Jobs(now time.Time) []NotifyJob {
+	var jobs []NotifyJob
+	for _, kind := range NotifyKinds {
+		if s.Enabled(kind) {
+			jobs = append(jobs, NotifyJob{UserID: s.UserID, Kind: kind, RunAt: s.NextRun(kind, now)})
+		}
+	}
+	return jobs
+}
+
+// clock — момент minutes от полуночи через days дней после дня t по часовому поясу пользователя
+func (s *NotifySettings) clock(t time.Time, days, minutes int) time.Time {
+	loc := s.Location()
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d+days, minutes/60, minutes%60, 0, 0, loc)
+}
+
+// Timezone — часовой пояс для выбора кнопкой
+type Timezone struct {
+	Name string // город
+	Zone string // IANA
+}
+
+// Timezones — часовые пояса России для выбора в настройках
+var Timezones = []Timezone{
+	{"Калининград", "Europe/Kaliningrad"},
+	{"Москва", "Europe/Moscow"},
+	{"Самара", "Europe/Samara"},
+	{"Екатеринбург", "Asia/Yekaterinburg"},
+	{"Омск", "Asia/Omsk"},
+	{"Новосибирск", "Asia/Novosibirsk"},
+	{"Красноярск", "Asia/Krasnoyarsk"},
+	{"Иркутск", "Asia/Irkutsk"},
+	{"Якутск", "Asia/Yakutsk"},
+	{"Владивосток", "Asia/Vladivostok"},
+	{"Магадан", "Asia/Magadan"},
+	{"Камчатка", "Asia/Kamchatka"},
+}
+
+// utcOffsetRe — смещение от UTC: «UTC+3», «+5:30», «GMT-4»
+var utcOffsetRe = regexp.MustCompile(`(?i)^(?:utc|gmt)?\s*([+-])\s*(\d{1,2})(?::?(\d{2}))?$`)
+
+// LoadTimezone распознаёт часовой пояс: имя IANA или смещение от UTC.
+// Возвращает пояс и его имя для сохранения.
+func LoadTimezone(name string) (*time.Location, string, bool) {
+	name = strings.TrimSpace(name)
+	if m := utcOffsetRe.FindStringSubmatch(name); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		if hours > 14 || minutes >= 60 {
+			return nil, "", false
+		}
+
+		name = fmt.Sprintf("UTC%s%d", m[1], hours)
+		if minutes > 0 {
+			name += fmt.Sprintf(":%02d", minutes)
+		}
+		offset := (hours*60 + minutes) * 60
+		if m[1] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone(name, offset), name, true
+	}
+
+	if strings.EqualFold(name, "UTC") {
+		return time.UTC, "UTC", true
+	}
+	// Имена IANA содержат «/»: «Local» и пустая строка дали бы часовой пояс сервера
+	if !strings.Contains(name, "/") {
+		return nil, "", false
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, "", false
+	}
+	return loc, loc.String(), true
+}
+
+// clockRe — время суток: «18:30», «9.00», «7»
+var clockRe = regexp.MustCompile(`^(\d{1,2})(?:[:.](\d{2}))?$`)
+
+// ParseClock разбирает время суток в минуты от полуночи
+func ParseClock(text string) (int, bool) {
+	m := clockRe.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil {
+		return 0, false
+	}
+	hours, _ := strconv.Atoi(m[1])
+	minutes, _ := strconv.Atoi(m[2])
+	if hours > 23 || minutes > 59 {
+		return 0, false
+	}
+	return hours*60 + minutes, true
+}
+
+// ParseQuietHours разбирает тихие часы вида «23:00-08:00»
+func ParseQuietHours(text string) (start, end int, ok bool) {
+	from, to, found := strings.Cut(strings.NewReplacer("–", "-", "—", "-").Replace(text), "-")
+	if !found {
+		return 0, 0, false
+	}
+	if start, ok = ParseClock(from); !ok {
+		return 0, 0, false
+	}
+	if end, ok = ParseClock(to); !ok {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// FormatClock — минуты от полуночи в виде «08:30»
+func FormatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsQuiet(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end int
+		clock      string
+		want       bool
+	}{
+		{"через полночь, вечер", 23 * 60, 8 * 60, "23:30", true},
+		{"через полночь, ночь", 23 * 60, 8 * 60, "03:00", true},
+		{"через полночь, конец", 23 * 60, 8 * 60, "08:00", false},
+		{"через полночь, день", 23 * 60, 8 * 60, "12:00", false},
+		{"днём", 13 * 60, 15 * 60, "14:59", true},
+		{"днём, вне", 13 * 60, 15 * 60, "15:00", false},
+		{"выключены", 0, 0, "03:00", false},
+	}
+
+	for _, tt := range tests {
+		s := &NotifySettings{Timezone: "UTC+3", QuietStart: tt.start, QuietEnd: tt.end}
+		at, _ := ParseClock(tt.clock)
+		// Момент задан по UTC: часы сравниваются в часовом поясе пользователя
+		moment := time.Date(2024, 3, 4, 0, at-3*60, 0, 0, time.UTC)
+		if got := s.IsQuiet(moment); got != tt.want {
+			t.Errorf("%s: IsQuiet(%s) = %v; ожидалось %v", tt.name, tt.clock, got, tt.want)
+		}
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	loc, _, _ := LoadTimezone("UTC+3")
+	day := func(d, h, m int) time.Time { return time.Date(2024, 3, d, h, m, 0, 0, loc) }
+
+	tests := []struct {
+		name string
+		at   int
+		now  time.Time
+		want time.Time
+	}{
+		{"сегодня", 10 * 60, day(4, 9, 0), day(4, 10, 0)},
+		{"уже прошло", 10 * 60, day(4, 10, 0), day(5, 10, 0)},
+		{"поздно вечером", 10 * 60, day(4, 23, 30), day(5, 10, 0)},
+		// Время в тихих часах — отправка в их конце
+		{"в тихие часы ночью", 2 * 60, day(4, 12, 0), day(5, 8, 0)},
+		{"в тихие часы вечером", 23*60 + 30, day(4, 12, 0), day(5, 8, 0)},
+	}
+
+	for _, tt := range tests {
+		s := DefaultNotifySettings(1)
+		s.Timezone = "UTC+3"
+		s.ExpiryAt = tt.at
+		if got := s.NextRun(NotifyExpiry, tt.now); !got.Equal(tt.want) {
+			t.Errorf("%s: NextRun = %v; ожидалось %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestJobs(t *testing.T) {
+	s := DefaultNotifySettings(7)
+	s.Timezone = "UTC"
+	s.Plan = true
+
+	jobs := s.Jobs(time.Date(2024, 3, 4, 9, 30, 0, 0, time.UTC))
+	if len(jobs) != 2 || jobs[0].Kind != NotifyExpiry || jobs[1].Kind != NotifyPlan {
+		t.Fatalf("Jobs = %+v; ожидались напоминания о сроках и плане", jobs)
+	}
+	if want := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC); !jobs[1].RunAt.Equal(want) || jobs[1].UserID != 7 {
+		t.Errorf("план: %+v; ожидалось %v", jobs[1], want)
+	}
+}
+
+func TestLoadTimezone(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   string
+		offset int
+		ok     bool
+	}{
+		{"UTC+3", "UTC+3", 3 * 3600, true},
+		{"+5:30", "UTC+5:30", 5*3600 + 30*60, true},
+		{"gmt-4", "UTC-4", -4 * 3600, true},
+		{"utc", "UTC", 0, true},
+		{"UTC+15", "", 0, false},
+		{"Local", "", 0, false},
+		{"", "", 0, false},
+		{"Europe/Нигде", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		loc, name, ok := LoadTimezone(tt.in)
+		if ok != tt.ok || name != tt.want {
+			t.Errorf("LoadTimezone(%q) = %q, %v; ожидалось %q, %v", tt.in, name, ok, tt.want, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if _, offset := time.Date(2024, 1, 1, 0, 0, 0, 0, loc).Zone(); offset != tt.offset {
+			t.Errorf("LoadTimezone(%q): смещение %d; ожидалось %d", tt.in, offset, tt.offset)
+		}
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"18:30", 18*60 + 30, true},
+		{"9.05", 9*60 + 5, true},
+		{" 7 ", 7 * 60, true},
+		{"24:00", 0, false},
+		{"12:60", 0, false},
+		{"полдень", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseClock(tt.in)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseClock(%q) = %d, %v; ожидалось %d, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseQuietHours(t *testing.T) {
+	tests := []struct {
+		in         string
+		start, end int
+		ok         bool
+	}{
+		{"23:00-08:00", 23 * 60, 8 * 60, true},
+		{"22 – 7", 22 * 60, 7 * 60, true},
+		{"23:00", 0, 0, false},
+		{"23:00-25:00", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		start, end, ok := ParseQuietHours(tt.in)
+		if ok != tt.ok || start != tt.start || end != tt.end {
+			t.Errorf("ParseQuietHours(%q) = %d, %d, %v; ожидалось %d, %d, %v", tt.in, start, end, ok, tt.start, tt.end, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// PantryItem — продукт в запасах пользователя («что есть в холодильнике»).
+// Одинаковые продукты в одной единице складываются, как в списке покупок.
+type PantryItem struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	Amount    float64   `json:"amount"` // 0 — количество не указано
+	Unit      string    `json:"unit"`
+	Expires   string    `json:"expires,omitempty"` // DayLayout; пусто — без срока годности
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PantryUse — сколько продукта из запасов израсходовано
+type PantryUse struct {
+	ItemID int64
+	Amount float64
+}
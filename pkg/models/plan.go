@@ -0,0 +1,290 @@
+package models
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PlanDays — на сколько дней составляется план питания
+const PlanDays = 7
+
+// DayLayout — формат дня в плане питания и сроках годности
+const DayLayout = "2006-01-02"
+
+// PlanMeal — блюдо плана питания на день и приём пищи
+type PlanMeal struct {
+	UserID    int64     `json:"user_id"`
+	Day       string    `json:"day"`  // DayLayout
+	Slot      string    `json:"slot"` // код из PlanSlots
+	Title     string    `json:"title"`
+	Protein   string    `json:"protein"` // основной источник белка из PlanProteins
+	Calories  int       `json:"calories"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PlanSlot — приём пищи в плане. Share — доля дневной нормы калорий.
+type PlanSlot struct {
+	Code  string
+	Name  string
+	Emoji string
+	Share float64
+}
+
+// PlanSlots — приёмы пищи в порядке показа
+var PlanSlots = []PlanSlot{
+	{"breakfast", "Завтрак", "🍳", 0.3},
+	{"lunch", "Обед", "🍲", 0.4},
+	{"dinner", "Ужин", "🍽", 0.3},
+}
+
+// PlanSlotByCode ищет приём пищи по коду
+func PlanSlotByCode(code string) (PlanSlot, bool) {
+	for _, s := range PlanSlots {
+		if s.Code == code {
+			return s, true
+		}
+	}
+	return PlanSlot{}, false
+}
+
+// Calories — норма калорий на приём пищи при дневной норме daily
+func (s PlanSlot) Calories(daily int) int {
+	return int(math.Round(float64(daily) * s.Share))
+}
+
+// ProteinNone — у блюда нет выраженного источника белка (каша, овощной салат)
+const ProteinNone = "нет"
+
+// PlanProtein — основной источник белка. Stems — начала слов, по которым
+// распознаётся ответ модели («куриное филе» — курица).
+type PlanProtein struct {
+	Name  string
+	Stems []string
+}
+
+// PlanProteins — источники белка, из которых модель выбирает основной для блюда
+var PlanProteins = []PlanProtein{
+	{"курица", []string{"куриц", "курин", "цыпл"}},
+	{"индейка", []string{"индей"}},
+	{"говядина", []string{"говя", "телят"}},
+	{"свинина", []string{"свин"}},
+	{"рыба", []string{"рыб", "лосос", "семг", "форел", "тунец", "тунц", "треск", "минта", "хек", "скумбри", "горбуш"}},
+	{"морепродукты", []string{"морепродукт", "креветк", "кальмар", "миди"}},
+	{"яйца", []string{"яйц", "яйко", "омлет"}},
+	{"творог", []string{"творо"}},
+	{"сыр", []string{"сыр"}},
+	{"бобовые", []string{"бобов", "фасол", "нут", "чечевиц", "горох"}},
+	{"тофу", []string{"тофу"}},
+	{"грибы", []string{"гриб", "шампиньон"}},
+	{ProteinNone, []string{"нет", "без"}},
+}
+
+// PlanProteinNames — названия источников белка через запятую для промпта
+func PlanProteinNames() string {
+	names := make([]string, len(PlanProteins))
+	for i, p := range PlanProteins {
+		names[i] = p.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// NormalizeProtein приводит источник белка из ответа модели к названию из PlanProteins.
+// Незнакомый источник возвращается в нижнем регистре, пустой — как ProteinNone.
+func NormalizeProtein(s string) string {
+	key := normalizeName(strings.Trim(s, " .,;:()"))
+	if key == "" {
+		return ProteinNone
+	}
+	for _, w := range strings.Fields(key) {
+		for _, p := range PlanProteins {
+			for _, stem := range p.Stems {
+				if strings.HasPrefix(w, stem) {
+					return p.Name
+				}
+			}
+		}
+	}
+	return key
+}
+
+// PlanDailyCalories — дневная норма калорий для плана: по параметрам тела,
+// а без них — ориентир по цели по весу
+func PlanDailyCalories(prefs *UserPreferences) int {
+	if prefs == nil {
+		return 2000
+	}
+	if prefs.Body.Complete() {
+		return prefs.Body.DailyTargets(prefs.DietaryType, prefs.DietRestrictions).Calories
+	}
+	switch prefs.DietaryType {
+	case "Похудение":
+		return 1500
+	case "Набор массы":
+		return 2600
+	}
+	return 2000
+}
+
+// planNumberRe — первое число в поле: «День 3», «~450 ккал»
+var planNumberRe = regexp.MustCompile(`\d+`)
+
+// ParseMealPlan разбирает план из ответа модели: строки «День N | Приём | Блюдо | Белок | ккал».
+// start — дата первого дня. Строки в другом формате, дни вне плана и повторы пропускаются.
+func ParseMealPlan(text string, start time.Time) []PlanMeal {
+	var meals []PlanMeal
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(text, "\n") {
+		fields := planFields(line)
+		if len(fields) < 5 || !strings.HasPrefix(strings.ToLower(fields[0]), "день") {
+			continue
+		}
+
+		n, err := strconv.Atoi(planNumberRe.FindString(fields[0]))
+		if err != nil || n < 1 || n > PlanDays {
+			continue
+		}
+		slot, ok := planSlotByName(fields[1])
+		if !ok {
+			continue
+		}
+		meal, ok := planMeal(fields[2:])
+		if !ok {
+			continue
+		}
+
+		meal.Day = start.AddDate(0, 0, n-1).Format(DayLayout)
+		meal.Slot = slot.Code
+		if key := meal.Day + meal.Slot; !seen[key] {
+			seen[key] = true
+			meals = append(meals, meal)
+		}
+	}
+	return meals
+}
+
+// ParsePlanMeal разбирает одно блюдо из ответа модели: «Блюдо | Белок | ккал»
+func ParsePlanMeal(text string) (PlanMeal, bool) {
+	for _, line := range strings.Split(text, "\n") {
+		if meal, ok := planMeal(planFields(line)); ok {
+			return meal, true
+		}
+	}
+	return PlanMeal{}, false
+}
+
+// planFields делит строку на поля по «|», убирая разметку и рамки таблицы
+func planFields(line string) []string {
+	line = strings.NewReplacer("*", "", "_", "", "`", "").Replace(line)
+	line = strings.Trim(strings.TrimSpace(line), "|")
+	if !strings.Contains(line, "|") {
+		return nil
+	}
+
+	fields := strings.Split(line, "|")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	return fields
+}
+
+// planMeal собирает блюдо из полей «Блюдо | Белок | ккал»
+func planMeal(fields []string) (PlanMeal, bool) {
+	if len(fields) < 3 || fields[0] == "" {
+		return PlanMeal{}, false
+	}
+	calories, err := strconv.Atoi(planNumberRe.FindString(fields[2]))
+	if err != nil {
+		return PlanMeal{}, false
+	}
+	return PlanMeal{Title: fields[0], Protein: NormalizeProtein(fields[1]), Calories: calories}, true
+}
+
+func planSlotByName(name string) (PlanSlot, bool) {
+	name = strings.ToLower(name)
+	for _, s := range PlanSlots {
+		if strings.HasPrefix(name, strings.ToLower(s.Name)) {
+			return s, true
+		}
+	}
+	return PlanSlot{}, false
+}
+
+// PlanGaps возвращает пустые ячейки плана на days дней с даты start (заполнены только Day и Slot)
+func PlanGaps(meals []PlanMeal, start time.Time, days int) []PlanMeal {
+	filled := make(map[string]bool, len(meals))
+	for _, m := range meals {
+		filled[m.Day+m.Slot] = true
+	}
+
+	var gaps []PlanMeal
+	for i := 0; i < days; i++ {
+		day := start.AddDate(0, 0, i).Format(DayLayout)
+		for _, s := range PlanSlots {
+			if !filled[day+s.Code] {
+				gaps = append(gaps, PlanMeal{Day: day, Slot: s.Code})
+			}
+		}
+	}
+	return gaps
+}
+
+// PlanConflicts возвращает индексы блюд, основной белок которых уже был
+// в предыдущий день плана. ProteinNone не учитывается.
+func PlanConflicts(meals []PlanMeal) []int {
+	byDay := planProteinsByDay(meals)
+
+	var conflicts []int
+	for i, m := range meals {
+		if m.Protein == ProteinNone {
+			continue
+		}
+		if byDay[shiftPlanDay(m.Day, -1)][m.Protein] {
+			conflicts = append(conflicts, i)
+		}
+	}
+	return conflicts
+}
+
+// PlanNeighborProteins — источники белка из дней до и после day: их нельзя брать
+// для блюда на day, чтобы белок не повторялся два дня подряд
+func PlanNeighborProteins(meals []PlanMeal, day string) []string {
+	byDay := planProteinsByDay(meals)
+
+	var proteins []string
+	seen := make(map[string]bool)
+	for _, d := range []string{shiftPlanDay(day, -1), shiftPlanDay(day, 1)} {
+		for _, p := range PlanProteins {
+			if byDay[d][p.Name] && !seen[p.Name] {
+				seen[p.Name] = true
+				proteins = append(proteins, p.Name)
+			}
+		}
+	}
+	return proteins
+}
+
+func planProteinsByDay(meals []PlanMeal) map[string]map[string]bool {
+	byDay := make(map[string]map[string]bool)
+	for _, m := range meals {
+		if m.Protein == ProteinNone {
+			continue
+		}
+		if byDay[m.Day] == nil {
+			byDay[m.Day] = make(map[string]bool)
+		}
+		byDay[m.Day][m.Protein] = true
+	}
+	return byDay
+}
+
+// shiftPlanDay сдвигает день в формате DayLayout на n дней
+func shiftPlanDay(day string, n int) string {
+	t, err := time.Parse(DayLayout, day)
+	if err != nil {
+		return ""
+	}
+	return t.AddDate(0, 0, n).Format(DayLayout)
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseMealPlan(t *testing.T) {
+	start := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	text := `Вот ваш план:
+| День | Приём | Блюдо | Белок | ккал |
+|---|---|---|---|---|
+| День 1 | Завтрак | Омлет с томатами | яйца | ~450 ккал |
+| **День 1** | Обед | Суп с куриными фрикадельками | Куриное филе | 600 |
+День 2 | ужин | Лосось на пару | рыба (лосось) | 550
+День 1 | Завтрак | Сырники | творог | 500
+День 8 | Обед | Плов | говядина | 700
+День 3 | Перекус | Яблоко | нет | 80
+День 3 | Обед | Гречка с грибами | | 480
+День 3 | Ужин | Салат | нет | много`
+
+	want := []PlanMeal{
+		{Day: "2024-03-04", Slot: "breakfast", Title: "Омлет с томатами", Protein: "яйца", Calories: 450},
+		{Day: "2024-03-04", Slot: "lunch", Title: "Суп с куриными фрикадельками", Protein: "курица", Calories: 600},
+		{Day: "2024-03-05", Slot: "dinner", Title: "Лосось на пару", Protein: "рыба", Calories: 550},
+		{Day: "2024-03-06", Slot: "lunch", Title: "Гречка с грибами", Protein: ProteinNone, Calories: 480},
+	}
+
+	if got := ParseMealPlan(text, start); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseMealPlan:\nполучено  %+v\nожидалось %+v", got, want)
+	}
+}
+
+func TestNormalizeProtein(t *testing.T) {
+	tests := map[string]string{
+		"Куриное филе": "курица",
+		"телятина":     "говядина",
+		"(креветки)":   "морепродукты",
+		"нут.":         "бобовые",
+		"без белка":    ProteinNone,
+		"":             ProteinNone,
+		"Сейтан":       "сейтан",
+	}
+	for in, want := range tests {
+		if got := NormalizeProtein(in); got != want {
+			t.Errorf("NormalizeProtein(%q) = %q; ожидалось %q", in, got, want)
+		}
+	}
+}
+
+func TestPlanConflicts(t *testing.T) {
+	meals := []PlanMeal{
+		{Day: "2024-03-04", Slot: "breakfast", Protein: "яйца"},
+		{Day: "2024-03-04", Slot: "lunch", Protein: "курица"},
+		{Day: "2024-03-04", Slot: "dinner", Protein: ProteinNone},
+		{Day: "2024-03-05", Slot: "breakfast", Protein: ProteinNone},
+		{Day: "2024-03-05", Slot: "lunch", Protein: "курица"},
+		{Day: "2024-03-05", Slot: "dinner", Protein: "рыба"},
+		// Через день белок можно повторить
+		{Day: "2024-03-07", Slot: "lunch", Protein: "курица"},
+		// Повтор в тот же день не конфликт
+		{Day: "2024-03-07", Slot: "dinner", Protein: "курица"},
+		{Day: "2024-03-08", Slot: "breakfast", Protein: "курица"},
+	}
+
+	want := []int{4, 8}
+	if got := PlanConflicts(meals); !reflect.DeepEqual(got, want) {
+		t.Errorf("PlanConflicts = %v; ожидалось %v", got, want)
+	}
+
+	if got := PlanNeighborProteins(meals, "2024-03-06"); !reflect.DeepEqual(got, []string{"курица", "рыба"}) {
+		t.Errorf("PlanNeighborProteins = %v; ожидалось [курица рыба]", got)
+	}
+}
+
+func TestPlanGaps(t *testing.T) {
+	start := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	meals := []PlanMeal{
+		{Day: "2024-03-04", Slot: "breakfast"},
+		{Day: "2024-03-04", Slot: "dinner"},
+		{Day: "2024-03-05", Slot: "breakfast"},
+		{Day: "2024-03-05", Slot: "lunch"},
+		{Day: "2024-03-05", Slot: "dinner"},
+	}
+
+	want := []PlanMeal{{Day: "2024-03-04", Slot: "lunch"}}
+	if got := PlanGaps(meals, start, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("PlanGaps = %+v; ожидалось %+v", got, want)
+	}
+	if got := PlanGaps(nil, start, PlanDays); len(got) != PlanDays*len(PlanSlots) {
+		t.Errorf("PlanGaps пустого плана: %d ячеек; ожидалось %d", len(got), PlanDays*len(PlanSlots))
+	}
+}
+
+func TestPlanDailyCalories(t *testing.T) {
+	tests := []struct {
+		name  string
+		prefs *UserPreferences
+		want  int
+	}{
+		{"без предпочтений", nil, 2000},
+		{"похудение", &UserPreferences{DietaryType: "Похудение"}, 1500},
+		{"набор массы", &UserPreferences{DietaryType: "Набор массы"}, 2600},
+		{"по параметрам тела", &UserPreferences{
+			DietaryType: "Похудение",
+			Body:        &BodyProfile{Sex: SexMale, Age: 30, HeightCm: 180, WeightKg: 80, Activity: "moderate"},
+		}, 2207},
+	}
+
+	for _, tt := range tests {
+		if got := PlanDailyCalories(tt.prefs); got != tt.want {
+			t.Errorf("%s: PlanDailyCalories = %d; ожидалось %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Recipe — сгенерированный рецепт с контекстом генерации
+type Recipe struct {
+	ID            int64            `json:"id"`
+	UserID        int64            `json:"user_id"`
+	Request       string           `json:"request"`              // исходный запрос
+	Refinement    string           `json:"refinement,omitempty"` // уточнение кнопкой доработки
+	Title         string           `json:"title"`
+	Text          string           `json:"text"`
+	Preferences   *UserPreferences `json:"preferences"` // предпочтения в момент генерации
+	Model         string           `json:"model"`
+	PromptVersion string           `json:"prompt_version"`
+	IsFavorite    bool             `json:"is_favorite"`
+	CreatedAt     time.Time        `json:"created_at"`
+}
+
+// RecipeTitle достаёт название блюда из первой непустой строки рецепта
+// («*1. Название блюда*» → «Название блюда»)
+func RecipeTitle(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Trim(strings.TrimSpace(line), "*_# ")
+		if line == "" {
+			continue
+		}
+		if i := strings.Index(line, ". "); i > 0 && i <= 3 {
+			line = strings.TrimSpace(line[i+2:])
+		}
+		line = strings.Trim(line, "*_ ")
+
+		const maxLen = 60
+		if runes := []rune(line); len(runes) > maxLen {
+			line = string(runes[:maxLen-1]) + "…"
+		}
+		return line
+	}
+	return ""
+}
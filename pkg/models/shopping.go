@@ -0,0 +1,129 @@
+package models
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ShoppingItem — продукт в списке покупок. Одинаковые продукты в одной единице
+// складываются: ключ — основы слов названия (ShoppingKey) и единица.
+type ShoppingItem struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	Amount    float64   `json:"amount"` // 0 — количество не указано
+	Unit      string    `json:"unit"`
+	Section   string    `json:"section"` // код из StoreSections
+	Checked   bool      `json:"checked"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ShoppingKey — ключ продукта для сложения в списке покупок и запасах: основы слов
+// без окончаний и без учёта порядка (ProductStems), так что «курица» и «курицы»,
+// «филе куриное» и «куриное филе» складываются. Название без значимых слов
+// сравнивается целиком.
+func ShoppingKey(name string) string {
+	if stems := ProductStems(name); len(stems) > 0 {
+		return strings.Join(stems, " ")
+	}
+	return normalizeName(name)
+}
+
+// productParenthesesRe — уточнения в скобках: «(для подачи)», «(репчатый)»
+var productParenthesesRe = regexp.MustCompile(`\s*\([^)]*\)`)
+
+// ProductStems — отсортированные основы слов названия продукта длиной от трёх букв;
+// предлоги, союзы и уточнения в скобках не учитываются
+func ProductStems(name string) []string {
+	name = productParenthesesRe.ReplaceAllString(strings.ReplaceAll(strings.ToLower(name), "ё", "е"), " ")
+	var stems []string
+	for _, w := range strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if utf8.RuneCountInString(w) < 3 {
+			continue
+		}
+		stems = append(stems, strings.TrimRight(w, "аеиоуыэюяйь"))
+	}
+	sort.Strings(stems)
+	return stems
+}
+
+// normalizeName приводит название к нижнему регистру без «ё» и лишних пробелов
+func normalizeName(name string) string {
+	name = strings.ReplaceAll(strings.ToLower(name), "ё", "е")
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// StoreSection — отдел магазина. Stems — начала слов в названиях продуктов.
+type StoreSection struct {
+	Code  string
+	Name  string
+	Stems []string
+}
+
+// SectionOther — отдел для продуктов, которых нет в справочнике
+const SectionOther = "other"
+
+// StoreSections — отделы в порядке обхода магазина
+var StoreSections = []StoreSection{
+	{"produce", "🥦 Овощи, фрукты и зелень", []string{
+		"картоф", "картош", "морков", "лук", "чеснок", "капуст", "брокколи", "помидор", "томат", "черри",
+		"огур", "болгарск", "кабач", "цукини", "баклажан", "свекл", "тыкв", "шпинат", "салат", "зелен",
+		"укроп", "петрушк", "кинз", "базилик", "сельдер", "редис", "гриб", "шампиньон", "яблок", "груш",
+		"банан", "лимон", "лайм", "апельсин", "мандарин", "ягод", "клубник", "черник", "малин", "авокадо", "имбир",
+	}},
+	{"meat", "🥩 Мясо и птица", []string{
+		"куриц", "курин", "цыпл", "индейк", "говяд", "телят", "свин", "баранин", "фарш", "бекон",
+		"ветчин", "колбас", "сосиск", "грудк", "бедр",
+	}},
+	{"fish", "🐟 Рыба и морепродукты", []string{
+		"рыб", "лосос", "семг", "форел", "тунец", "тунц", "треск", "минта", "хек", "сельд", "скумбри",
+		"горбуш", "креветк", "кальмар", "миди",
+	}},
+	{"dairy", "🥛 Молочное и яйца", []string{
+		"молок", "кефир", "йогурт", "творог", "творожн", "сыр", "сметан", "сливк", "сливочн", "ряженк", "яйц", "яйко",
+	}},
+	{"bakery", "🍞 Хлеб и выпечка", []string{
+		"хлеб", "батон", "лаваш", "булк", "булоч", "лепешк", "тортиль", "хлебц",
+	}},
+	{"grocery", "🌾 Крупы, макароны и бакалея", []string{
+		"рис", "греч", "овсян", "хлопь", "макарон", "спагетти", "паст", "лапш", "мук", "сахар", "круп",
+		"пшен", "булгур", "киноа", "кускус", "фасол", "нут", "чечевиц", "горох", "орех", "миндал", "изюм",
+		"мед", "какао", "шоколад", "крахмал", "разрыхлител", "дрожж", "консерв",
+	}},
+	{"pantry", "🧂 Специи, соусы и масла", []string{
+		"соль", "соли", "перец", "перц", "специ", "паприк", "куркум", "кориандр", "корица", "лавр", "приправ",
+		"ванил", "соус", "кетчуп", "майонез", "горчиц", "уксус", "масл", "томатн",
+	}},
+	{SectionOther, "🛍 Прочее", nil},
+}
+
+// StoreSectionFor определяет отдел по названию продукта. Побеждает самое длинное совпадение,
+// поэтому «томатная паста» попадает в соусы, а «болгарский перец» — в овощи.
+func StoreSectionFor(name string) string {
+	words := strings.Fields(normalizeName(name))
+	best, bestLen := SectionOther, 0
+	for _, s := range StoreSections {
+		for _, stem := range s.Stems {
+			for _, w := range words {
+				if len(stem) > bestLen && strings.HasPrefix(w, stem) {
+					best, bestLen = s.Code, len(stem)
+				}
+			}
+		}
+	}
+	return best
+}
+
+// StoreSectionName возвращает название отдела по коду
+func StoreSectionName(code string) string {
+	for _, s := range StoreSections {
+		if s.Code == code {
+			return s.Name
+		}
+	}
+	return code
+}
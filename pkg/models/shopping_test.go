@@ -0,0 +1,44 @@
+package models
+
+import "testing"
+
+func TestShoppingKey(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"Курица", "курицы", true},
+		{"Филе куриное", "куриное  филе", true},
+		{"Свёкла", "свекла", true},
+		{"Лук", "лук (репчатый)", true},
+		{"Масло сливочное", "масло растительное", false},
+		{"Сыр", "сыр твёрдый", false},
+		// Без слов от трёх букв название сравнивается целиком
+		{"Чай", "чай", true},
+		{"ЕЖ", "еж", true},
+		{"ЕЖ", "уж", false},
+	}
+
+	for _, tt := range tests {
+		if got := ShoppingKey(tt.a) == ShoppingKey(tt.b); got != tt.same {
+			t.Errorf("ShoppingKey(%q) = %q, ShoppingKey(%q) = %q; совпадение %v, ожидалось %v",
+				tt.a, ShoppingKey(tt.a), tt.b, ShoppingKey(tt.b), got, tt.same)
+		}
+	}
+}
+
+func TestStoreSectionFor(t *testing.T) {
+	tests := map[string]string{
+		"Томатная паста":   "pantry",
+		"Болгарский перец": "produce",
+		"Куриное филе":     "meat",
+		"Соль":             "pantry",
+		"Корица":           "pantry",
+		"Ананас":           SectionOther,
+	}
+	for name, want := range tests {
+		if got := StoreSectionFor(name); got != want {
+			t.Errorf("StoreSectionFor(%q) = %q; ожидалось %q", name, got, want)
+		}
+	}
+}